@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// APIKeyHeader is the HTTP header API keys are presented in.
+	APIKeyHeader = "X-API-Key"
+
+	// Metadata keys the authenticated principal is forwarded to the backends
+	// under. The gateway never forwards caller supplied values for these.
+	SubjectKey = "x-auth-subject"
+	ScopesKey  = "x-auth-scopes"
+	MethodKey  = "x-auth-method"
+)
+
+var (
+	errNoCredentials      = errors.New("no credentials")
+	errInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is an authenticated caller.
+type Principal struct {
+	// Subject identifies the caller, e.g. a partner name or the JWT "sub".
+	Subject string
+	// Scopes lists what the caller may do, e.g. "races:read".
+	Scopes []string
+	// Method is how the caller authenticated: "api_key" or "jwt".
+	Method string
+}
+
+type principalKey struct{}
+
+// FromContext returns the principal authenticated by Middleware, if any.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// Authenticator verifies API keys and JWT bearer tokens.
+type Authenticator struct {
+	apiKeys  map[string]*Principal // by hex encoded SHA-256 of the key
+	jwtKeys  *KeySet
+	parser   *jwt.Parser
+	issuer   string
+	audience string
+}
+
+// NewAuthenticator creates an authenticator accepting the given API keys and
+// JWTs signed by one of jwtKeys. Either may be empty. If issuer or audience
+// are set, tokens must carry matching "iss"/"aud" claims.
+func NewAuthenticator(apiKeys []APIKey, jwtKeys *KeySet, issuer, audience string) *Authenticator {
+	a := &Authenticator{
+		apiKeys:  make(map[string]*Principal, len(apiKeys)),
+		jwtKeys:  jwtKeys,
+		parser:   jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"})),
+		issuer:   issuer,
+		audience: audience,
+	}
+
+	for _, key := range apiKeys {
+		a.apiKeys[key.hash()] = &Principal{Subject: key.Subject, Scopes: key.Scopes, Method: "api_key"}
+	}
+
+	return a
+}
+
+// Authenticate returns the principal identified by the credentials on r.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return a.authenticateAPIKey(key)
+	}
+
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token := splitAuthorization(header)
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return nil, fmt.Errorf("%w: unsupported authorization scheme", errInvalidCredentials)
+		}
+		return a.authenticateJWT(token)
+	}
+
+	return nil, errNoCredentials
+}
+
+func (a *Authenticator) authenticateAPIKey(key string) (*Principal, error) {
+	sum := sha256.Sum256([]byte(key))
+	if p, ok := a.apiKeys[hex.EncodeToString(sum[:])]; ok {
+		return p, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown API key", errInvalidCredentials)
+}
+
+// claims are the JWT claims the gateway understands.
+type claims struct {
+	jwt.RegisteredClaims
+	// Scope is the OAuth 2.0 space separated scope list.
+	Scope string `json:"scope,omitempty"`
+	// Scp is the scope list as an array, as issued by some providers.
+	Scp []string `json:"scp,omitempty"`
+}
+
+func (a *Authenticator) authenticateJWT(raw string) (*Principal, error) {
+	if a.jwtKeys == nil {
+		return nil, fmt.Errorf("%w: bearer tokens are not accepted", errInvalidCredentials)
+	}
+
+	var c claims
+	if _, err := a.parser.ParseWithClaims(raw, &c, a.jwtKeys.keyFunc); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidCredentials, err)
+	}
+
+	if a.issuer != "" && !c.VerifyIssuer(a.issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer", errInvalidCredentials)
+	}
+	if a.audience != "" && !c.VerifyAudience(a.audience, true) {
+		return nil, fmt.Errorf("%w: unexpected audience", errInvalidCredentials)
+	}
+	if c.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", errInvalidCredentials)
+	}
+
+	scopes := append(strings.Fields(c.Scope), c.Scp...)
+
+	return &Principal{Subject: c.Subject, Scopes: scopes, Method: "jwt"}, nil
+}
+
+// Middleware rejects requests without valid credentials with 401, and stores
+// the authenticated principal in the request context otherwise.
+func Middleware(a *Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, err := a.Authenticate(r)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="entain"`)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"code":    16, // codes.Unauthenticated, matching the gateway's error bodies
+					"message": err.Error(),
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+		})
+	}
+}
+
+// ForwardPrincipal passes the principal authenticated by Middleware on to the
+// backends in gRPC metadata.
+func ForwardPrincipal(ctx context.Context, _ *http.Request) metadata.MD {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	return metadata.Pairs(
+		SubjectKey, p.Subject,
+		ScopesKey, strings.Join(p.Scopes, " "),
+		MethodKey, p.Method,
+	)
+}
+
+// IsReservedMetadata reports whether key is one of the metadata keys the
+// gateway sets itself, which callers must not be able to inject.
+func IsReservedMetadata(key string) bool {
+	switch strings.ToLower(key) {
+	case SubjectKey, ScopesKey, MethodKey:
+		return true
+	}
+
+	return false
+}
+
+func splitAuthorization(header string) (string, string) {
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	if len(parts) != 2 {
+		return parts[0], ""
+	}
+
+	return parts[0], strings.TrimSpace(parts[1])
+}
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+// writeJWKS writes the public half of key as a single key JWKS document.
+func writeJWKS(t *testing.T, key *rsa.PrivateKey, kid string) string {
+	doc := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, kid string, c jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+	token.Header["kid"] = kid
+	raw, err := token.SignedString(key)
+	require.NoError(t, err)
+	return raw
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	sum := sha256.Sum256([]byte("hashed-secret"))
+	a := NewAuthenticator([]APIKey{
+		{Key: "plain-secret", Subject: "partner-a", Scopes: []string{"races:read"}},
+		{KeySHA256: hex.EncodeToString(sum[:]), Subject: "partner-b", Scopes: []string{"sports:read"}},
+	}, nil, "", "")
+
+	for key, subject := range map[string]string{"plain-secret": "partner-a", "hashed-secret": "partner-b"} {
+		r := httptest.NewRequest(http.MethodGet, "/v1/list-races", nil)
+		r.Header.Set(APIKeyHeader, key)
+
+		p, err := a.Authenticate(r)
+		require.NoError(t, err)
+		assert.Equal(t, subject, p.Subject)
+		assert.Equal(t, "api_key", p.Method)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/list-races", nil)
+	r.Header.Set(APIKeyHeader, "wrong")
+	_, err := a.Authenticate(r)
+	assert.ErrorIs(t, err, errInvalidCredentials)
+
+	_, err = a.Authenticate(httptest.NewRequest(http.MethodGet, "/v1/list-races", nil))
+	assert.ErrorIs(t, err, errNoCredentials)
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ks, err := LoadKeySet(writeJWKS(t, key, "k1"))
+	require.NoError(t, err)
+
+	a := NewAuthenticator(nil, ks, "https://issuer.example", "entain-api")
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":   "user-1",
+			"iss":   "https://issuer.example",
+			"aud":   "entain-api",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"scope": "races:read sports:read",
+		}
+	}
+	request := func(token string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/v1/list-races", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		return r
+	}
+
+	p, err := a.Authenticate(request(sign(t, key, "k1", valid())))
+	require.NoError(t, err)
+	assert.Equal(t, &Principal{Subject: "user-1", Scopes: []string{"races:read", "sports:read"}, Method: "jwt"}, p)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	for name, token := range map[string]string{
+		"Expired": sign(t, key, "k1", func() jwt.MapClaims {
+			c := valid()
+			c["exp"] = time.Now().Add(-time.Minute).Unix()
+			return c
+		}()),
+		"WrongIssuer": sign(t, key, "k1", func() jwt.MapClaims {
+			c := valid()
+			c["iss"] = "https://elsewhere.example"
+			return c
+		}()),
+		"WrongAudience": sign(t, key, "k1", func() jwt.MapClaims {
+			c := valid()
+			c["aud"] = "someone-else"
+			return c
+		}()),
+		"UnknownKeyID":  sign(t, key, "k2", valid()),
+		"WrongKey":      sign(t, other, "k1", valid()),
+		"NotAJWT":       "not-a-jwt",
+		"AlgorithmNone": "eyJhbGciOiJub25lIn0.eyJzdWIiOiJ1c2VyLTEifQ.",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := a.Authenticate(request(token))
+			assert.ErrorIs(t, err, errInvalidCredentials)
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	a := NewAuthenticator([]APIKey{{Key: "secret", Subject: "partner-a", Scopes: []string{"races:read"}}}, nil, "", "")
+
+	var (
+		got *Principal
+		md  metadata.MD
+	)
+	handler := Middleware(a)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = FromContext(r.Context())
+		md = ForwardPrincipal(r.Context(), r)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/list-races", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+	assert.Nil(t, got)
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/list-races", nil)
+	r.Header.Set(APIKeyHeader, "secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, got)
+	assert.Equal(t, "partner-a", got.Subject)
+	assert.Equal(t, []string{"partner-a"}, md.Get(SubjectKey))
+	assert.Equal(t, []string{"races:read"}, md.Get(ScopesKey))
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	good := filepath.Join(dir, "good.yaml")
+	require.NoError(t, os.WriteFile(good, []byte(`
+api_keys:
+  - key_sha256: 2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b
+    subject: partner-a
+    scopes: [races:read]
+`), 0o600))
+	keys, err := LoadAPIKeys(good)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "partner-a", keys[0].Subject)
+
+	bad := filepath.Join(dir, "bad.yaml")
+	require.NoError(t, os.WriteFile(bad, []byte(`
+api_keys:
+  - key: secret
+    key_sha256: 2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b
+    subject: partner-a
+`), 0o600))
+	_, err = LoadAPIKeys(bad)
+	assert.Error(t, err)
+}
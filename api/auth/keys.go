@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// APIKey grants a subject the listed scopes.
+type APIKey struct {
+	// Key is the API key itself. Prefer KeySHA256 so the file holds no
+	// usable secrets.
+	Key string `yaml:"key"`
+	// KeySHA256 is the hex encoded SHA-256 digest of the API key.
+	KeySHA256 string   `yaml:"key_sha256"`
+	Subject   string   `yaml:"subject"`
+	Scopes    []string `yaml:"scopes"`
+}
+
+func (k APIKey) hash() string {
+	if k.KeySHA256 != "" {
+		return strings.ToLower(k.KeySHA256)
+	}
+
+	sum := sha256.Sum256([]byte(k.Key))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadAPIKeys reads API keys from a YAML file of the form:
+//
+//	api_keys:
+//	  - key_sha256: 9f86d081884c7d65...
+//	    subject: partner-a
+//	    scopes: [races:read, sports:read]
+func LoadAPIKeys(path string) ([]APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		APIKeys []APIKey `yaml:"api_keys"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	for i, key := range file.APIKeys {
+		if (key.Key == "") == (key.KeySHA256 == "") {
+			return nil, fmt.Errorf("%s: api_keys[%d]: exactly one of key and key_sha256 must be set", path, i)
+		}
+		if key.KeySHA256 != "" {
+			if b, err := hex.DecodeString(key.KeySHA256); err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("%s: api_keys[%d]: key_sha256 must be a hex encoded SHA-256 digest", path, i)
+			}
+		}
+		if key.Subject == "" {
+			return nil, fmt.Errorf("%s: api_keys[%d]: subject must be set", path, i)
+		}
+	}
+
+	return file.APIKeys, nil
+}
+
+// KeySet holds the public keys JWTs may be signed with.
+type KeySet struct {
+	byID map[string]crypto.PublicKey
+	all  []crypto.PublicKey
+}
+
+// LoadKeySet reads public keys from a JWKS document and/or PEM files. Either
+// may be empty.
+func LoadKeySet(jwksFile string, pemFiles ...string) (*KeySet, error) {
+	ks := &KeySet{byID: make(map[string]crypto.PublicKey)}
+
+	if jwksFile != "" {
+		data, err := os.ReadFile(jwksFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := ks.addJWKS(data); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", jwksFile, err)
+		}
+	}
+
+	for _, path := range pemFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := parsePEMPublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		ks.all = append(ks.all, key)
+	}
+
+	if len(ks.all) == 0 {
+		return nil, errors.New("no JWT verification keys found")
+	}
+
+	return ks, nil
+}
+
+// keyFunc picks the key a token was signed with: by "kid" if the token names
+// one, or the only key if there is just one.
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+		if key, ok := ks.byID[kid]; ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+
+	if len(ks.all) == 1 {
+		return ks.all[0], nil
+	}
+
+	return nil, errors.New("token has no key ID")
+}
+
+// jwk is a JSON Web Key (RFC 7517), restricted to public key members.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (ks *KeySet) addJWKS(data []byte) error {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return err
+	}
+
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			return fmt.Errorf("keys[%d]: %w", i, err)
+		}
+
+		ks.all = append(ks.all, key)
+		if k.Kid != "" {
+			ks.byID[k.Kid] = key
+		}
+	}
+
+	return nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+func parsePEMPublicKey(data []byte) (crypto.PublicKey, error) {
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("no RSA, ECDSA or Ed25519 public key found")
+}
@@ -0,0 +1,107 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+// Dial connects to a backend served at one or more addresses, balancing calls
+// across them round robin. name identifies the backend, e.g. "racing".
+func Dial(ctx context.Context, name string, addrs []string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("%s: no backend addresses", name)
+	}
+
+	state := resolver.State{}
+	for _, addr := range addrs {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		// Each address is verified against its own host name over TLS.
+		state.Addresses = append(state.Addresses, resolver.Address{Addr: addr, ServerName: host})
+	}
+
+	r := manual.NewBuilderWithScheme("entain-" + name)
+	r.InitialState(state)
+
+	opts = append([]grpc.DialOption{grpc.WithResolvers(r)}, opts...)
+	return grpc.DialContext(ctx, r.Scheme()+":///"+name, opts...)
+}
+
+// RetryPolicy configures how failed calls are retried by the client.
+type RetryPolicy struct {
+	// Methods lists the RPCs safe to retry, as "package.Service/Method".
+	// Only idempotent reads belong here.
+	Methods []string
+	// MaxAttempts bounds the attempts per call, including the first. Values
+	// below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff and MaxBackoff bound the randomised delay between
+	// attempts, which doubles after every attempt.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// ServiceConfig returns the gRPC service config balancing calls round robin
+// and retrying the policy's methods when a backend is UNAVAILABLE.
+func ServiceConfig(policy RetryPolicy) (string, error) {
+	type name struct {
+		Service string `json:"service"`
+		Method  string `json:"method,omitempty"`
+	}
+	type retryPolicy struct {
+		MaxAttempts          int      `json:"maxAttempts"`
+		InitialBackoff       string   `json:"initialBackoff"`
+		MaxBackoff           string   `json:"maxBackoff"`
+		BackoffMultiplier    float64  `json:"backoffMultiplier"`
+		RetryableStatusCodes []string `json:"retryableStatusCodes"`
+	}
+	type methodConfig struct {
+		Name        []name       `json:"name"`
+		RetryPolicy *retryPolicy `json:"retryPolicy,omitempty"`
+	}
+
+	cfg := struct {
+		LoadBalancingConfig []map[string]struct{} `json:"loadBalancingConfig"`
+		MethodConfig        []methodConfig        `json:"methodConfig,omitempty"`
+	}{
+		LoadBalancingConfig: []map[string]struct{}{{"round_robin": {}}},
+	}
+
+	if policy.MaxAttempts >= 2 && len(policy.Methods) > 0 {
+		mc := methodConfig{
+			RetryPolicy: &retryPolicy{
+				MaxAttempts:          policy.MaxAttempts,
+				InitialBackoff:       seconds(policy.InitialBackoff),
+				MaxBackoff:           seconds(policy.MaxBackoff),
+				BackoffMultiplier:    2,
+				RetryableStatusCodes: []string{"UNAVAILABLE"},
+			},
+		}
+		for _, method := range policy.Methods {
+			parts := strings.SplitN(method, "/", 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return "", fmt.Errorf("retry method %q: must be package.Service/Method", method)
+			}
+			mc.Name = append(mc.Name, name{Service: parts[0], Method: parts[1]})
+		}
+		cfg.MethodConfig = append(cfg.MethodConfig, mc)
+	}
+
+	b, err := json.Marshal(cfg)
+	return string(b), err
+}
+
+// seconds formats d as the service config expects, e.g. "0.1s".
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%gs", d.Seconds())
+}
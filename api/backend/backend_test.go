@@ -0,0 +1,196 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeRacing fails the first failures calls with UNAVAILABLE.
+type fakeRacing struct {
+	racing.UnimplementedRacingServer
+
+	mu       sync.Mutex
+	calls    int
+	failures int
+}
+
+func (f *fakeRacing) GetRace(context.Context, *racing.GetRaceRequest) (*racing.Race, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, status.Error(codes.Unavailable, "try again")
+	}
+	return &racing.Race{Id: 1}, nil
+}
+
+func (f *fakeRacing) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func serve(t *testing.T, srv racing.RacingServer) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := grpc.NewServer()
+	racing.RegisterRacingServer(s, srv)
+	go func() { _ = s.Serve(ln) }()
+	t.Cleanup(s.Stop)
+
+	return ln.Addr().String()
+}
+
+func dial(t *testing.T, policy RetryPolicy, addrs ...string) racing.RacingClient {
+	sc, err := ServiceConfig(policy)
+	require.NoError(t, err)
+
+	conn, err := Dial(context.Background(), "racing", addrs, grpc.WithInsecure(), grpc.WithDefaultServiceConfig(sc))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return racing.NewRacingClient(conn)
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{
+		Methods:        []string{"racing.Racing/GetRace"},
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+	}
+
+	t.Run("RecoversWithinAttempts", func(t *testing.T) {
+		srv := &fakeRacing{failures: 2}
+		client := dial(t, policy, serve(t, srv))
+
+		_, err := client.GetRace(context.Background(), &racing.GetRaceRequest{Id: 1}, grpc.WaitForReady(true))
+		require.NoError(t, err)
+		assert.Equal(t, 3, srv.callCount())
+	})
+
+	t.Run("GivesUp", func(t *testing.T) {
+		srv := &fakeRacing{failures: 5}
+		client := dial(t, policy, serve(t, srv))
+
+		_, err := client.GetRace(context.Background(), &racing.GetRaceRequest{Id: 1}, grpc.WaitForReady(true))
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, 3, srv.callCount())
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		srv := &fakeRacing{failures: 1}
+		client := dial(t, RetryPolicy{}, serve(t, srv))
+
+		_, err := client.GetRace(context.Background(), &racing.GetRaceRequest{Id: 1}, grpc.WaitForReady(true))
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, 1, srv.callCount())
+	})
+}
+
+func TestRoundRobin(t *testing.T) {
+	a, b := &fakeRacing{}, &fakeRacing{}
+	client := dial(t, RetryPolicy{}, serve(t, a), serve(t, b))
+
+	// wait until both subchannels are connected
+	require.Eventually(t, func() bool {
+		_, _ = client.GetRace(context.Background(), &racing.GetRaceRequest{Id: 1}, grpc.WaitForReady(true))
+		return a.callCount() > 0 && b.callCount() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestServiceConfig(t *testing.T) {
+	sc, err := ServiceConfig(RetryPolicy{Methods: []string{"racing.Racing/ListRaces"}, MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"loadBalancingConfig": [{"round_robin": {}}],
+		"methodConfig": [{
+			"name": [{"service": "racing.Racing", "method": "ListRaces"}],
+			"retryPolicy": {
+				"maxAttempts": 3,
+				"initialBackoff": "0.1s",
+				"maxBackoff": "1s",
+				"backoffMultiplier": 2,
+				"retryableStatusCodes": ["UNAVAILABLE"]
+			}
+		}]
+	}`, sc)
+
+	_, err = ServiceConfig(RetryPolicy{Methods: []string{"ListRaces"}, MaxAttempts: 3})
+	assert.Error(t, err)
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC)
+	b := NewBreaker("racing", 2, 10*time.Second)
+	b.now = func() time.Time { return now }
+	interceptor := b.UnaryClientInterceptor()
+
+	var result error
+	calls := 0
+	invoke := func(method string) error {
+		return interceptor(context.Background(), method, nil, nil, nil,
+			func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+				calls++
+				return result
+			})
+	}
+
+	result = status.Error(codes.Unavailable, "down")
+	_ = invoke("/racing.Racing/ListRaces")
+	assert.Equal(t, Closed, b.State())
+	_ = invoke("/racing.Racing/ListRaces")
+	assert.Equal(t, Open, b.State())
+
+	// open: fails fast without calling the backend
+	calls = 0
+	err := invoke("/racing.Racing/ListRaces")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Zero(t, calls)
+
+	// health checks always go through
+	_ = invoke("/grpc.health.v1.Health/Check")
+	assert.Equal(t, 1, calls)
+
+	// after the open duration a failing probe reopens it
+	now = now.Add(10 * time.Second)
+	assert.Equal(t, HalfOpen, b.State())
+	_ = invoke("/racing.Racing/ListRaces")
+	assert.Equal(t, Open, b.State())
+
+	// and a successful one closes it
+	now = now.Add(10 * time.Second)
+	result = nil
+	require.NoError(t, invoke("/racing.Racing/ListRaces"))
+	assert.Equal(t, Closed, b.State())
+
+	// errors caused by the request do not count
+	result = status.Error(codes.NotFound, "no such race")
+	for i := 0; i < 3; i++ {
+		_ = invoke("/racing.Racing/GetRace")
+	}
+	assert.Equal(t, Closed, b.State())
+}
+
+func TestIsFailure(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.True(t, isFailure(context.Background(), status.Error(codes.DeadlineExceeded, "slow")))
+	assert.False(t, isFailure(canceled, status.Error(codes.DeadlineExceeded, "slow")))
+	assert.False(t, isFailure(context.Background(), status.Error(codes.InvalidArgument, "bad")))
+	assert.True(t, isFailure(context.Background(), errors.New("transport")))
+}
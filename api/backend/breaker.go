@@ -0,0 +1,160 @@
+package backend
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"git.neds.sh/matty/entain/common/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// State is the state of a circuit breaker.
+type State int
+
+const (
+	// Closed lets calls through while counting consecutive failures.
+	Closed State = iota
+	// Open fails calls immediately until the open duration has passed.
+	Open
+	// HalfOpen lets a single probe call through to test the backend.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+
+	return "unknown"
+}
+
+// Breaker is a circuit breaker for one backend. After a run of consecutive
+// failures it opens, failing calls fast with UNAVAILABLE instead of letting
+// them queue up on a struggling backend, then lets a probe through to decide
+// whether to close again.
+type Breaker struct {
+	name         string
+	failures     int
+	openDuration time.Duration
+	now          func() time.Time
+
+	mu          sync.Mutex
+	state       State
+	consecutive int
+	openedAt    time.Time
+	probing     bool
+}
+
+// NewBreaker creates a closed breaker for the named backend, opening after
+// failures consecutive failures for openDuration.
+func NewBreaker(name string, failures int, openDuration time.Duration) *Breaker {
+	return &Breaker{name: name, failures: failures, openDuration: openDuration, now: time.Now}
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.openDuration {
+		return HalfOpen
+	}
+	return b.state
+}
+
+// allow reports whether a call may proceed, and whether it is the probe of a
+// half-open breaker.
+func (b *Breaker) allow() (bool, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Closed:
+		return true, false
+	case Open:
+		if b.now().Sub(b.openedAt) < b.openDuration {
+			return false, false
+		}
+		b.state = HalfOpen
+	}
+
+	// half-open: one probe at a time
+	if b.probing {
+		return false, false
+	}
+	b.probing = true
+	return true, true
+}
+
+// record updates the breaker with the outcome of a call, returning the state
+// it moved to if it changed.
+func (b *Breaker) record(failed, probe bool) (State, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+
+	prev := b.state
+	switch {
+	case !failed:
+		b.consecutive = 0
+		b.state = Closed
+	case b.state == HalfOpen:
+		b.state, b.openedAt = Open, b.now()
+	default:
+		b.consecutive++
+		if b.state == Closed && b.consecutive >= b.failures {
+			b.state, b.openedAt = Open, b.now()
+		}
+	}
+
+	return b.state, b.state != prev
+}
+
+// UnaryClientInterceptor guards calls with the breaker. Health checks bypass
+// it so readiness keeps reflecting the backend itself.
+func (b *Breaker) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if strings.HasPrefix(method, "/grpc.health.v1.Health/") {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		ok, probe := b.allow()
+		if !ok {
+			return status.Errorf(codes.Unavailable, "%s backend circuit breaker is open", b.name)
+		}
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		if state, changed := b.record(isFailure(ctx, err), probe); changed {
+			logging.FromContext(ctx).WithField("backend", b.name).Warnf("circuit breaker %s", state)
+		}
+
+		return err
+	}
+}
+
+// isFailure reports whether err says the backend is unhealthy, as opposed to
+// rejecting the request or the caller giving up.
+func isFailure(ctx context.Context, err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Internal, codes.Unknown:
+		return true
+	case codes.DeadlineExceeded:
+		// a slow backend, unless the caller went away first
+		return ctx.Err() != context.Canceled
+	}
+
+	return false
+}
@@ -0,0 +1,319 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"git.neds.sh/matty/entain/api/ratelimit"
+	"git.neds.sh/matty/entain/common/configload"
+	"github.com/sirupsen/logrus"
+)
+
+// EnvPrefix prefixes the environment variable of every setting, e.g.
+// API_API_ENDPOINT for --api-endpoint.
+const EnvPrefix = "API"
+
+// Config is the effective configuration of the API gateway.
+type Config struct {
+	APIEndpoint                string        `yaml:"api_endpoint" toml:"api_endpoint"`
+	GRPCEndpointRacing         string        `yaml:"grpc_endpoint_racing" toml:"grpc_endpoint_racing"`
+	GRPCEndpointSports         string        `yaml:"grpc_endpoint_sports" toml:"grpc_endpoint_sports"`
+	LogLevel                   string        `yaml:"log_level" toml:"log_level"`
+	LogFormat                  string        `yaml:"log_format" toml:"log_format"`
+	ReadinessTimeout           time.Duration `yaml:"readiness_timeout" toml:"readiness_timeout"`
+	ShutdownDelay              time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay"`
+	ShutdownTimeout            time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	TLSCertFile                string        `yaml:"tls_cert_file" toml:"tls_cert_file"`
+	TLSKeyFile                 string        `yaml:"tls_key_file" toml:"tls_key_file"`
+	BackendTLS                 bool          `yaml:"backend_tls" toml:"backend_tls"`
+	BackendTLSCAFile           string        `yaml:"backend_tls_ca_file" toml:"backend_tls_ca_file"`
+	BackendTLSCertFile         string        `yaml:"backend_tls_cert_file" toml:"backend_tls_cert_file"`
+	BackendTLSKeyFile          string        `yaml:"backend_tls_key_file" toml:"backend_tls_key_file"`
+	TLSReloadInterval          time.Duration `yaml:"tls_reload_interval" toml:"tls_reload_interval"`
+	AuthEnabled                bool          `yaml:"auth_enabled" toml:"auth_enabled"`
+	AuthAPIKeysFile            string        `yaml:"auth_api_keys_file" toml:"auth_api_keys_file"`
+	AuthJWKSFile               string        `yaml:"auth_jwks_file" toml:"auth_jwks_file"`
+	AuthJWTPublicKeyFile       string        `yaml:"auth_jwt_public_key_file" toml:"auth_jwt_public_key_file"`
+	AuthJWTIssuer              string        `yaml:"auth_jwt_issuer" toml:"auth_jwt_issuer"`
+	AuthJWTAudience            string        `yaml:"auth_jwt_audience" toml:"auth_jwt_audience"`
+	RateLimit                  string        `yaml:"rate_limit" toml:"rate_limit"`
+	RateLimitRoutes            string        `yaml:"rate_limit_routes" toml:"rate_limit_routes"`
+	RateLimitClients           string        `yaml:"rate_limit_clients" toml:"rate_limit_clients"`
+	CORSAllowedOrigins         string        `yaml:"cors_allowed_origins" toml:"cors_allowed_origins"`
+	CORSAllowedMethods         string        `yaml:"cors_allowed_methods" toml:"cors_allowed_methods"`
+	CORSAllowedHeaders         string        `yaml:"cors_allowed_headers" toml:"cors_allowed_headers"`
+	CORSExposedHeaders         string        `yaml:"cors_exposed_headers" toml:"cors_exposed_headers"`
+	CORSAllowCredentials       bool          `yaml:"cors_allow_credentials" toml:"cors_allow_credentials"`
+	CORSMaxAge                 time.Duration `yaml:"cors_max_age" toml:"cors_max_age"`
+	Compression                bool          `yaml:"compression" toml:"compression"`
+	CompressionMinSize         int           `yaml:"compression_min_size" toml:"compression_min_size"`
+	SecurityHeaders            bool          `yaml:"security_headers" toml:"security_headers"`
+	Docs                       bool          `yaml:"docs" toml:"docs"`
+	BackendTimeout             time.Duration `yaml:"backend_timeout" toml:"backend_timeout"`
+	BackendRouteTimeouts       string        `yaml:"backend_route_timeouts" toml:"backend_route_timeouts"`
+	BackendRetryMaxAttempts    int           `yaml:"backend_retry_max_attempts" toml:"backend_retry_max_attempts"`
+	BackendRetryInitialBackoff time.Duration `yaml:"backend_retry_initial_backoff" toml:"backend_retry_initial_backoff"`
+	BackendRetryMaxBackoff     time.Duration `yaml:"backend_retry_max_backoff" toml:"backend_retry_max_backoff"`
+	BackendBreakerFailures     int           `yaml:"backend_breaker_failures" toml:"backend_breaker_failures"`
+	BackendBreakerOpenDuration time.Duration `yaml:"backend_breaker_open_duration" toml:"backend_breaker_open_duration"`
+
+	// PrintConfig asks for the effective configuration to be printed instead
+	// of starting the gateway. It can only be set as a flag.
+	PrintConfig bool `yaml:"-" toml:"-"`
+
+	flags *flag.FlagSet
+}
+
+// Default returns the configuration used when nothing else is specified.
+func Default() *Config {
+	return &Config{
+		APIEndpoint:                "localhost:8000",
+		GRPCEndpointRacing:         "localhost:9000",
+		GRPCEndpointSports:         "localhost:9001",
+		LogLevel:                   "info",
+		LogFormat:                  "json",
+		ReadinessTimeout:           2 * time.Second,
+		ShutdownTimeout:            15 * time.Second,
+		TLSReloadInterval:          30 * time.Second,
+		CORSAllowedMethods:         "GET,POST",
+		CORSAllowedHeaders:         "Authorization,Content-Type,X-API-Key,X-Request-ID",
+		CORSExposedHeaders:         "X-Request-ID,Retry-After,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,RateLimit-Policy",
+		CORSMaxAge:                 10 * time.Minute,
+		Compression:                true,
+		CompressionMinSize:         1024,
+		SecurityHeaders:            true,
+		Docs:                       true,
+		BackendTimeout:             10 * time.Second,
+		BackendRetryMaxAttempts:    3,
+		BackendRetryInitialBackoff: 100 * time.Millisecond,
+		BackendRetryMaxBackoff:     time.Second,
+		BackendBreakerFailures:     5,
+		BackendBreakerOpenDuration: 10 * time.Second,
+	}
+}
+
+// Load builds the configuration from, in increasing order of precedence: the
+// defaults, the config file given by --config (or API_CONFIG), API_*
+// environment variables and command line flags. The result is validated.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	c := Default()
+
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	if err := configload.Load(c, fs, EnvPrefix, args, lookupEnv, c.bind); err != nil {
+		return nil, err
+	}
+	c.flags = fs
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// bind registers a flag for every setting, defaulting to its current value.
+func (c *Config) bind(fs *flag.FlagSet) {
+	fs.StringVar(&c.APIEndpoint, "api-endpoint", c.APIEndpoint, "API endpoint")
+	fs.StringVar(&c.GRPCEndpointRacing, "grpc-endpoint-racing", c.GRPCEndpointRacing, "Comma separated gRPC server endpoints of the racing service, balanced round robin")
+	fs.StringVar(&c.GRPCEndpointSports, "grpc-endpoint-sports", c.GRPCEndpointSports, "Comma separated gRPC server endpoints of the sports service, balanced round robin")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "Log level (debug, info, warn, error)")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "Log format (json or text)")
+	fs.DurationVar(&c.ReadinessTimeout, "readiness-timeout", c.ReadinessTimeout, "Deadline for backend health checks made by /readyz")
+	fs.DurationVar(&c.ShutdownDelay, "shutdown-delay", c.ShutdownDelay, "How long to keep serving after /readyz starts failing on shutdown, so load balancers can react")
+	fs.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout, "Deadline for in-flight requests to drain on shutdown")
+	fs.StringVar(&c.TLSCertFile, "tls-cert-file", c.TLSCertFile, "PEM certificate to serve HTTPS with (plain HTTP if empty)")
+	fs.StringVar(&c.TLSKeyFile, "tls-key-file", c.TLSKeyFile, "PEM private key of --tls-cert-file")
+	fs.BoolVar(&c.BackendTLS, "backend-tls", c.BackendTLS, "Connect to the backends over TLS")
+	fs.StringVar(&c.BackendTLSCAFile, "backend-tls-ca-file", c.BackendTLSCAFile, "PEM CA bundle to verify backend certificates against (system roots if empty)")
+	fs.StringVar(&c.BackendTLSCertFile, "backend-tls-cert-file", c.BackendTLSCertFile, "PEM client certificate presented to the backends (mutual TLS)")
+	fs.StringVar(&c.BackendTLSKeyFile, "backend-tls-key-file", c.BackendTLSKeyFile, "PEM private key of --backend-tls-cert-file")
+	fs.DurationVar(&c.TLSReloadInterval, "tls-reload-interval", c.TLSReloadInterval, "How often TLS files are checked for changes and reloaded")
+	fs.BoolVar(&c.AuthEnabled, "auth-enabled", c.AuthEnabled, "Require an API key or JWT bearer token on every API route")
+	fs.StringVar(&c.AuthAPIKeysFile, "auth-api-keys-file", c.AuthAPIKeysFile, "YAML file of accepted API keys and their scopes")
+	fs.StringVar(&c.AuthJWKSFile, "auth-jwks-file", c.AuthJWKSFile, "JWKS file of keys JWT bearer tokens may be signed with")
+	fs.StringVar(&c.AuthJWTPublicKeyFile, "auth-jwt-public-key-file", c.AuthJWTPublicKeyFile, "PEM public key JWT bearer tokens may be signed with")
+	fs.StringVar(&c.AuthJWTIssuer, "auth-jwt-issuer", c.AuthJWTIssuer, "Required \"iss\" claim of JWT bearer tokens (not checked if empty)")
+	fs.StringVar(&c.AuthJWTAudience, "auth-jwt-audience", c.AuthJWTAudience, "Required \"aud\" claim of JWT bearer tokens (not checked if empty)")
+	fs.StringVar(&c.RateLimit, "rate-limit", c.RateLimit, "Default per-client rate limit as requests per second:burst, e.g. 10:20 (unlimited if empty)")
+	fs.StringVar(&c.RateLimitRoutes, "rate-limit-routes", c.RateLimitRoutes, "Comma separated path-prefix=rate:burst limits overriding --rate-limit, e.g. /v1/list-races=5:10")
+	fs.StringVar(&c.RateLimitClients, "rate-limit-clients", c.RateLimitClients, "Comma separated subject=rate:burst limits overriding all others for authenticated clients")
+	fs.StringVar(&c.CORSAllowedOrigins, "cors-allowed-origins", c.CORSAllowedOrigins, "Comma separated origins browsers may call the API from, e.g. https://app.example.com or https://*.example.com (CORS disabled if empty)")
+	fs.StringVar(&c.CORSAllowedMethods, "cors-allowed-methods", c.CORSAllowedMethods, "Comma separated methods allowed in cross-origin requests")
+	fs.StringVar(&c.CORSAllowedHeaders, "cors-allowed-headers", c.CORSAllowedHeaders, "Comma separated request headers allowed in cross-origin requests")
+	fs.StringVar(&c.CORSExposedHeaders, "cors-exposed-headers", c.CORSExposedHeaders, "Comma separated response headers exposed to cross-origin scripts")
+	fs.BoolVar(&c.CORSAllowCredentials, "cors-allow-credentials", c.CORSAllowCredentials, "Allow cross-origin requests to carry credentials")
+	fs.DurationVar(&c.CORSMaxAge, "cors-max-age", c.CORSMaxAge, "How long browsers may cache CORS preflight responses")
+	fs.BoolVar(&c.Compression, "compression", c.Compression, "Compress responses with brotli or gzip when the client accepts it")
+	fs.IntVar(&c.CompressionMinSize, "compression-min-size", c.CompressionMinSize, "Responses smaller than this many bytes are not compressed")
+	fs.BoolVar(&c.SecurityHeaders, "security-headers", c.SecurityHeaders, "Send standard security headers (nosniff, frame denial, HSTS over TLS, ...)")
+	fs.BoolVar(&c.Docs, "docs", c.Docs, "Serve the OpenAPI spec at /openapi.json and interactive docs at /docs/")
+	fs.DurationVar(&c.BackendTimeout, "backend-timeout", c.BackendTimeout, "Deadline for API requests, including backend calls and retries (0 for none)")
+	fs.StringVar(&c.BackendRouteTimeouts, "backend-route-timeouts", c.BackendRouteTimeouts, "Comma separated path-prefix=duration deadlines overriding --backend-timeout, e.g. /v1/list-races=5s")
+	fs.IntVar(&c.BackendRetryMaxAttempts, "backend-retry-max-attempts", c.BackendRetryMaxAttempts, "Attempts per read call to an UNAVAILABLE backend, including the first (1 disables retries)")
+	fs.DurationVar(&c.BackendRetryInitialBackoff, "backend-retry-initial-backoff", c.BackendRetryInitialBackoff, "Backoff before the first retry, doubling with every attempt")
+	fs.DurationVar(&c.BackendRetryMaxBackoff, "backend-retry-max-backoff", c.BackendRetryMaxBackoff, "Upper bound of the backoff between retries")
+	fs.IntVar(&c.BackendBreakerFailures, "backend-breaker-failures", c.BackendBreakerFailures, "Consecutive failures after which a backend's circuit breaker opens (0 disables it)")
+	fs.DurationVar(&c.BackendBreakerOpenDuration, "backend-breaker-open-duration", c.BackendBreakerOpenDuration, "How long an open circuit breaker fails calls before probing the backend again")
+	fs.BoolVar(&c.PrintConfig, "print-config", false, "Print the effective configuration and exit")
+}
+
+// Validate checks the configuration is usable.
+func (c *Config) Validate() error {
+	var errs []string
+
+	if _, _, err := net.SplitHostPort(c.APIEndpoint); err != nil {
+		errs = append(errs, fmt.Sprintf("api_endpoint: %s", err))
+	}
+	for name, endpoints := range map[string]string{
+		"grpc_endpoint_racing": c.GRPCEndpointRacing,
+		"grpc_endpoint_sports": c.GRPCEndpointSports,
+	} {
+		if len(SplitList(endpoints)) == 0 {
+			errs = append(errs, fmt.Sprintf("%s: must not be empty", name))
+		}
+		for _, endpoint := range SplitList(endpoints) {
+			if _, _, err := net.SplitHostPort(endpoint); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+			}
+		}
+	}
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Sprintf("log_level: %s", err))
+	}
+	if c.LogFormat != "json" && c.LogFormat != "text" {
+		errs = append(errs, fmt.Sprintf("log_format: must be json or text, got %q", c.LogFormat))
+	}
+	if c.ReadinessTimeout <= 0 {
+		errs = append(errs, "readiness_timeout: must be positive")
+	}
+	if c.ShutdownDelay < 0 {
+		errs = append(errs, "shutdown_delay: must not be negative")
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, "shutdown_timeout: must be positive")
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, "tls_cert_file, tls_key_file: must be set together")
+	}
+	if (c.BackendTLSCertFile == "") != (c.BackendTLSKeyFile == "") {
+		errs = append(errs, "backend_tls_cert_file, backend_tls_key_file: must be set together")
+	}
+	if !c.BackendTLS && (c.BackendTLSCAFile != "" || c.BackendTLSCertFile != "") {
+		errs = append(errs, "backend_tls_ca_file, backend_tls_cert_file: require backend_tls")
+	}
+	if c.TLSReloadInterval <= 0 {
+		errs = append(errs, "tls_reload_interval: must be positive")
+	}
+
+	jwtKeys := c.AuthJWKSFile != "" || c.AuthJWTPublicKeyFile != ""
+	if c.AuthEnabled && c.AuthAPIKeysFile == "" && !jwtKeys {
+		errs = append(errs, "auth_enabled: requires auth_api_keys_file, auth_jwks_file or auth_jwt_public_key_file")
+	}
+	if !jwtKeys && (c.AuthJWTIssuer != "" || c.AuthJWTAudience != "") {
+		errs = append(errs, "auth_jwt_issuer, auth_jwt_audience: require auth_jwks_file or auth_jwt_public_key_file")
+	}
+
+	if c.RateLimit != "" {
+		if _, err := ratelimit.ParseLimit(c.RateLimit); err != nil {
+			errs = append(errs, fmt.Sprintf("rate_limit: %s", err))
+		}
+	}
+	if _, err := ratelimit.ParseLimits(c.RateLimitRoutes); err != nil {
+		errs = append(errs, fmt.Sprintf("rate_limit_routes: %s", err))
+	}
+	if _, err := ratelimit.ParseLimits(c.RateLimitClients); err != nil {
+		errs = append(errs, fmt.Sprintf("rate_limit_clients: %s", err))
+	} else if c.RateLimitClients != "" && !c.AuthEnabled {
+		errs = append(errs, "rate_limit_clients: requires auth_enabled")
+	}
+
+	if c.CORSAllowCredentials {
+		for _, origin := range SplitList(c.CORSAllowedOrigins) {
+			if origin == "*" {
+				errs = append(errs, "cors_allow_credentials: cannot be combined with a * origin")
+			}
+		}
+	}
+	if c.CORSMaxAge < 0 {
+		errs = append(errs, "cors_max_age: must not be negative")
+	}
+	if c.CompressionMinSize < 0 {
+		errs = append(errs, "compression_min_size: must not be negative")
+	}
+
+	if c.BackendTimeout < 0 {
+		errs = append(errs, "backend_timeout: must not be negative")
+	}
+	if _, err := ParseDurations(c.BackendRouteTimeouts); err != nil {
+		errs = append(errs, fmt.Sprintf("backend_route_timeouts: %s", err))
+	}
+	if c.BackendRetryMaxAttempts < 1 {
+		errs = append(errs, "backend_retry_max_attempts: must be at least 1")
+	}
+	if c.BackendRetryMaxAttempts > 1 && (c.BackendRetryInitialBackoff <= 0 || c.BackendRetryMaxBackoff < c.BackendRetryInitialBackoff) {
+		errs = append(errs, "backend_retry_initial_backoff, backend_retry_max_backoff: must be positive, initial not above max")
+	}
+	if c.BackendBreakerFailures < 0 {
+		errs = append(errs, "backend_breaker_failures: must not be negative")
+	}
+	if c.BackendBreakerFailures > 0 && c.BackendBreakerOpenDuration <= 0 {
+		errs = append(errs, "backend_breaker_open_duration: must be positive")
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return errors.New(configload.FormatErrors(errs))
+	}
+
+	return nil
+}
+
+// SplitList splits a comma separated setting into its trimmed, non-empty
+// items.
+func SplitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// ParseDurations parses a comma separated list of name=duration pairs, e.g.
+// "/v1/list-races=5s,/v1/race/=1s".
+func ParseDurations(s string) (map[string]time.Duration, error) {
+	durations := make(map[string]time.Duration)
+	for _, item := range SplitList(s) {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("%q: must be name=duration", item)
+		}
+
+		d, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", item, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("%q: must not be negative", item)
+		}
+		durations[strings.TrimSpace(parts[0])] = d
+	}
+
+	return durations, nil
+}
+
+// Print writes the effective configuration to w as YAML. None of the
+// gateway's settings is secret: API keys and signing keys are only read from
+// the files they name.
+func (c *Config) Print(w io.Writer) error {
+	return configload.Print(w, c.flags, nil)
+}
@@ -0,0 +1,289 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(nil, env(nil))
+	require.NoError(t, err)
+
+	expected := Default()
+	expected.flags = cfg.flags
+	assert.Equal(t, expected, cfg)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "api.yaml", "api_endpoint: 0.0.0.0:8100\nlog_level: debug\nbackend_timeout: 30s\n")
+
+	cfg, err := Load(
+		[]string{"--config", path, "--log-level", "warn"},
+		env(map[string]string{"API_LOG_LEVEL": "error", "API_BACKEND_TIMEOUT": "20s"}),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "0.0.0.0:8100", cfg.APIEndpoint, "file overrides default")
+	assert.Equal(t, 20*time.Second, cfg.BackendTimeout, "env overrides file")
+	assert.Equal(t, "warn", cfg.LogLevel, "flag overrides env")
+	assert.Equal(t, "localhost:9000", cfg.GRPCEndpointRacing, "default kept")
+}
+
+func TestLoadConfigFromEnvAndTOML(t *testing.T) {
+	path := writeFile(t, "api.toml", "grpc_endpoint_racing = \"racing-1:9000, racing-2:9000\"\ncors_allowed_origins = \"https://app.example.com\"\ncompression = false\n")
+
+	cfg, err := Load(nil, env(map[string]string{"API_CONFIG": path}))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"racing-1:9000", "racing-2:9000"}, SplitList(cfg.GRPCEndpointRacing))
+	assert.Equal(t, "https://app.example.com", cfg.CORSAllowedOrigins)
+	assert.False(t, cfg.Compression)
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		err  string
+	}{
+		{
+			name: "UnknownFileKey",
+			args: []string{"--config", writeFile(t, "api.yaml", "api_endpiont: localhost:1\n")},
+			err:  "field api_endpiont not found",
+		},
+		{
+			name: "InvalidEnvValue",
+			env:  map[string]string{"API_COMPRESSION": "sometimes"},
+			err:  "API_COMPRESSION",
+		},
+		{
+			name: "InvalidEndpoint",
+			args: []string{"--api-endpoint", "8000"},
+			err:  "api_endpoint",
+		},
+		{
+			name: "InvalidBackendEndpoint",
+			args: []string{"--grpc-endpoint-sports", "sports-1:9001,sports-2"},
+			err:  "grpc_endpoint_sports",
+		},
+		{
+			name: "InvalidLogFormat",
+			args: []string{"--log-format", "xml"},
+			err:  "log_format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.args, env(tt.env))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		errs   []string
+	}{
+		{
+			name:   "Default",
+			modify: func(c *Config) {},
+		},
+		{
+			name: "EmptyBackends",
+			modify: func(c *Config) {
+				c.GRPCEndpointRacing = " , "
+				c.GRPCEndpointSports = ""
+			},
+			errs: []string{"grpc_endpoint_racing: must not be empty", "grpc_endpoint_sports: must not be empty"},
+		},
+		{
+			name: "Durations",
+			modify: func(c *Config) {
+				c.ReadinessTimeout = 0
+				c.ShutdownDelay = -time.Second
+				c.ShutdownTimeout = 0
+				c.TLSReloadInterval = 0
+			},
+			errs: []string{
+				"readiness_timeout: must be positive",
+				"shutdown_delay: must not be negative",
+				"shutdown_timeout: must be positive",
+				"tls_reload_interval: must be positive",
+			},
+		},
+		{
+			name: "TLS",
+			modify: func(c *Config) {
+				c.TLSCertFile = "cert.pem"
+				c.BackendTLSCertFile = "client.pem"
+			},
+			errs: []string{
+				"backend_tls_ca_file, backend_tls_cert_file: require backend_tls",
+				"backend_tls_cert_file, backend_tls_key_file: must be set together",
+				"tls_cert_file, tls_key_file: must be set together",
+			},
+		},
+		{
+			name: "BackendTLS",
+			modify: func(c *Config) {
+				c.BackendTLS = true
+				c.BackendTLSCAFile = "ca.pem"
+				c.BackendTLSCertFile = "client.pem"
+				c.BackendTLSKeyFile = "client-key.pem"
+			},
+		},
+		{
+			name:   "AuthWithoutKeys",
+			modify: func(c *Config) { c.AuthEnabled = true },
+			errs:   []string{"auth_enabled: requires auth_api_keys_file, auth_jwks_file or auth_jwt_public_key_file"},
+		},
+		{
+			name: "JWTClaimsWithoutKeys",
+			modify: func(c *Config) {
+				c.AuthEnabled = true
+				c.AuthAPIKeysFile = "keys.yaml"
+				c.AuthJWTIssuer = "https://issuer.example.com"
+			},
+			errs: []string{"auth_jwt_issuer, auth_jwt_audience: require auth_jwks_file or auth_jwt_public_key_file"},
+		},
+		{
+			name: "JWT",
+			modify: func(c *Config) {
+				c.AuthEnabled = true
+				c.AuthJWKSFile = "jwks.json"
+				c.AuthJWTIssuer = "https://issuer.example.com"
+				c.AuthJWTAudience = "entain"
+			},
+		},
+		{
+			name: "RateLimits",
+			modify: func(c *Config) {
+				c.RateLimit = "fast"
+				c.RateLimitRoutes = "/v1/list-races"
+				c.RateLimitClients = "client-1=5:10"
+			},
+			errs: []string{"rate_limit: ", "rate_limit_clients: requires auth_enabled", "rate_limit_routes: "},
+		},
+		{
+			name: "CORS",
+			modify: func(c *Config) {
+				c.CORSAllowedOrigins = "https://app.example.com, *"
+				c.CORSAllowCredentials = true
+				c.CORSMaxAge = -time.Minute
+				c.CompressionMinSize = -1
+			},
+			errs: []string{
+				"compression_min_size: must not be negative",
+				"cors_allow_credentials: cannot be combined with a * origin",
+				"cors_max_age: must not be negative",
+			},
+		},
+		{
+			name: "Backend",
+			modify: func(c *Config) {
+				c.BackendTimeout = -time.Second
+				c.BackendRouteTimeouts = "/v1/list-races"
+				c.BackendRetryInitialBackoff = 2 * time.Second
+				c.BackendBreakerOpenDuration = 0
+			},
+			errs: []string{
+				"backend_breaker_open_duration: must be positive",
+				"backend_retry_initial_backoff, backend_retry_max_backoff: must be positive, initial not above max",
+				"backend_route_timeouts: ",
+				"backend_timeout: must not be negative",
+			},
+		},
+		{
+			name: "RetriesAndBreakerDisabled",
+			modify: func(c *Config) {
+				c.BackendRetryMaxAttempts = 1
+				c.BackendRetryInitialBackoff = 0
+				c.BackendBreakerFailures = 0
+				c.BackendBreakerOpenDuration = 0
+			},
+		},
+		{
+			name:   "NoAttempts",
+			modify: func(c *Config) { c.BackendRetryMaxAttempts = 0 },
+			errs:   []string{"backend_retry_max_attempts: must be at least 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default()
+			tt.modify(c)
+
+			err := c.Validate()
+			if len(tt.errs) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			lines := splitErrors(err.Error())
+			require.Len(t, lines, len(tt.errs), err.Error())
+			for i, msg := range tt.errs {
+				assert.Contains(t, lines[i], msg)
+			}
+		})
+	}
+}
+
+// splitErrors returns the problems listed in a validation error.
+func splitErrors(msg string) []string {
+	return strings.Split(msg, "\n  ")[1:]
+}
+
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"a", "b c", "d"}, SplitList(" a,b c ,, d,"))
+	assert.Empty(t, SplitList(" , "))
+}
+
+func TestParseDurations(t *testing.T) {
+	durations, err := ParseDurations("/v1/list-races=5s, /v1/race/ = 1s")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{"/v1/list-races": 5 * time.Second, "/v1/race/": time.Second}, durations)
+
+	for _, s := range []string{"/v1/list-races", "=5s", "/v1/list-races=soon", "/v1/list-races=-1s"} {
+		_, err := ParseDurations(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	cfg, err := Load([]string{"--auth-enabled", "--auth-api-keys-file", "/etc/api/keys.yaml", "--print-config"}, env(nil))
+	require.NoError(t, err)
+	assert.True(t, cfg.PrintConfig)
+
+	var buf bytes.Buffer
+	require.NoError(t, cfg.Print(&buf))
+	assert.Contains(t, buf.String(), "auth_api_keys_file: /etc/api/keys.yaml\n")
+	assert.Contains(t, buf.String(), "auth_enabled: true\n")
+	assert.Contains(t, buf.String(), "backend_timeout: 10s\n")
+	assert.NotContains(t, buf.String(), "print_config")
+}
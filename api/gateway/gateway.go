@@ -0,0 +1,69 @@
+// Package gateway builds the REST to gRPC mux the API serves: the generated
+// racing and sports handlers and the search endpoint.
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	// Registers the google.rpc error details, e.g. the BadRequest field
+	// violations the backends return, so errors render them as JSON.
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"git.neds.sh/matty/entain/api/auth"
+	"git.neds.sh/matty/entain/api/logging"
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"git.neds.sh/matty/entain/api/proto/sports"
+	"git.neds.sh/matty/entain/api/search"
+	commonlogging "git.neds.sh/matty/entain/common/logging"
+)
+
+// NewMux returns the mux translating REST requests into calls to the racing
+// and sports services over the given connections. It forwards the request ID
+// and the authenticated principal to the services.
+func NewMux(ctx context.Context, racingConn, sportsConn *grpc.ClientConn) (*runtime.ServeMux, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(incomingHeaderMatcher),
+		runtime.WithMetadata(forwardRequestID),
+		runtime.WithMetadata(auth.ForwardPrincipal),
+	)
+	if err := racing.RegisterRacingHandler(ctx, mux, racingConn); err != nil {
+		return nil, err
+	}
+
+	if err := sports.RegisterSportsHandler(ctx, mux, sportsConn); err != nil {
+		return nil, err
+	}
+
+	if err := search.Register(mux, racing.NewRacingClient(racingConn), sports.NewSportsClient(sportsConn)); err != nil {
+		return nil, err
+	}
+
+	return mux, nil
+}
+
+// incomingHeaderMatcher forwards headers like the default matcher does, except
+// for metadata the gateway itself is responsible for setting.
+func incomingHeaderMatcher(key string) (string, bool) {
+	canonical := textproto.CanonicalMIMEHeaderKey(key)
+	prefix := textproto.CanonicalMIMEHeaderKey(runtime.MetadataHeaderPrefix)
+	if strings.HasPrefix(canonical, prefix) {
+		name := strings.TrimPrefix(canonical, prefix)
+		if strings.EqualFold(name, commonlogging.RequestIDKey) || auth.IsReservedMetadata(name) {
+			return "", false
+		}
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// forwardRequestID passes the request ID assigned by logging.Middleware on to
+// the backend services in gRPC metadata.
+func forwardRequestID(ctx context.Context, _ *http.Request) metadata.MD {
+	return metadata.Pairs(commonlogging.RequestIDKey, logging.RequestIDFromContext(ctx))
+}
@@ -0,0 +1,176 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"git.neds.sh/matty/entain/api/auth"
+	"git.neds.sh/matty/entain/api/config"
+	"git.neds.sh/matty/entain/api/health"
+	"git.neds.sh/matty/entain/api/logging"
+	"git.neds.sh/matty/entain/api/middleware"
+	"git.neds.sh/matty/entain/api/openapi"
+	"git.neds.sh/matty/entain/api/ratelimit"
+)
+
+// Handler is everything the API serves over HTTP: the mux behind the
+// middleware cfg enables, the health endpoints and, if enabled, the docs.
+type Handler struct {
+	http.Handler
+
+	// Readiness is the handler served on /readyz, to fail it while the
+	// gateway drains.
+	Readiness *health.Readiness
+}
+
+// NewHandler returns the handler serving the API over the given connections to
+// the racing and sports services, configured by cfg.
+func NewHandler(ctx context.Context, cfg *config.Config, logger *logrus.Logger, racingConn, sportsConn *grpc.ClientConn) (*Handler, error) {
+	mux, err := NewMux(ctx, racingConn, sportsConn)
+	if err != nil {
+		return nil, err
+	}
+
+	readiness := health.NewReadiness(
+		cfg.ReadinessTimeout,
+		health.Backend{Name: "racing", Client: healthpb.NewHealthClient(racingConn)},
+		health.Backend{Name: "sports", Client: healthpb.NewHealthClient(sportsConn)},
+	)
+
+	var authMiddleware, limitMiddleware middleware.Middleware
+	if cfg.AuthEnabled {
+		authenticator, err := newAuthenticator(cfg)
+		if err != nil {
+			return nil, err
+		}
+		authMiddleware = auth.Middleware(authenticator)
+	}
+	if cfg.RateLimit != "" || cfg.RateLimitRoutes != "" || cfg.RateLimitClients != "" {
+		limitMiddleware = ratelimit.Middleware(newLimiter(cfg))
+	}
+
+	routeTimeouts, _ := config.ParseDurations(cfg.BackendRouteTimeouts)
+
+	// Authentication runs before rate limiting so clients are limited by
+	// identity, and the deadline only starts once a request is let through.
+	api := middleware.Chain(
+		authMiddleware,
+		limitMiddleware,
+		middleware.Deadline(cfg.BackendTimeout, routeTimeouts),
+	)(mux)
+
+	root := http.NewServeMux()
+	root.Handle("/healthz", health.LivenessHandler())
+	root.Handle("/readyz", readiness)
+	root.Handle("/", api)
+
+	if cfg.Docs {
+		spec, err := openapi.Spec(openapi.Info{
+			Title:       "Entain API",
+			Description: "Racing and sports data, served by the API gateway.",
+			Version:     "v1",
+		}, cfg.AuthEnabled)
+		if err != nil {
+			return nil, err
+		}
+		root.Handle("/openapi.json", openapi.Handler(spec))
+		root.Handle("/docs/", openapi.DocsHandler("/docs/"))
+	}
+
+	handler := middleware.Chain(
+		logging.Middleware(logger),
+		securityMiddleware(cfg),
+		corsMiddleware(cfg),
+		compressMiddleware(cfg),
+	)(root)
+
+	return &Handler{Handler: handler, Readiness: readiness}, nil
+}
+
+// newAuthenticator loads the API keys and JWT verification keys configured.
+func newAuthenticator(cfg *config.Config) (*auth.Authenticator, error) {
+	var apiKeys []auth.APIKey
+	if cfg.AuthAPIKeysFile != "" {
+		var err error
+		if apiKeys, err = auth.LoadAPIKeys(cfg.AuthAPIKeysFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var jwtKeys *auth.KeySet
+	if cfg.AuthJWKSFile != "" || cfg.AuthJWTPublicKeyFile != "" {
+		var pemFiles []string
+		if cfg.AuthJWTPublicKeyFile != "" {
+			pemFiles = append(pemFiles, cfg.AuthJWTPublicKeyFile)
+		}
+
+		var err error
+		if jwtKeys, err = auth.LoadKeySet(cfg.AuthJWKSFile, pemFiles...); err != nil {
+			return nil, err
+		}
+	}
+
+	return auth.NewAuthenticator(apiKeys, jwtKeys, cfg.AuthJWTIssuer, cfg.AuthJWTAudience), nil
+}
+
+// newLimiter builds the rate limiter configured. The settings have already
+// been validated.
+func newLimiter(cfg *config.Config) *ratelimit.Limiter {
+	var def ratelimit.Limit
+	if cfg.RateLimit != "" {
+		def, _ = ratelimit.ParseLimit(cfg.RateLimit)
+	}
+
+	var opts []ratelimit.Option
+	routes, _ := ratelimit.ParseLimits(cfg.RateLimitRoutes)
+	for prefix, limit := range routes {
+		opts = append(opts, ratelimit.WithRouteLimit(prefix, limit))
+	}
+	clients, _ := ratelimit.ParseLimits(cfg.RateLimitClients)
+	for subject, limit := range clients {
+		opts = append(opts, ratelimit.WithClientLimit(subject, limit))
+	}
+
+	return ratelimit.New(ratelimit.NewMemoryStore(), def, opts...)
+}
+
+// corsMiddleware returns the configured CORS middleware, or nil if CORS is
+// disabled. It runs ahead of authentication, as preflight requests carry no
+// credentials.
+func corsMiddleware(cfg *config.Config) middleware.Middleware {
+	if cfg.CORSAllowedOrigins == "" {
+		return nil
+	}
+
+	return middleware.CORS(middleware.CORSOptions{
+		AllowedOrigins:   config.SplitList(cfg.CORSAllowedOrigins),
+		AllowedMethods:   config.SplitList(cfg.CORSAllowedMethods),
+		AllowedHeaders:   config.SplitList(cfg.CORSAllowedHeaders),
+		ExposedHeaders:   config.SplitList(cfg.CORSExposedHeaders),
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
+	})
+}
+
+// compressMiddleware returns the compression middleware, or nil if disabled.
+func compressMiddleware(cfg *config.Config) middleware.Middleware {
+	if !cfg.Compression {
+		return nil
+	}
+
+	return middleware.Compress(cfg.CompressionMinSize)
+}
+
+// securityMiddleware returns the security headers middleware, or nil if
+// disabled.
+func securityMiddleware(cfg *config.Config) middleware.Middleware {
+	if !cfg.SecurityHeaders {
+		return nil
+	}
+
+	return middleware.SecurityHeaders()
+}
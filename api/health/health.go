@@ -0,0 +1,120 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+
+	"git.neds.sh/matty/entain/api/logging"
+	commonlogging "git.neds.sh/matty/entain/common/logging"
+)
+
+// Backend is a gRPC service whose health contributes to gateway readiness.
+type Backend struct {
+	// Name identifies the backend in readiness responses.
+	Name string
+	// Client queries the backend's grpc.health.v1 service.
+	Client healthpb.HealthClient
+}
+
+// Response is the JSON body returned by the health endpoints.
+type Response struct {
+	Status   string            `json:"status"`
+	Backends map[string]string `json:"backends,omitempty"`
+}
+
+// LivenessHandler reports that the gateway process is up and serving HTTP.
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, http.StatusOK, Response{Status: "ok"})
+	})
+}
+
+// Readiness reports the gateway as ready only if every backend answers its
+// health check with SERVING within timeout, and the gateway itself is not
+// shutting down.
+type Readiness struct {
+	timeout  time.Duration
+	backends []Backend
+	draining int32
+}
+
+// NewReadiness creates a readiness handler aggregating the given backends.
+func NewReadiness(timeout time.Duration, backends ...Backend) *Readiness {
+	return &Readiness{timeout: timeout, backends: backends}
+}
+
+// SetDraining makes every subsequent readiness check fail, so load balancers
+// stop routing to the gateway before it shuts down.
+func (h *Readiness) SetDraining() {
+	atomic.StoreInt32(&h.draining, 1)
+}
+
+func (h *Readiness) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&h.draining) == 1 {
+		writeResponse(w, http.StatusServiceUnavailable, Response{Status: "draining"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	if requestID := logging.RequestIDFromContext(ctx); requestID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, commonlogging.RequestIDKey, requestID)
+	}
+
+	statuses := checkAll(ctx, h.backends)
+
+	code, resp := http.StatusOK, Response{Status: "ok", Backends: statuses}
+	for _, status := range statuses {
+		if status != healthpb.HealthCheckResponse_SERVING.String() {
+			code, resp.Status = http.StatusServiceUnavailable, "unavailable"
+			break
+		}
+	}
+
+	writeResponse(w, code, resp)
+}
+
+// checkAll queries every backend concurrently and returns their statuses by
+// name. Backends that cannot be reached are reported as UNKNOWN.
+func checkAll(ctx context.Context, backends []Backend) map[string]string {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		statuses = make(map[string]string, len(backends))
+	)
+
+	for _, backend := range backends {
+		wg.Add(1)
+		go func(backend Backend) {
+			defer wg.Done()
+
+			status := healthpb.HealthCheckResponse_UNKNOWN
+			resp, err := backend.Client.Check(ctx, &healthpb.HealthCheckRequest{})
+			if err == nil {
+				status = resp.Status
+			}
+
+			mu.Lock()
+			statuses[backend.Name] = status.String()
+			mu.Unlock()
+		}(backend)
+	}
+	wg.Wait()
+
+	return statuses
+}
+
+func writeResponse(w http.ResponseWriter, code int, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,82 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// fakeHealthClient answers health checks with a fixed status or error.
+type fakeHealthClient struct {
+	healthpb.HealthClient
+	status healthpb.HealthCheckResponse_ServingStatus
+	err    error
+}
+
+func (c fakeHealthClient) Check(ctx context.Context, in *healthpb.HealthCheckRequest, opts ...grpc.CallOption) (*healthpb.HealthCheckResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return &healthpb.HealthCheckResponse{Status: c.status}, nil
+}
+
+func TestReadinessHandler(t *testing.T) {
+	serving := fakeHealthClient{status: healthpb.HealthCheckResponse_SERVING}
+	notServing := fakeHealthClient{status: healthpb.HealthCheckResponse_NOT_SERVING}
+	unreachable := fakeHealthClient{err: errors.New("connection refused")}
+
+	tests := []struct {
+		name     string
+		backends []Backend
+		code     int
+		body     string
+	}{
+		{
+			name:     "AllServing",
+			backends: []Backend{{"racing", serving}, {"sports", serving}},
+			code:     http.StatusOK,
+			body:     `{"status":"ok","backends":{"racing":"SERVING","sports":"SERVING"}}`,
+		},
+		{
+			name:     "OneNotServing",
+			backends: []Backend{{"racing", serving}, {"sports", notServing}},
+			code:     http.StatusServiceUnavailable,
+			body:     `{"status":"unavailable","backends":{"racing":"SERVING","sports":"NOT_SERVING"}}`,
+		},
+		{
+			name:     "OneUnreachable",
+			backends: []Backend{{"racing", unreachable}, {"sports", serving}},
+			code:     http.StatusServiceUnavailable,
+			body:     `{"status":"unavailable","backends":{"racing":"UNKNOWN","sports":"SERVING"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			NewReadiness(time.Second, tt.backends...).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			assert.Equal(t, tt.code, rec.Code)
+			assert.JSONEq(t, tt.body, rec.Body.String())
+		})
+	}
+
+	t.Run("Draining", func(t *testing.T) {
+		readiness := NewReadiness(time.Second, Backend{"racing", serving})
+		readiness.SetDraining()
+
+		rec := httptest.NewRecorder()
+		readiness.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.JSONEq(t, `{"status":"draining"}`, rec.Body.String())
+	})
+}
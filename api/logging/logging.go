@@ -0,0 +1,99 @@
+// Package logging tags the gateway's HTTP requests with a request ID and logs
+// them, on top of the shared common/logging.
+package logging
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"git.neds.sh/matty/entain/common/logging"
+)
+
+const (
+	// RequestIDHeader is the HTTP header carrying the request correlation ID.
+	RequestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLength bounds the size of caller supplied request IDs.
+	maxRequestIDLength = 128
+)
+
+// requestIDKey is the context key of the request ID.
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the request ID stored in ctx by Middleware.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Middleware accepts the caller's X-Request-ID (or generates one), echoes it
+// on the response, stores it with a request scoped log entry in the request
+// context and writes an access log line once the request has been served.
+func Middleware(logger *logrus.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = logging.NewRequestID()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			entry := logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"method":     r.Method,
+				"path":       r.URL.Path,
+			})
+
+			ctx := context.WithValue(r.Context(), requestIDKey{}, requestID)
+			ctx = logging.WithEntry(ctx, entry)
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			entry.WithFields(logrus.Fields{
+				"status":   rec.status,
+				"bytes":    rec.bytes,
+				"duration": time.Since(start).String(),
+				"remote":   r.RemoteAddr,
+			}).Info("request completed")
+		})
+	}
+}
+
+// validRequestID reports whether a caller supplied request ID is safe to
+// propagate: non-empty, bounded in length and printable ASCII only.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// statusRecorder captures the status code and body size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
@@ -2,55 +2,177 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"flag"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"git.neds.sh/matty/entain/api/proto/racing"
-	"git.neds.sh/matty/entain/api/proto/sports"
-	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"git.neds.sh/matty/entain/api/backend"
+	"git.neds.sh/matty/entain/api/config"
+	"git.neds.sh/matty/entain/api/gateway"
+	commonlogging "git.neds.sh/matty/entain/common/logging"
+	"git.neds.sh/matty/entain/common/tlsutil"
+	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
-)
-
-var (
-	apiEndpoint        = flag.String("api-endpoint", "localhost:8000", "API endpoint")
-	grpcEndpointRacing = flag.String("grpc-endpoint-racing", "localhost:9000", "gRPC server endpoint")
-	grpcEndpointSports = flag.String("grpc-endpoint-sports", "localhost:9001", "gRPC server endpoint")
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
-	flag.Parse()
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("failed loading config: %s\n", err)
+	}
+
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("failed printing config: %s\n", err)
+		}
+		return
+	}
 
-	if err := run(); err != nil {
+	if err := run(cfg); err != nil {
 		log.Printf("failed running api server: %s\n", err)
 	}
 }
 
-func run() error {
-	ctx := context.Background()
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+func run(cfg *config.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger, err := commonlogging.New(cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		return err
+	}
+
+	transport, err := backendTransport(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	racingConn, err := dialBackend(ctx, cfg, "racing", cfg.GRPCEndpointRacing, transport)
+	if err != nil {
+		return err
+	}
+	defer racingConn.Close()
 
-	mux := runtime.NewServeMux()
-	if err := racing.RegisterRacingHandlerFromEndpoint(
-		ctx,
-		mux,
-		*grpcEndpointRacing,
-		[]grpc.DialOption{grpc.WithInsecure()},
-	); err != nil {
+	sportsConn, err := dialBackend(ctx, cfg, "sports", cfg.GRPCEndpointSports, transport)
+	if err != nil {
 		return err
 	}
+	defer sportsConn.Close()
+
+	handler, err := gateway.NewHandler(ctx, cfg, logger, racingConn, sportsConn)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:    cfg.APIEndpoint,
+		Handler: handler,
+	}
+
+	if cfg.TLSCertFile != "" {
+		reloader, err := tlsutil.NewReloader(cfg.TLSCertFile, cfg.TLSKeyFile, "")
+		if err != nil {
+			return err
+		}
+		go reloader.Run(commonlogging.WithEntry(ctx, logger.WithField("component", "tls")), cfg.TLSReloadInterval)
+
+		server.TLSConfig = reloader.ServerConfig(tls.NoClientCert, "h2", "http/1.1")
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if server.TLSConfig != nil {
+			serveErr <- server.ListenAndServeTLS("", "")
+		} else {
+			serveErr <- server.ListenAndServe()
+		}
+	}()
 
-	if err := sports.RegisterSportsHandlerFromEndpoint(
-		ctx,
-		mux,
-		*grpcEndpointSports,
-		[]grpc.DialOption{grpc.WithInsecure()},
-	); err != nil {
+	logger.Infof("API server listening on: %s", cfg.APIEndpoint)
+
+	select {
+	case err := <-serveErr:
 		return err
+	case <-ctx.Done():
+	}
+
+	// Fail readiness first so no new traffic is routed here, then drain.
+	logger.Info("shutdown signal received, failing readiness")
+	handler.Readiness.SetDraining()
+	time.Sleep(cfg.ShutdownDelay)
+
+	logger.Infof("draining in-flight requests (timeout %s)", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.WithError(err).Warn("shutdown timeout exceeded, closing remaining connections")
+		_ = server.Close()
+	}
+	logger.Info("API server stopped")
+
+	return nil
+}
+
+// backendTransport returns the transport credentials used to dial the
+// backends: plaintext, or TLS (presenting a client certificate for mutual TLS
+// if one is configured) with certificates reloaded on change.
+func backendTransport(ctx context.Context, cfg *config.Config, logger *logrus.Logger) (grpc.DialOption, error) {
+	if !cfg.BackendTLS {
+		return grpc.WithInsecure(), nil
+	}
+
+	reloader, err := tlsutil.NewReloader(cfg.BackendTLSCertFile, cfg.BackendTLSKeyFile, cfg.BackendTLSCAFile)
+	if err != nil {
+		return nil, err
 	}
+	go reloader.Run(commonlogging.WithEntry(ctx, logger.WithField("component", "backend-tls")), cfg.TLSReloadInterval)
+
+	return grpc.WithTransportCredentials(credentials.NewTLS(reloader.ClientConfig(""))), nil
+}
 
-	log.Printf("API server listening on: %s\n", *apiEndpoint)
+// retryMethods lists the backend RPCs that are safe to retry: idempotent reads.
+var retryMethods = []string{
+	"racing.Racing/ListRaces",
+	"racing.Racing/GetRace",
+	"racing.Racing/SearchRaces",
+	"racing.Racing/GetClock",
+	"sports.Sports/ListEvents",
+	"sports.Sports/GetEvent",
+	"sports.Sports/SearchEvents",
+	"sports.Sports/ListSportTypes",
+	"sports.Sports/GetClock",
+}
+
+// dialBackend connects to the named backend, balancing over its comma
+// separated endpoints, retrying reads it reports UNAVAILABLE and guarding calls
+// with a circuit breaker if one is configured.
+func dialBackend(ctx context.Context, cfg *config.Config, name, endpoints string, transport grpc.DialOption) (*grpc.ClientConn, error) {
+	sc, err := backend.ServiceConfig(backend.RetryPolicy{
+		Methods:        retryMethods,
+		MaxAttempts:    cfg.BackendRetryMaxAttempts,
+		InitialBackoff: cfg.BackendRetryInitialBackoff,
+		MaxBackoff:     cfg.BackendRetryMaxBackoff,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []grpc.DialOption{transport, grpc.WithDefaultServiceConfig(sc)}
+	if cfg.BackendBreakerFailures > 0 {
+		breaker := backend.NewBreaker(name, cfg.BackendBreakerFailures, cfg.BackendBreakerOpenDuration)
+		opts = append(opts, grpc.WithChainUnaryInterceptor(breaker.UnaryClientInterceptor()))
+	}
 
-	return http.ListenAndServe(*apiEndpoint, mux)
+	return backend.Dial(ctx, name, config.SplitList(endpoints), opts...)
 }
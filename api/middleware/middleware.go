@@ -0,0 +1,20 @@
+package middleware
+
+import "net/http"
+
+// Middleware wraps an http.Handler with extra behaviour.
+type Middleware func(http.Handler) http.Handler
+
+// Chain composes middlewares so that the first one given is the outermost,
+// i.e. sees the request first and the response last.
+func Chain(mws ...Middleware) Middleware {
+	return func(h http.Handler) http.Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			if mws[i] != nil {
+				h = mws[i](h)
+			}
+		}
+
+		return h
+	}
+}
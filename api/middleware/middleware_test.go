@@ -0,0 +1,240 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChain(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	h := Chain(mw("outer"), nil, mw("inner"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, []string{"outer", "inner", "handler"}, order)
+}
+
+func TestCORS(t *testing.T) {
+	served := false
+	h := CORS(CORSOptions{
+		AllowedOrigins: []string{"https://app.example.com", "https://*.partner.example"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type", "X-API-Key"},
+		ExposedHeaders: []string{"X-Request-ID"},
+		MaxAge:         10 * time.Minute,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = true
+	}))
+
+	request := func(method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+		served = false
+		r := httptest.NewRequest(method, "/v1/list-races", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	t.Run("SameOrigin", func(t *testing.T) {
+		rec := request(http.MethodPost, "", nil)
+		assert.True(t, served)
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("AllowedOrigin", func(t *testing.T) {
+		for _, origin := range []string{"https://app.example.com", "https://b2b.partner.example"} {
+			rec := request(http.MethodPost, origin, nil)
+			assert.True(t, served)
+			assert.Equal(t, origin, rec.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, "X-Request-ID", rec.Header().Get("Access-Control-Expose-Headers"))
+			assert.Contains(t, rec.Header().Values("Vary"), "Origin")
+		}
+	})
+
+	t.Run("DisallowedOrigin", func(t *testing.T) {
+		for _, origin := range []string{"https://evil.example", "http://app.example.com", "https://.partner.example"} {
+			rec := request(http.MethodPost, origin, nil)
+			assert.True(t, served)
+			assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"), origin)
+		}
+	})
+
+	t.Run("Preflight", func(t *testing.T) {
+		rec := request(http.MethodOptions, "https://app.example.com", map[string]string{
+			"Access-Control-Request-Method":  "POST",
+			"Access-Control-Request-Headers": "content-type, x-api-key",
+		})
+		assert.False(t, served)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "GET, POST", rec.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "Content-Type, X-API-Key", rec.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("PreflightRefused", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			origin, method, headers string
+		}{
+			"Origin":  {"https://evil.example", "POST", ""},
+			"Method":  {"https://app.example.com", "DELETE", ""},
+			"Headers": {"https://app.example.com", "POST", "X-Secret"},
+		} {
+			rec := request(http.MethodOptions, tc.origin, map[string]string{
+				"Access-Control-Request-Method":  tc.method,
+				"Access-Control-Request-Headers": tc.headers,
+			})
+			assert.False(t, served, name)
+			assert.Equal(t, http.StatusForbidden, rec.Code, name)
+		}
+	})
+}
+
+func TestCORSWildcard(t *testing.T) {
+	h := CORS(CORSOptions{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"POST"}})(http.NotFoundHandler())
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Origin", "https://anywhere.example")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat(`{"id":"1","name":"Race 1"},`, 100)
+	h := Compress(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/small" {
+			_, _ = io.WriteString(w, `{}`)
+			return
+		}
+		// written in pieces, as the gateway's marshaller may
+		_, _ = io.WriteString(w, body[:100])
+		_, _ = io.WriteString(w, body[100:])
+	}))
+
+	serve := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+	}
+
+	for acceptEncoding, want := range map[string]string{
+		"gzip":                     "gzip",
+		"gzip, deflate, br":        "br",
+		"br;q=0.5, gzip;q=0.8":     "gzip",
+		"*":                        "br",
+		"gzip;q=0, br;q=0":         "",
+		"identity":                 "",
+		"":                         "",
+		"deflate, *;q=0.1, br;q=0": "gzip",
+	} {
+		rec := serve("/large", acceptEncoding)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, want, rec.Header().Get("Content-Encoding"), acceptEncoding)
+		assert.Contains(t, rec.Header().Values("Vary"), "Accept-Encoding")
+
+		var got []byte
+		if want == "" {
+			got = rec.Body.Bytes()
+		} else {
+			r, err := decoders[want](rec.Body)
+			require.NoError(t, err)
+			got, err = io.ReadAll(r)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, body, string(got), acceptEncoding)
+	}
+
+	rec := serve("/small", "gzip, br")
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, `{}`, rec.Body.String())
+}
+
+func TestCompressSkipsBodilessResponses(t *testing.T) {
+	h := Compress(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Zero(t, rec.Body.Len())
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	h := SecurityHeaders()(http.NotFoundHandler())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", rec.Header().Get("X-Frame-Options"))
+	assert.Empty(t, rec.Header().Get("Strict-Transport-Security"))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.TLS = &tls.ConnectionState{}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	assert.NotEmpty(t, rec.Header().Get("Strict-Transport-Security"))
+}
+
+func TestDeadline(t *testing.T) {
+	var deadline time.Time
+	var ok bool
+	h := Deadline(time.Minute, map[string]time.Duration{
+		"/v1/list-": time.Second,
+		"/v1/race/": 0,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok = r.Context().Deadline()
+	}))
+
+	for path, want := range map[string]time.Duration{
+		"/v1/list-races": time.Second,
+		"/v1/event/1":    time.Minute,
+		"/v1/race/1":     0,
+	} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+		if want == 0 {
+			assert.False(t, ok, path)
+			continue
+		}
+		require.True(t, ok, path)
+		assert.WithinDuration(t, time.Now().Add(want), deadline, 100*time.Millisecond, path)
+	}
+}
@@ -0,0 +1,156 @@
+package openapi
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// specs are the OpenAPI v2 documents generated from the gateway's protos by
+// protoc-gen-openapiv2 (see proto/api.go), plus the hand written one for the
+// search endpoint, which has no proto of its own.
+//
+//go:embed racing/racing.swagger.json sports/sports.swagger.json search/search.swagger.json
+var specs embed.FS
+
+//go:embed ui
+var ui embed.FS
+
+// Info describes the merged API.
+type Info struct {
+	Title       string
+	Description string
+	Version     string
+}
+
+// Spec merges the embedded per-service documents into a single one. With
+// auth set it also declares the API key and bearer token security schemes and
+// requires one of them on every operation.
+func Spec(info Info, auth bool) ([]byte, error) {
+	merged := map[string]interface{}{
+		"swagger": "2.0",
+		"info": map[string]interface{}{
+			"title":       info.Title,
+			"description": info.Description,
+			"version":     info.Version,
+		},
+		"consumes": []string{"application/json"},
+		"produces": []string{"application/json"},
+	}
+	paths := make(map[string]interface{})
+	definitions := make(map[string]interface{})
+	var tags []interface{}
+
+	files, err := fs.Glob(specs, "*/*.swagger.json")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	for _, name := range files {
+		data, err := specs.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		var doc struct {
+			Tags        []interface{}          `json:"tags"`
+			Paths       map[string]interface{} `json:"paths"`
+			Definitions map[string]interface{} `json:"definitions"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", name, err)
+		}
+
+		tags = append(tags, doc.Tags...)
+		for path, item := range doc.Paths {
+			if _, ok := paths[path]; ok {
+				return nil, fmt.Errorf("%s: path %s is defined by another service", name, path)
+			}
+			paths[path] = item
+		}
+		// Shared types such as rpcStatus appear in every document; they
+		// must agree.
+		for def, schema := range doc.Definitions {
+			if existing, ok := definitions[def]; ok && !reflect.DeepEqual(existing, schema) {
+				return nil, fmt.Errorf("%s: definition %s conflicts with another service", name, def)
+			}
+			definitions[def] = schema
+		}
+	}
+
+	merged["tags"] = tags
+	merged["paths"] = paths
+	merged["definitions"] = definitions
+
+	if auth {
+		merged["securityDefinitions"] = map[string]interface{}{
+			"ApiKey": map[string]interface{}{
+				"type": "apiKey",
+				"in":   "header",
+				"name": "X-API-Key",
+			},
+			"Bearer": map[string]interface{}{
+				"type":        "apiKey",
+				"in":          "header",
+				"name":        "Authorization",
+				"description": "A JWT, given as \"Bearer <token>\".",
+			},
+		}
+		merged["security"] = []interface{}{
+			map[string]interface{}{"ApiKey": []string{}},
+			map[string]interface{}{"Bearer": []string{}},
+		}
+	}
+
+	return json.MarshalIndent(merged, "", "  ")
+}
+
+// Handler serves spec as JSON, letting clients revalidate it cheaply.
+func Handler(spec []byte) http.Handler {
+	sum := sha256.Sum256(spec)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		if strings.Contains(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(spec)
+	})
+}
+
+// DocsHandler serves the interactive API documentation under prefix, e.g.
+// "/docs/". The page renders the spec served by Handler at /openapi.json.
+func DocsHandler(prefix string) http.Handler {
+	sub, err := fs.Sub(ui, "ui")
+	if err != nil {
+		panic(err) // the embedded directory always exists
+	}
+	files := http.StripPrefix(prefix, http.FileServer(http.FS(sub)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The page only loads its own script and stylesheet, and fetches
+		// the spec and API from this origin.
+		w.Header().Set("Content-Security-Policy",
+			"default-src 'none'; script-src 'self'; style-src 'self'; connect-src 'self'; frame-ancestors 'none'")
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,76 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpec(t *testing.T) {
+	data, err := Spec(Info{Title: "Entain API", Version: "v1"}, false)
+	require.NoError(t, err)
+
+	var doc struct {
+		Swagger     string                            `json:"swagger"`
+		Info        map[string]string                 `json:"info"`
+		Paths       map[string]map[string]interface{} `json:"paths"`
+		Definitions map[string]interface{}            `json:"definitions"`
+		Security    []interface{}                     `json:"security"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc))
+
+	assert.Equal(t, "2.0", doc.Swagger)
+	assert.Equal(t, "Entain API", doc.Info["title"])
+	for _, path := range []string{"/v1/list-races", "/v1/race/{id}", "/v1/list-events", "/v1/event/{id}"} {
+		assert.Contains(t, doc.Paths, path)
+	}
+	for _, def := range []string{"racingListRacesRequestFilter", "sportsListEventsRequestFilter", "rpcStatus"} {
+		assert.Contains(t, doc.Definitions, def)
+	}
+	assert.Empty(t, doc.Security)
+
+	data, err = Spec(Info{Title: "Entain API", Version: "v1"}, true)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Len(t, doc.Security, 2)
+}
+
+func TestHandler(t *testing.T) {
+	h := Handler([]byte(`{"swagger":"2.0"}`))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `{"swagger":"2.0"}`, rec.Body.String())
+
+	r := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	r.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/openapi.json", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestDocsHandler(t *testing.T) {
+	h := DocsHandler("/docs/")
+
+	for path, contentType := range map[string]string{
+		"/docs/":         "text/html; charset=utf-8",
+		"/docs/docs.js":  "text/javascript; charset=utf-8",
+		"/docs/docs.css": "text/css; charset=utf-8",
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+		assert.Equal(t, contentType, rec.Header().Get("Content-Type"), path)
+		assert.Contains(t, rec.Header().Get("Content-Security-Policy"), "script-src 'self'", path)
+	}
+}
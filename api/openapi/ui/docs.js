@@ -0,0 +1,253 @@
+// Renders the gateway's OpenAPI document as browsable, executable docs.
+(function () {
+  "use strict";
+
+  var spec;
+
+  function el(tag, attrs, children) {
+    var node = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (key) {
+      if (key === "text") {
+        node.textContent = attrs[key];
+      } else {
+        node.setAttribute(key, attrs[key]);
+      }
+    });
+    (children || []).forEach(function (child) {
+      if (child) {
+        node.appendChild(child);
+      }
+    });
+    return node;
+  }
+
+  function resolve(schema) {
+    var seen = 0;
+    while (schema && schema.$ref && seen++ < 32) {
+      var ref = schema.$ref.replace("#/definitions/", "");
+      schema = Object.assign({}, spec.definitions[ref], { description: schema.description || spec.definitions[ref].description });
+    }
+    return schema || {};
+  }
+
+  function typeName(schema) {
+    if (schema.$ref) {
+      var target = resolve(schema);
+      return target.enum ? "enum" : schema.$ref.replace("#/definitions/", "");
+    }
+    if (schema.type === "array") {
+      return typeName(schema.items || {}) + "[]";
+    }
+    return schema.format ? schema.type + " (" + schema.format + ")" : schema.type || "object";
+  }
+
+  function describe(schema) {
+    var text = schema.description || schema.title || "";
+    var target = resolve(schema);
+    if (target.enum) {
+      text += (text ? "\n" : "") + "One of: " + target.enum.join(", ");
+    }
+    return text;
+  }
+
+  // fieldRows flattens a schema into table rows, nesting object fields under
+  // their parent's name.
+  function fieldRows(schema, prefix, depth, rows) {
+    var target = resolve(schema);
+    if (target.type === "array") {
+      target = resolve(target.items || {});
+      prefix += "[]";
+    }
+    Object.keys(target.properties || {}).forEach(function (name) {
+      var field = target.properties[name];
+      rows.push(el("tr", {}, [
+        el("td", { class: "name", text: prefix + name }),
+        el("td", { class: "type", text: typeName(field) }),
+        el("td", { class: "description", text: describe(field) })
+      ]));
+      var nested = resolve(field.type === "array" ? field.items || {} : field);
+      if (nested.properties && depth < 4) {
+        fieldRows(field, prefix + name + ".", depth + 1, rows);
+      }
+    });
+    return rows;
+  }
+
+  function fieldTable(schema) {
+    var rows = fieldRows(schema, "", 0, []);
+    if (!rows.length) {
+      return el("p", { text: "No fields." });
+    }
+    return el("table", {}, [
+      el("thead", {}, [el("tr", {}, [el("th", { text: "Field" }), el("th", { text: "Type" }), el("th", { text: "Description" })])]),
+      el("tbody", {}, rows)
+    ]);
+  }
+
+  // example builds a request body skeleton showing every field.
+  function example(schema, depth) {
+    var target = resolve(schema);
+    if (depth > 4) {
+      return null;
+    }
+    if (target.enum) {
+      return target.enum[0];
+    }
+    switch (target.type) {
+      case "array":
+        return [];
+      case "boolean":
+        return false;
+      case "integer":
+      case "number":
+        return 0;
+      case "string":
+        return target.format === "date-time" ? new Date().toISOString() : "";
+    }
+    var out = {};
+    Object.keys(target.properties || {}).forEach(function (name) {
+      out[name] = example(target.properties[name], depth + 1);
+    });
+    return out;
+  }
+
+  function credentialHeaders() {
+    var headers = {};
+    var key = document.getElementById("api-key").value;
+    var bearer = document.getElementById("bearer").value;
+    if (key) {
+      headers["X-API-Key"] = key;
+    } else if (bearer) {
+      headers.Authorization = "Bearer " + bearer;
+    }
+    return headers;
+  }
+
+  function tryIt(path, method, op) {
+    var params = (op.parameters || []).filter(function (p) { return p.in === "path"; });
+    var body = (op.parameters || []).filter(function (p) { return p.in === "body"; })[0];
+
+    var inputs = {};
+    var form = el("div", { class: "try" }, [el("h4", { text: "Try it" })]);
+    params.forEach(function (p) {
+      inputs[p.name] = el("input", { type: "text", placeholder: p.name });
+      form.appendChild(el("label", { text: p.name + " " }, [inputs[p.name]]));
+    });
+
+    var textarea;
+    if (body) {
+      textarea = el("textarea", { spellcheck: "false" });
+      textarea.value = JSON.stringify(example(body.schema, 0), null, 2);
+      form.appendChild(textarea);
+    }
+
+    var output = el("pre", { hidden: "" });
+    var status = el("p", {});
+    var button = el("button", { type: "button", text: "Send" });
+    button.addEventListener("click", function () {
+      var url = path.replace(/\{([^}]+)\}/g, function (_, name) {
+        return encodeURIComponent(inputs[name] ? inputs[name].value : "");
+      });
+      var headers = credentialHeaders();
+      var init = { method: method.toUpperCase(), headers: headers };
+      if (textarea) {
+        headers["Content-Type"] = "application/json";
+        init.body = textarea.value;
+      }
+      status.textContent = "Sending…";
+      fetch(url, init).then(function (res) {
+        status.textContent = res.status + " " + res.statusText;
+        status.className = res.ok ? "status-ok" : "status-error";
+        return res.text();
+      }).then(function (text) {
+        try {
+          text = JSON.stringify(JSON.parse(text), null, 2);
+        } catch (e) {
+          // not JSON; show as is
+        }
+        output.textContent = text;
+        output.hidden = false;
+      }).catch(function (err) {
+        status.textContent = String(err);
+        status.className = "status-error";
+      });
+    });
+    form.appendChild(el("p", {}, [button]));
+    form.appendChild(status);
+    form.appendChild(output);
+    return form;
+  }
+
+  function operation(path, method, op) {
+    var body = el("div", { class: "body" });
+    if (op.description) {
+      body.appendChild(el("p", { text: op.description }));
+    }
+
+    (op.parameters || []).forEach(function (p) {
+      if (p.in === "path") {
+        body.appendChild(el("p", {}, [
+          el("strong", { text: "Path parameter " }),
+          el("code", { text: p.name }),
+          document.createTextNode(" (" + (p.format || p.type) + ") " + (p.description || ""))
+        ]));
+      } else if (p.in === "body") {
+        body.appendChild(el("h4", { text: "Request body" }));
+        body.appendChild(fieldTable(p.schema));
+      }
+    });
+
+    var ok = (op.responses || {})["200"];
+    if (ok && ok.schema) {
+      body.appendChild(el("h4", { text: "Response" }));
+      body.appendChild(fieldTable(ok.schema));
+    }
+
+    body.appendChild(tryIt(path, method, op));
+
+    return el("details", { class: "operation" }, [
+      el("summary", {}, [
+        el("span", { class: "method", text: method.toUpperCase() }),
+        document.createTextNode(path),
+        el("span", { class: "summary", text: op.summary || "" })
+      ]),
+      body
+    ]);
+  }
+
+  function render() {
+    document.getElementById("title").textContent = spec.info.title;
+    document.title = spec.info.title;
+    document.getElementById("description").textContent = spec.info.description || "";
+    document.getElementById("credentials").hidden = !spec.securityDefinitions;
+
+    var main = document.getElementById("operations");
+    main.textContent = "";
+
+    var byTag = {};
+    Object.keys(spec.paths).sort().forEach(function (path) {
+      Object.keys(spec.paths[path]).forEach(function (method) {
+        var op = spec.paths[path][method];
+        var tag = (op.tags || ["Other"])[0];
+        (byTag[tag] = byTag[tag] || []).push(operation(path, method, op));
+      });
+    });
+
+    Object.keys(byTag).sort().forEach(function (tag) {
+      main.appendChild(el("h2", { text: tag }));
+      byTag[tag].forEach(function (node) { main.appendChild(node); });
+    });
+  }
+
+  fetch("../openapi.json").then(function (res) {
+    if (!res.ok) {
+      throw new Error("loading openapi.json: " + res.status);
+    }
+    return res.json();
+  }).then(function (doc) {
+    spec = doc;
+    render();
+  }).catch(function (err) {
+    document.getElementById("operations").textContent = String(err);
+  });
+})();
@@ -19,8 +19,12 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Sports_ListEvents_FullMethodName = "/sports.Sports/ListEvents"
-	Sports_GetEvent_FullMethodName   = "/sports.Sports/GetEvent"
+	Sports_ListEvents_FullMethodName     = "/sports.Sports/ListEvents"
+	Sports_GetEvent_FullMethodName       = "/sports.Sports/GetEvent"
+	Sports_SearchEvents_FullMethodName   = "/sports.Sports/SearchEvents"
+	Sports_ListSportTypes_FullMethodName = "/sports.Sports/ListSportTypes"
+	Sports_GetClock_FullMethodName       = "/sports.Sports/GetClock"
+	Sports_SetClock_FullMethodName       = "/sports.Sports/SetClock"
 )
 
 // SportsClient is the client API for Sports service.
@@ -31,6 +35,18 @@ type SportsClient interface {
 	ListEvents(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (*ListEventsResponse, error)
 	// GetEvent returns a single Event based on the given ID.
 	GetEvent(ctx context.Context, in *GetEventRequest, opts ...grpc.CallOption) (*Event, error)
+	// SearchEvents returns the events whose name or sports_type match a free
+	// text query, best matches first.
+	SearchEvents(ctx context.Context, in *SearchEventsRequest, opts ...grpc.CallOption) (*SearchEventsResponse, error)
+	// ListSportTypes returns the types of sport events are of, by name.
+	ListSportTypes(ctx context.Context, in *ListSportTypesRequest, opts ...grpc.CallOption) (*ListSportTypesResponse, error)
+	// GetClock returns the time the service takes as now, e.g. to derive
+	// event statuses.
+	GetClock(ctx context.Context, in *GetClockRequest, opts ...grpc.CallOption) (*Clock, error)
+	// SetClock makes the service take a simulated time as now, running on from
+	// there, or the real time again if none is given. It is only available when
+	// the service runs with --simulated-clock.
+	SetClock(ctx context.Context, in *SetClockRequest, opts ...grpc.CallOption) (*Clock, error)
 }
 
 type sportsClient struct {
@@ -59,6 +75,42 @@ func (c *sportsClient) GetEvent(ctx context.Context, in *GetEventRequest, opts .
 	return out, nil
 }
 
+func (c *sportsClient) SearchEvents(ctx context.Context, in *SearchEventsRequest, opts ...grpc.CallOption) (*SearchEventsResponse, error) {
+	out := new(SearchEventsResponse)
+	err := c.cc.Invoke(ctx, Sports_SearchEvents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sportsClient) ListSportTypes(ctx context.Context, in *ListSportTypesRequest, opts ...grpc.CallOption) (*ListSportTypesResponse, error) {
+	out := new(ListSportTypesResponse)
+	err := c.cc.Invoke(ctx, Sports_ListSportTypes_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sportsClient) GetClock(ctx context.Context, in *GetClockRequest, opts ...grpc.CallOption) (*Clock, error) {
+	out := new(Clock)
+	err := c.cc.Invoke(ctx, Sports_GetClock_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sportsClient) SetClock(ctx context.Context, in *SetClockRequest, opts ...grpc.CallOption) (*Clock, error) {
+	out := new(Clock)
+	err := c.cc.Invoke(ctx, Sports_SetClock_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SportsServer is the server API for Sports service.
 // All implementations must embed UnimplementedSportsServer
 // for forward compatibility
@@ -67,6 +119,18 @@ type SportsServer interface {
 	ListEvents(context.Context, *ListEventsRequest) (*ListEventsResponse, error)
 	// GetEvent returns a single Event based on the given ID.
 	GetEvent(context.Context, *GetEventRequest) (*Event, error)
+	// SearchEvents returns the events whose name or sports_type match a free
+	// text query, best matches first.
+	SearchEvents(context.Context, *SearchEventsRequest) (*SearchEventsResponse, error)
+	// ListSportTypes returns the types of sport events are of, by name.
+	ListSportTypes(context.Context, *ListSportTypesRequest) (*ListSportTypesResponse, error)
+	// GetClock returns the time the service takes as now, e.g. to derive
+	// event statuses.
+	GetClock(context.Context, *GetClockRequest) (*Clock, error)
+	// SetClock makes the service take a simulated time as now, running on from
+	// there, or the real time again if none is given. It is only available when
+	// the service runs with --simulated-clock.
+	SetClock(context.Context, *SetClockRequest) (*Clock, error)
 	mustEmbedUnimplementedSportsServer()
 }
 
@@ -80,6 +144,18 @@ func (UnimplementedSportsServer) ListEvents(context.Context, *ListEventsRequest)
 func (UnimplementedSportsServer) GetEvent(context.Context, *GetEventRequest) (*Event, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetEvent not implemented")
 }
+func (UnimplementedSportsServer) SearchEvents(context.Context, *SearchEventsRequest) (*SearchEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchEvents not implemented")
+}
+func (UnimplementedSportsServer) ListSportTypes(context.Context, *ListSportTypesRequest) (*ListSportTypesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSportTypes not implemented")
+}
+func (UnimplementedSportsServer) GetClock(context.Context, *GetClockRequest) (*Clock, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetClock not implemented")
+}
+func (UnimplementedSportsServer) SetClock(context.Context, *SetClockRequest) (*Clock, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetClock not implemented")
+}
 func (UnimplementedSportsServer) mustEmbedUnimplementedSportsServer() {}
 
 // UnsafeSportsServer may be embedded to opt out of forward compatibility for this service.
@@ -129,6 +205,78 @@ func _Sports_GetEvent_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Sports_SearchEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SportsServer).SearchEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sports_SearchEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SportsServer).SearchEvents(ctx, req.(*SearchEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sports_ListSportTypes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSportTypesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SportsServer).ListSportTypes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sports_ListSportTypes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SportsServer).ListSportTypes(ctx, req.(*ListSportTypesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sports_GetClock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetClockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SportsServer).GetClock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sports_GetClock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SportsServer).GetClock(ctx, req.(*GetClockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sports_SetClock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetClockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SportsServer).SetClock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sports_SetClock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SportsServer).SetClock(ctx, req.(*SetClockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Sports_ServiceDesc is the grpc.ServiceDesc for Sports service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -144,6 +292,22 @@ var Sports_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetEvent",
 			Handler:    _Sports_GetEvent_Handler,
 		},
+		{
+			MethodName: "SearchEvents",
+			Handler:    _Sports_SearchEvents_Handler,
+		},
+		{
+			MethodName: "ListSportTypes",
+			Handler:    _Sports_ListSportTypes_Handler,
+		},
+		{
+			MethodName: "GetClock",
+			Handler:    _Sports_GetClock_Handler,
+		},
+		{
+			MethodName: "SetClock",
+			Handler:    _Sports_SetClock_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "sports/sports.proto",
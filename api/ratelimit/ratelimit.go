@@ -0,0 +1,231 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.neds.sh/matty/entain/api/auth"
+	"git.neds.sh/matty/entain/common/logging"
+)
+
+// Limit is a token bucket: Burst requests may be made at once, refilling at
+// Rate requests per second. A Rate of zero or less means unlimited.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) unlimited() bool {
+	return l.Rate <= 0
+}
+
+// duration is how long a bucket takes to refill the given tokens.
+func (l Limit) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.Rate * float64(time.Second))
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of whole tokens left in the bucket.
+	Remaining int
+	// RetryAfter is how long until the next token is available, if the
+	// request was not allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store holds the token buckets. It is an interface so that gateway replicas
+// can share buckets through an external store.
+type Store interface {
+	// Take removes a token from the bucket identified by key, creating it
+	// full if it does not exist.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Limiter decides which limit applies to a request and enforces it.
+type Limiter struct {
+	store   Store
+	def     Limit
+	routes  []route
+	clients map[string]Limit
+}
+
+type route struct {
+	prefix string
+	limit  Limit
+}
+
+// Option configures a Limiter.
+type Option func(*Limiter)
+
+// WithRouteLimit applies limit instead of the default to paths starting with
+// prefix. The longest matching prefix wins.
+func WithRouteLimit(prefix string, limit Limit) Option {
+	return func(l *Limiter) {
+		l.routes = append(l.routes, route{prefix: prefix, limit: limit})
+		sort.SliceStable(l.routes, func(i, j int) bool {
+			return len(l.routes[i].prefix) > len(l.routes[j].prefix)
+		})
+	}
+}
+
+// WithClientLimit applies limit to every route for the authenticated subject,
+// overriding the default and route limits, e.g. for a partner with a larger
+// quota.
+func WithClientLimit(subject string, limit Limit) Option {
+	return func(l *Limiter) {
+		l.clients[subject] = limit
+	}
+}
+
+// New creates a limiter applying def to every route and client unless
+// overridden by an option.
+func New(store Store, def Limit, opts ...Option) *Limiter {
+	l := &Limiter{store: store, def: def, clients: make(map[string]Limit)}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Middleware answers 429 Too Many Requests once a client exceeds its limit,
+// with Retry-After and RateLimit-* headers telling it when to come back.
+// Clients are identified by their authenticated subject, or by IP address when
+// authentication is disabled, and have a bucket per route.
+//
+// Requests are let through if the store fails, so an outage of a shared store
+// does not take the gateway down with it.
+func Middleware(l *Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, subject := clientKey(r)
+			limit, bucket := l.limitFor(r.URL.Path, subject)
+			if limit.unlimited() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := l.store.Take(r.Context(), client+" "+bucket, limit)
+			if err != nil {
+				logging.FromContext(r.Context()).WithError(err).Warn("rate limit store failed, allowing request")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+			h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Burst, seconds(limit.duration(float64(limit.Burst)))))
+
+			if !res.Allowed {
+				h.Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+				h.Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"code":    8, // codes.ResourceExhausted, matching the gateway's error bodies
+					"message": "rate limit exceeded",
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitFor returns the limit applying to path for subject, and the name of
+// the bucket it is counted in.
+func (l *Limiter) limitFor(path, subject string) (Limit, string) {
+	limit, bucket := l.def, "*"
+	for _, rt := range l.routes {
+		if strings.HasPrefix(path, rt.prefix) {
+			limit, bucket = rt.limit, rt.prefix
+			break
+		}
+	}
+
+	if subject != "" {
+		if cl, ok := l.clients[subject]; ok {
+			limit = cl
+		}
+	}
+
+	return limit, bucket
+}
+
+// clientKey identifies the caller of r, returning its authenticated subject
+// if there is one.
+func clientKey(r *http.Request) (string, string) {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		return "subject:" + p.Subject, p.Subject
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host, ""
+}
+
+// seconds rounds d up to whole seconds, as the headers require.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// ParseLimit parses a limit written as "rate:burst", e.g. "5:10" for five
+// requests per second with bursts of up to ten.
+func ParseLimit(s string) (Limit, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("limit %q: must be rate:burst", s)
+	}
+
+	rate, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || rate < 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		return Limit{}, fmt.Errorf("limit %q: rate must be a non-negative number", s)
+	}
+	burst, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || (rate > 0 && burst < 1) {
+		return Limit{}, fmt.Errorf("limit %q: burst must be a positive integer", s)
+	}
+
+	return Limit{Rate: rate, Burst: burst}, nil
+}
+
+// ParseLimits parses a comma separated list of "name=rate:burst" entries,
+// e.g. "/v1/list-races=5:10,/v1/race/=20:40".
+func ParseLimits(s string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+	if strings.TrimSpace(s) == "" {
+		return limits, nil
+	}
+
+	for _, entry := range strings.Split(s, ",") {
+		parts := strings.SplitN(entry, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" {
+			return nil, fmt.Errorf("entry %q: must be name=rate:burst", entry)
+		}
+
+		limit, err := ParseLimit(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("entry %q: %w", entry, err)
+		}
+		limits[name] = limit
+	}
+
+	return limits, nil
+}
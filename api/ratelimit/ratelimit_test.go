@@ -0,0 +1,155 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/api/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a manually advanced clock for the memory store.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestStore() (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC)}
+	s := NewMemoryStore()
+	s.now = clock.Now
+	return s, clock
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	s, clock := newTestStore()
+	limit := Limit{Rate: 2, Burst: 3}
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		res, err := s.Take(ctx, "k", limit)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res, err := s.Take(ctx, "k", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, res.Reset)
+
+	// other keys have their own bucket
+	res, err = s.Take(ctx, "other", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	res, err = s.Take(ctx, "k", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s, clock := newTestStore()
+	limit := Limit{Rate: 1, Burst: 1}
+
+	_, _ = s.Take(context.Background(), "idle", limit)
+	clock.now = clock.now.Add(sweepInterval)
+	_, _ = s.Take(context.Background(), "active", limit)
+
+	assert.NotContains(t, s.buckets, "idle")
+	assert.Contains(t, s.buckets, "active")
+}
+
+func TestMiddleware(t *testing.T) {
+	s, _ := newTestStore()
+	l := New(s, Limit{Rate: 1, Burst: 2},
+		WithRouteLimit("/v1/list-races", Limit{Rate: 1, Burst: 1}),
+		WithRouteLimit("/v1/race/", Limit{}),
+		WithClientLimit("partner-a", Limit{Rate: 10, Burst: 5}),
+	)
+	handler := Middleware(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	authenticated := auth.Middleware(auth.NewAuthenticator([]auth.APIKey{{Key: "partner-a-key", Subject: "partner-a"}}, nil, "", ""))(handler)
+
+	serve := func(path, remote, apiKey string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, nil)
+		r.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		if apiKey != "" {
+			r.Header.Set(auth.APIKeyHeader, apiKey)
+			authenticated.ServeHTTP(rec, r)
+		} else {
+			handler.ServeHTTP(rec, r)
+		}
+		return rec
+	}
+
+	t.Run("RouteLimit", func(t *testing.T) {
+		rec := serve("/v1/list-races", "10.0.0.1:1234", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "1", rec.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+		rec = serve("/v1/list-races", "10.0.0.1:5678", "")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"code":8,"message":"rate limit exceeded"}`, rec.Body.String())
+
+		// a different client IP is unaffected
+		assert.Equal(t, http.StatusOK, serve("/v1/list-races", "10.0.0.2:1234", "").Code)
+		// as is a different route for the same client
+		assert.Equal(t, http.StatusOK, serve("/v1/list-events", "10.0.0.1:1234", "").Code)
+	})
+
+	t.Run("Unlimited", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			rec := serve("/v1/race/1", "10.0.0.3:1234", "")
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+		}
+	})
+
+	t.Run("ClientLimit", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, http.StatusOK, serve("/v1/list-races", "10.0.0.4:1234", "partner-a-key").Code)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, serve("/v1/list-races", "10.0.0.4:1234", "partner-a-key").Code)
+	})
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit) (Result, error) {
+	return Result{}, errors.New("store unavailable")
+}
+
+func TestMiddlewareFailsOpen(t *testing.T) {
+	handler := Middleware(New(failingStore{}, Limit{Rate: 1, Burst: 1}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/list-races", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("/v1/list-races=5:10, /v1/race/=0.5:1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Limit{
+		"/v1/list-races": {Rate: 5, Burst: 10},
+		"/v1/race/":      {Rate: 0.5, Burst: 1},
+	}, limits)
+
+	for _, bad := range []string{"/v1/list-races", "/v1/list-races=5", "=5:10", "/v1/list-races=-1:10", "/v1/list-races=5:0"} {
+		_, err := ParseLimits(bad)
+		assert.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,145 @@
+// Package search serves the gateway's search endpoint, which fans a text query
+// out to the racing and sports services and merges their results.
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"git.neds.sh/matty/entain/api/proto/sports"
+)
+
+// Path is where the search endpoint is served.
+const Path = "/v1/search"
+
+// defaultLimit bounds the merged results when the request gives no limit,
+// matching the services' own default.
+const defaultLimit = 20
+
+// Response is the JSON body returned by the search endpoint.
+type Response struct {
+	Results []Result `json:"results"`
+}
+
+// Result is a single match: a race or a sports event, depending on Type.
+type Result struct {
+	// Type is "race" or "event".
+	Type string `json:"type"`
+	// Score ranks the result, higher being better.
+	Score float64 `json:"score"`
+	// Highlights maps matched fields to their value with the matching words
+	// wrapped in <mark></mark>.
+	Highlights map[string]string `json:"highlights,omitempty"`
+	Race       json.RawMessage   `json:"race,omitempty"`
+	Event      json.RawMessage   `json:"event,omitempty"`
+}
+
+// Register serves the search endpoint on mux, so requests are annotated and
+// errors rendered just like those of the generated gateway handlers.
+func Register(mux *runtime.ServeMux, racingClient racing.RacingClient, sportsClient sports.SportsClient) error {
+	return mux.HandlePath(http.MethodGet, Path, Handler(mux, racingClient, sportsClient))
+}
+
+// Handler searches races and sports events concurrently for the q query
+// parameter, returning up to limit results across both, best first. It fails
+// if either service does.
+func Handler(mux *runtime.ServeMux, racingClient racing.RacingClient, sportsClient sports.SportsClient) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		_, marshaler := runtime.MarshalerForRequest(mux, r)
+
+		ctx, err := runtime.AnnotateContext(r.Context(), mux, r, Path)
+		if err != nil {
+			runtime.HTTPError(r.Context(), mux, marshaler, w, r, err)
+			return
+		}
+
+		query := r.URL.Query().Get("q")
+		var limit int
+		if s := r.URL.Query().Get("limit"); s != "" {
+			if limit, err = strconv.Atoi(s); err != nil {
+				runtime.HTTPError(ctx, mux, marshaler, w, r, status.Errorf(codes.InvalidArgument, "invalid limit %q", s))
+				return
+			}
+		}
+
+		results, err := search(ctx, marshaler, racingClient, sportsClient, query, limit)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, marshaler, w, r, err)
+			return
+		}
+
+		body, err := json.Marshal(Response{Results: results})
+		if err != nil {
+			runtime.HTTPError(ctx, mux, marshaler, w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}
+}
+
+// search queries both services and merges their results by score.
+func search(ctx context.Context, marshaler runtime.Marshaler, racingClient racing.RacingClient, sportsClient sports.SportsClient, query string, limit int) ([]Result, error) {
+	var (
+		wg                  sync.WaitGroup
+		races               *racing.SearchRacesResponse
+		events              *sports.SearchEventsResponse
+		racingErr, sportErr error
+	)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		races, racingErr = racingClient.SearchRaces(ctx, &racing.SearchRacesRequest{Query: query, Limit: int32(limit)})
+	}()
+	go func() {
+		defer wg.Done()
+		events, sportErr = sportsClient.SearchEvents(ctx, &sports.SearchEventsRequest{Query: query, Limit: int32(limit)})
+	}()
+	wg.Wait()
+
+	if racingErr != nil {
+		return nil, racingErr
+	}
+	if sportErr != nil {
+		return nil, sportErr
+	}
+
+	results := make([]Result, 0, len(races.Results)+len(events.Results))
+	for _, match := range races.Results {
+		race, err := marshaler.Marshal(match.Race)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, Result{Type: "race", Score: match.Score, Highlights: match.Highlights, Race: race})
+	}
+	for _, match := range events.Results {
+		event, err := marshaler.Marshal(match.Event)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, Result{Type: "event", Score: match.Score, Highlights: match.Highlights, Event: event})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
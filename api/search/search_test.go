@@ -0,0 +1,176 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"git.neds.sh/matty/entain/api/proto/sports"
+)
+
+// fakeRacingClient answers searches with fixed results or an error,
+// recording the request and its outgoing metadata.
+type fakeRacingClient struct {
+	racing.RacingClient
+	results []*racing.RaceSearchResult
+	err     error
+	req     *racing.SearchRacesRequest
+	md      metadata.MD
+}
+
+func (c *fakeRacingClient) SearchRaces(ctx context.Context, in *racing.SearchRacesRequest, opts ...grpc.CallOption) (*racing.SearchRacesResponse, error) {
+	c.req = in
+	c.md, _ = metadata.FromOutgoingContext(ctx)
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return &racing.SearchRacesResponse{Results: c.results}, nil
+}
+
+// fakeSportsClient answers searches with fixed results or an error.
+type fakeSportsClient struct {
+	sports.SportsClient
+	results []*sports.EventSearchResult
+	err     error
+	req     *sports.SearchEventsRequest
+}
+
+func (c *fakeSportsClient) SearchEvents(ctx context.Context, in *sports.SearchEventsRequest, opts ...grpc.CallOption) (*sports.SearchEventsResponse, error) {
+	c.req = in
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return &sports.SearchEventsResponse{Results: c.results}, nil
+}
+
+// summary decodes a search response into "type:id score" lines, with the
+// highlights of each result.
+func summary(t *testing.T, body []byte) ([]string, []map[string]string) {
+	t.Helper()
+
+	var resp struct {
+		Results []struct {
+			Type       string               `json:"type"`
+			Score      float64              `json:"score"`
+			Highlights map[string]string    `json:"highlights"`
+			Race       *struct{ ID string } `json:"race"`
+			Event      *struct{ ID string } `json:"event"`
+		} `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(body, &resp))
+
+	var (
+		lines      []string
+		highlights []map[string]string
+	)
+	for _, result := range resp.Results {
+		id := ""
+		switch {
+		case result.Race != nil:
+			id = result.Race.ID
+		case result.Event != nil:
+			id = result.Event.ID
+		}
+		lines = append(lines, fmt.Sprintf("%s:%s %g", result.Type, id, result.Score))
+		highlights = append(highlights, result.Highlights)
+	}
+
+	return lines, highlights
+}
+
+func serve(t *testing.T, racingClient racing.RacingClient, sportsClient sports.SportsClient, target string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	mux := runtime.NewServeMux(runtime.WithMetadata(func(context.Context, *http.Request) metadata.MD {
+		return metadata.Pairs("x-request-id", "abc")
+	}))
+	require.NoError(t, Register(mux, racingClient, sportsClient))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestHandler(t *testing.T) {
+	racingClient := &fakeRacingClient{results: []*racing.RaceSearchResult{
+		{Race: &racing.Race{Id: 1, Name: "Flemington Cup"}, Score: 1.5, Highlights: map[string]string{"name": "<mark>Flemington</mark> Cup"}},
+		{Race: &racing.Race{Id: 2, Name: "Flemington Plate"}, Score: 0.5, Highlights: map[string]string{"name": "<mark>Flemington</mark> Plate"}},
+	}}
+	sportsClient := &fakeSportsClient{results: []*sports.EventSearchResult{
+		{Event: &sports.Event{Id: 3, Name: "Flemington Derby"}, Score: 1, Highlights: map[string]string{"name": "<mark>Flemington</mark> Derby"}},
+	}}
+
+	rec := serve(t, racingClient, sportsClient, "/v1/search?q=flem")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	lines, highlights := summary(t, rec.Body.Bytes())
+	assert.Equal(t, []string{"race:1 1.5", "event:3 1", "race:2 0.5"}, lines)
+	assert.Equal(t, []map[string]string{
+		{"name": "<mark>Flemington</mark> Cup"},
+		{"name": "<mark>Flemington</mark> Derby"},
+		{"name": "<mark>Flemington</mark> Plate"},
+	}, highlights)
+
+	assert.Equal(t, "flem", racingClient.req.Query)
+	assert.Equal(t, "flem", sportsClient.req.Query)
+	assert.Equal(t, []string{"abc"}, racingClient.md.Get("x-request-id"))
+}
+
+func TestHandlerLimit(t *testing.T) {
+	racingClient := &fakeRacingClient{results: []*racing.RaceSearchResult{
+		{Race: &racing.Race{Id: 1}, Score: 3},
+		{Race: &racing.Race{Id: 2}, Score: 1},
+	}}
+	sportsClient := &fakeSportsClient{results: []*sports.EventSearchResult{
+		{Event: &sports.Event{Id: 3}, Score: 2},
+	}}
+
+	rec := serve(t, racingClient, sportsClient, "/v1/search?q=x&limit=2")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	lines, _ := summary(t, rec.Body.Bytes())
+	assert.Equal(t, []string{"race:1 3", "event:3 2"}, lines)
+	assert.Equal(t, int32(2), racingClient.req.Limit)
+	assert.Equal(t, int32(2), sportsClient.req.Limit)
+}
+
+func TestHandlerErrors(t *testing.T) {
+	t.Run("InvalidLimit", func(t *testing.T) {
+		rec := serve(t, &fakeRacingClient{}, &fakeSportsClient{}, "/v1/search?q=x&limit=lots")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"code":3,"message":"invalid limit \"lots\"","details":[]}`, rec.Body.String())
+	})
+
+	t.Run("BackendError", func(t *testing.T) {
+		sportsClient := &fakeSportsClient{err: status.Error(codes.Unavailable, "sports backend circuit breaker is open")}
+		rec := serve(t, &fakeRacingClient{}, sportsClient, "/v1/search?q=x")
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.JSONEq(t, `{"code":14,"message":"sports backend circuit breaker is open","details":[]}`, rec.Body.String())
+	})
+
+	t.Run("MethodNotAllowed", func(t *testing.T) {
+		mux := runtime.NewServeMux()
+		require.NoError(t, Register(mux, &fakeRacingClient{}, &fakeSportsClient{}))
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/search?q=x", nil))
+		assert.NotEqual(t, http.StatusOK, rec.Code)
+	})
+}
@@ -0,0 +1,104 @@
+// Package admin serves the introspection endpoints of a service's admin
+// listener: build info, the effective configuration, database stats and
+// pprof. It is meant for operators and must not be exposed publicly.
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+)
+
+// Info is what the admin endpoints report on.
+type Info struct {
+	// Name of the service, e.g. "racing.Racing".
+	Name string
+
+	// PrintConfig writes the effective configuration of the service, with
+	// secrets redacted. /config is not served if it is nil.
+	PrintConfig func(io.Writer) error
+
+	// DB is the database of the service, nil if it keeps its data in memory.
+	DB *sql.DB
+}
+
+// BuildInfo is the body of /buildinfo.
+type BuildInfo struct {
+	Service   string            `json:"service"`
+	GoVersion string            `json:"go_version"`
+	Path      string            `json:"path"`
+	Version   string            `json:"version"`
+	Settings  map[string]string `json:"settings,omitempty"`
+}
+
+// NewHandler returns the handler of the admin listener:
+//
+//	/buildinfo       module, version and VCS details of the binary (JSON)
+//	/config          effective configuration, secrets redacted (YAML)
+//	/dbstats         sql.DB.Stats() of the service's database (JSON)
+//	/debug/pprof/    the net/http/pprof profiles
+func NewHandler(info Info) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/buildinfo", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, buildInfo(info.Name))
+	})
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		if info.PrintConfig == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		if err := info.PrintConfig(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	mux.HandleFunc("/dbstats", func(w http.ResponseWriter, r *http.Request) {
+		if info.DB == nil {
+			http.Error(w, "no database, data is kept in memory", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, info.DB.Stats())
+	})
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return mux
+}
+
+// buildInfo reads the build details embedded in the running binary.
+func buildInfo(service string) BuildInfo {
+	info := BuildInfo{Service: service}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.GoVersion = bi.GoVersion
+	info.Path = bi.Main.Path
+	info.Version = bi.Main.Version
+	for _, s := range bi.Settings {
+		if info.Settings == nil {
+			info.Settings = make(map[string]string)
+		}
+		info.Settings[s.Key] = s.Value
+	}
+
+	return info
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,74 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	return rec
+}
+
+func TestBuildInfo(t *testing.T) {
+	rec := get(t, NewHandler(Info{Name: "racing.Racing"}), "/buildinfo")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var info BuildInfo
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	assert.Equal(t, "racing.Racing", info.Service)
+	assert.NotEmpty(t, info.GoVersion)
+}
+
+func TestConfig(t *testing.T) {
+	h := NewHandler(Info{PrintConfig: func(w io.Writer) error {
+		_, err := io.WriteString(w, "api_key: REDACTED\n")
+		return err
+	}})
+	rec := get(t, h, "/config")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "api_key: REDACTED\n", rec.Body.String())
+
+	h = NewHandler(Info{PrintConfig: func(io.Writer) error { return errors.New("boom") }})
+	assert.Equal(t, http.StatusInternalServerError, get(t, h, "/config").Code)
+
+	assert.Equal(t, http.StatusNotFound, get(t, NewHandler(Info{}), "/config").Code)
+}
+
+func TestDBStats(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(3)
+
+	rec := get(t, NewHandler(Info{DB: db}), "/dbstats")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var stats sql.DBStats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.Equal(t, 3, stats.MaxOpenConnections)
+
+	assert.Equal(t, http.StatusNotFound, get(t, NewHandler(Info{}), "/dbstats").Code)
+}
+
+func TestPprof(t *testing.T) {
+	h := NewHandler(Info{})
+
+	assert.Equal(t, http.StatusOK, get(t, h, "/debug/pprof/").Code)
+	assert.Equal(t, http.StatusOK, get(t, h, "/debug/pprof/goroutine?debug=1").Code)
+	assert.Equal(t, http.StatusNotFound, get(t, h, "/unknown").Code)
+}
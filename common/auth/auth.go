@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys the API gateway forwards the authenticated principal under.
+const (
+	SubjectKey = "x-auth-subject"
+	ScopesKey  = "x-auth-scopes"
+	MethodKey  = "x-auth-method"
+)
+
+// Public marks an RPC callable without a principal.
+const Public = ""
+
+// Principal is the caller as authenticated by the API gateway.
+type Principal struct {
+	Subject string
+	Scopes  []string
+	Method  string
+}
+
+// HasScope reports whether the principal was granted scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+type principalKey struct{}
+
+// FromContext returns the principal stored in ctx by the interceptors, if any.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// UnaryServerInterceptor enforces the scope each RPC requires, keyed by full
+// method name (e.g. "/racing.Racing/ListRaces"). RPCs mapped to Public are
+// open to all; RPCs missing from scopes are denied.
+//
+// The principal is read from metadata set by the API gateway and is trusted as
+// is, so the server must only be reachable by the gateway (e.g. mutual TLS).
+func UnaryServerInterceptor(scopes map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorize(ctx, scopes, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of
+// UnaryServerInterceptor.
+func StreamServerInterceptor(scopes map[string]string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(ss.Context(), scopes, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authorize(ctx context.Context, scopes map[string]string, method string) (context.Context, error) {
+	required, known := scopes[method]
+	if !known {
+		return nil, status.Errorf(codes.PermissionDenied, "%s is not available", method)
+	}
+
+	p, ok := principalFromMetadata(ctx)
+	if ok {
+		ctx = context.WithValue(ctx, principalKey{}, p)
+	}
+
+	if required == Public {
+		return ctx, nil
+	}
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+	if !p.HasScope(required) {
+		return nil, status.Errorf(codes.PermissionDenied, "scope %q required", required)
+	}
+
+	return ctx, nil
+}
+
+func principalFromMetadata(ctx context.Context) (*Principal, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	subject := first(md, SubjectKey)
+	if subject == "" {
+		return nil, false
+	}
+
+	return &Principal{
+		Subject: subject,
+		Scopes:  strings.Fields(first(md, ScopesKey)),
+		Method:  first(md, MethodKey),
+	}, true
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// serverStream overrides the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(map[string]string{
+		"/racing.Racing/ListRaces":     "races:read",
+		"/grpc.health.v1.Health/Check": Public,
+	})
+
+	var got *Principal
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got, _ = FromContext(ctx)
+		return "ok", nil
+	}
+	call := func(method string, md metadata.MD) error {
+		got = nil
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	for name, tc := range map[string]struct {
+		method string
+		md     metadata.MD
+		code   codes.Code
+	}{
+		"Authorized": {
+			method: "/racing.Racing/ListRaces",
+			md:     metadata.Pairs(SubjectKey, "partner-a", ScopesKey, "sports:read races:read", MethodKey, "api_key"),
+			code:   codes.OK,
+		},
+		"MissingPrincipal": {
+			method: "/racing.Racing/ListRaces",
+			code:   codes.Unauthenticated,
+		},
+		"MissingScope": {
+			method: "/racing.Racing/ListRaces",
+			md:     metadata.Pairs(SubjectKey, "partner-a", ScopesKey, "sports:read"),
+			code:   codes.PermissionDenied,
+		},
+		"Public": {
+			method: "/grpc.health.v1.Health/Check",
+			code:   codes.OK,
+		},
+		"UnknownMethod": {
+			method: "/racing.Racing/DeleteRace",
+			md:     metadata.Pairs(SubjectKey, "partner-a", ScopesKey, "races:read races:write"),
+			code:   codes.PermissionDenied,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := call(tc.method, tc.md)
+			assert.Equal(t, tc.code, status.Code(err))
+		})
+	}
+
+	require.NoError(t, call("/racing.Racing/ListRaces", metadata.Pairs(SubjectKey, "partner-a", ScopesKey, "races:read", MethodKey, "jwt")))
+	assert.Equal(t, &Principal{Subject: "partner-a", Scopes: []string{"races:read"}, Method: "jwt"}, got)
+}
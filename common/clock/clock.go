@@ -0,0 +1,108 @@
+// Package clock abstracts the current time, so time based logic such as
+// deriving race and event statuses can be tested, and replayed against seeded
+// data.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// Real is the system clock.
+type Real struct{}
+
+// Now returns time.Now().
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Fake is a clock for tests, standing still until set or advanced.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake creates a fake clock showing now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the time the clock was last set or advanced to.
+func (c *Fake) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Set moves the clock to now.
+func (c *Fake) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
+
+// Advance moves the clock forward by d.
+func (c *Fake) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// Simulated follows a base clock until set to a simulated time, from which it
+// then runs on in step with the base clock. It lets QA replay a race day
+// against seeded data.
+type Simulated struct {
+	base Clock
+
+	mu     sync.RWMutex
+	offset time.Duration
+	active bool
+}
+
+// NewSimulated creates a simulated clock following base.
+func NewSimulated(base Clock) *Simulated {
+	return &Simulated{base: base}
+}
+
+// Now returns the simulated time, or the base clock's while not simulating.
+func (c *Simulated) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.base.Now().Add(c.offset)
+}
+
+// Set makes the clock show now, running on from there.
+func (c *Simulated) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.offset = now.Sub(c.base.Now())
+	c.active = true
+}
+
+// Reset returns the clock to following its base clock.
+func (c *Simulated) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.offset = 0
+	c.active = false
+}
+
+// Active reports whether the clock is showing a simulated time.
+func (c *Simulated) Active() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.active
+}
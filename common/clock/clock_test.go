@@ -0,0 +1,41 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2021, time.March, 3, 10, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+	assert.Equal(t, start, c.Now())
+
+	c.Advance(90 * time.Minute)
+	assert.Equal(t, start.Add(90*time.Minute), c.Now())
+
+	c.Set(start)
+	assert.Equal(t, start, c.Now())
+}
+
+func TestSimulated(t *testing.T) {
+	base := NewFake(time.Date(2026, time.October, 19, 9, 0, 0, 0, time.UTC))
+	c := NewSimulated(base)
+
+	assert.False(t, c.Active())
+	assert.Equal(t, base.Now(), c.Now())
+
+	raceDay := time.Date(2021, time.March, 3, 10, 0, 0, 0, time.UTC)
+	c.Set(raceDay)
+	assert.True(t, c.Active())
+	assert.Equal(t, raceDay, c.Now())
+
+	// the simulated time runs on with the base clock
+	base.Advance(time.Minute)
+	assert.Equal(t, raceDay.Add(time.Minute), c.Now())
+
+	c.Reset()
+	assert.False(t, c.Active())
+	assert.Equal(t, base.Now(), c.Now())
+}
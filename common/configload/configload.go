@@ -0,0 +1,199 @@
+// Package configload layers config files, environment variables and flags
+// into a binary's configuration.
+package configload
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces the value of secret settings when printing.
+const redacted = "REDACTED"
+
+// Load layers the config file, environment variables and flags in args onto
+// the defaults already held by cfg. bind must register one flag per setting,
+// using the setting's current value as the flag default. Environment variables
+// are named after the flags, prefixed by envPrefix: e.g. RACING_DB_PATH sets
+// --db-path, and RACING_CONFIG names the config file.
+func Load(cfg interface{}, fs *flag.FlagSet, envPrefix string, args []string, lookupEnv func(string) (string, bool), bind func(*flag.FlagSet)) error {
+	path, ok := lookupEnv(envPrefix + "_CONFIG")
+	if p := configFlag(args); p != "" {
+		path, ok = p, true
+	}
+	if ok && path != "" {
+		if err := decodeFile(path, cfg); err != nil {
+			return fmt.Errorf("loading config file %s: %w", path, err)
+		}
+	}
+
+	fs.String("config", path, "Path of a YAML or TOML config file")
+	bind(fs)
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || f.Name == "config" || f.Name == "print-config" {
+			return
+		}
+
+		name := envName(envPrefix, f.Name)
+		if value, ok := lookupEnv(name); ok {
+			if _, ok := f.Value.(flagOnlyValue); ok {
+				err = fmt.Errorf("%s: --%s can only be given on the command line", name, f.Name)
+				return
+			}
+			if setErr := fs.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid value %q for %s: %w", value, name, setErr)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return fs.Parse(args)
+}
+
+// FlagOnly makes the named setting of fs only settable by its command line
+// flag, rejecting it in the environment. Its config struct field must not be
+// decoded (tagged "-") for files to reject it too. It is meant for settings
+// asking for something to be done once, which left in a config file or the
+// environment would be done again on every start.
+func FlagOnly(fs *flag.FlagSet, name string) {
+	f := fs.Lookup(name)
+	f.Value = flagOnlyValue{f.Value}
+}
+
+// flagOnlyValue marks the value of a FlagOnly setting.
+type flagOnlyValue struct {
+	flag.Value
+}
+
+func (v flagOnlyValue) Get() interface{} {
+	if getter, ok := v.Value.(flag.Getter); ok {
+		return getter.Get()
+	}
+
+	return v.String()
+}
+
+func (v flagOnlyValue) IsBoolFlag() bool {
+	b, ok := v.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// configFlag extracts the value of --config from args, ahead of flag parsing.
+func configFlag(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+		if strings.HasPrefix(name, "config=") {
+			return strings.TrimPrefix(name, "config=")
+		}
+		if name == "config" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+
+	return ""
+}
+
+// decodeFile decodes a YAML or TOML file, chosen by extension, into cfg.
+// Unknown keys are rejected so typos don't go unnoticed.
+func decodeFile(path string, cfg interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && err != io.EOF {
+			return err
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return err
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown keys %v", undecoded)
+		}
+	default:
+		return fmt.Errorf("unsupported config file extension %q (want .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+
+	return nil
+}
+
+// envName maps a flag name to its environment variable.
+func envName(envPrefix, flagName string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// Print writes the value of every setting in fs to w as YAML, replacing the
+// value of the named secret settings. Of secret URLs only the user info and
+// query are replaced, so where they point stays visible.
+func Print(w io.Writer, fs *flag.FlagSet, secrets map[string]bool) error {
+	values := make(map[string]interface{})
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "config" || f.Name == "print-config" {
+			return
+		}
+
+		var value interface{} = f.Value.String()
+		if getter, ok := f.Value.(flag.Getter); ok {
+			value = getter.Get()
+		}
+		if d, ok := value.(time.Duration); ok {
+			value = d.String()
+		}
+		if secrets[f.Name] && f.Value.String() != "" {
+			value = redact(f.Value.String())
+		}
+
+		values[strings.ReplaceAll(f.Name, "-", "_")] = value
+	})
+
+	return yaml.NewEncoder(w).Encode(values)
+}
+
+// redact returns the printable form of a secret value.
+func redact(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return redacted
+	}
+
+	if u.User != nil {
+		u.User = url.User(redacted)
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = redacted
+	}
+
+	return u.String()
+}
+
+// FormatErrors joins validation errors into a single message.
+func FormatErrors(errs []string) string {
+	return "invalid configuration:\n  " + strings.Join(errs, "\n  ")
+}
@@ -0,0 +1,192 @@
+// Package filtering implements the AIP-160 filter language for list requests,
+// e.g.
+//
+//	visible = true AND advertised_start_time > "2026-10-18T00:00:00Z" AND meeting_id IN (1, 2)
+//
+// Expressions are parsed into an AST, type checked against the fields a
+// resource exposes, and compiled to a parameterised SQL condition.
+package filtering
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Type is the type of a filterable field.
+type Type int
+
+const (
+	// Int fields compare against integer literals.
+	Int Type = iota
+	// String fields compare against quoted string literals.
+	String
+	// Bool fields compare against true or false, with = and != only.
+	Bool
+	// Timestamp fields compare against quoted RFC 3339 timestamps.
+	Timestamp
+)
+
+func (t Type) String() string {
+	switch t {
+	case Int:
+		return "integer"
+	case String:
+		return "string"
+	case Bool:
+		return "boolean"
+	case Timestamp:
+		return "timestamp"
+	}
+
+	return "unknown"
+}
+
+// Field describes a field expressions may refer to.
+type Field struct {
+	// Column is the SQL column holding the field.
+	Column string
+	Type   Type
+}
+
+// Schema maps field names to their description.
+type Schema map[string]Field
+
+// Error reports an expression that cannot be parsed or does not type check.
+type Error struct {
+	// Pos is the 1-based position in the expression the error was found at.
+	Pos    int
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("filter: %s at position %d", e.Reason, e.Pos)
+}
+
+// Op is a comparison operator.
+type Op string
+
+// Comparison operators.
+const (
+	Eq Op = "="
+	Ne Op = "!="
+	Lt Op = "<"
+	Le Op = "<="
+	Gt Op = ">"
+	Ge Op = ">="
+)
+
+// Expr is a node of a parsed expression.
+type Expr interface {
+	expr()
+}
+
+// And matches when all of its terms match.
+type And struct {
+	Terms []Expr
+}
+
+// Or matches when any of its terms match.
+type Or struct {
+	Terms []Expr
+}
+
+// Not matches when its term does not.
+type Not struct {
+	Term Expr
+}
+
+// Compare compares a field against a value, which is an int64, string, bool
+// or time.Time matching the field's type.
+type Compare struct {
+	Field string
+	Op    Op
+	Value interface{}
+}
+
+// In matches when a field equals any of the values.
+type In struct {
+	Field  string
+	Values []interface{}
+}
+
+func (And) expr()     {}
+func (Or) expr()      {}
+func (Not) expr()     {}
+func (Compare) expr() {}
+func (In) expr()      {}
+
+// SQL compiles the expression into a SQL condition over the schema's columns
+// and the arguments for its placeholders.
+func SQL(e Expr, schema Schema) (string, []interface{}) {
+	var (
+		b    strings.Builder
+		args []interface{}
+	)
+	compile(&b, &args, e, schema)
+
+	return b.String(), args
+}
+
+func compile(b *strings.Builder, args *[]interface{}, e Expr, schema Schema) {
+	switch e := e.(type) {
+	case And:
+		join(b, args, e.Terms, " AND ", schema)
+	case Or:
+		join(b, args, e.Terms, " OR ", schema)
+	case Not:
+		b.WriteString("NOT (")
+		compile(b, args, e.Term, schema)
+		b.WriteString(")")
+	case Compare:
+		field := schema[e.Field]
+		b.WriteString(column(field) + " " + string(e.Op) + " ?")
+		*args = append(*args, arg(field, e.Value))
+	case In:
+		field := schema[e.Field]
+		b.WriteString(column(field) + " IN (" + strings.Repeat("?,", len(e.Values)-1) + "?)")
+		for _, v := range e.Values {
+			*args = append(*args, arg(field, v))
+		}
+	}
+}
+
+func join(b *strings.Builder, args *[]interface{}, terms []Expr, sep string, schema Schema) {
+	b.WriteString("(")
+	for i, term := range terms {
+		if i > 0 {
+			b.WriteString(sep)
+		}
+		compile(b, args, term, schema)
+	}
+	b.WriteString(")")
+}
+
+// TimeColumn returns the SQL expression comparing a timestamp column. Values
+// are normalised with datetime() so ones stored with different UTC offsets
+// compare correctly against FormatTime arguments.
+func TimeColumn(column string) string {
+	return "datetime(" + column + ")"
+}
+
+// FormatTime formats t as an argument to compare against TimeColumn.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// column returns the SQL expression for a field.
+func column(f Field) string {
+	if f.Type == Timestamp {
+		return TimeColumn(f.Column)
+	}
+
+	return f.Column
+}
+
+func arg(f Field, v interface{}) interface{} {
+	if t, ok := v.(time.Time); ok && f.Type == Timestamp {
+		return FormatTime(t)
+	}
+
+	return v
+}
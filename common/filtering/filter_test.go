@@ -0,0 +1,125 @@
+package filtering
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var schema = Schema{
+	"id":                    {Column: "id", Type: Int},
+	"meeting_id":            {Column: "meeting_id", Type: Int},
+	"name":                  {Column: "name", Type: String},
+	"visible":               {Column: "visible", Type: Bool},
+	"advertised_start_time": {Column: "advertised_start_time", Type: Timestamp},
+}
+
+func TestParse(t *testing.T) {
+	e, err := Parse(`visible = true AND advertised_start_time > "2026-10-18T10:00:00+10:00" AND meeting_id IN (1, 2)`, schema)
+	require.NoError(t, err)
+
+	assert.Equal(t, And{Terms: []Expr{
+		Compare{Field: "visible", Op: Eq, Value: true},
+		Compare{Field: "advertised_start_time", Op: Gt, Value: time.Date(2026, 10, 18, 10, 0, 0, 0, time.FixedZone("", 10*60*60))},
+		In{Field: "meeting_id", Values: []interface{}{int64(1), int64(2)}},
+	}}, e)
+}
+
+func TestParseEmpty(t *testing.T) {
+	e, err := Parse("  ", schema)
+	assert.NoError(t, err)
+	assert.Nil(t, e)
+}
+
+func TestSQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		query string
+		args  []interface{}
+	}{
+		{
+			name:  "Comparison",
+			expr:  `meeting_id >= 3`,
+			query: "meeting_id >= ?",
+			args:  []interface{}{int64(3)},
+		},
+		{
+			name:  "Timestamp",
+			expr:  `advertised_start_time < "2026-10-18T10:00:00+10:00"`,
+			query: "datetime(advertised_start_time) < ?",
+			args:  []interface{}{"2026-10-18 00:00:00"},
+		},
+		{
+			name:  "OrBindsTighterThanAnd",
+			expr:  `visible = true AND meeting_id = 1 OR meeting_id = 2`,
+			query: "(visible = ? AND (meeting_id = ? OR meeting_id = ?))",
+			args:  []interface{}{true, int64(1), int64(2)},
+		},
+		{
+			name:  "ImplicitAnd",
+			expr:  `name = "Lakers" id != 4`,
+			query: "(name = ? AND id != ?)",
+			args:  []interface{}{"Lakers", int64(4)},
+		},
+		{
+			name:  "NotAndParentheses",
+			expr:  `NOT (id IN (1,2) OR name = 'it\'s')`,
+			query: "NOT ((id IN (?,?) OR name = ?))",
+			args:  []interface{}{int64(1), int64(2), "it's"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Parse(tt.expr, schema)
+			require.NoError(t, err)
+
+			query, args := SQL(e, schema)
+			assert.Equal(t, tt.query, query)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{`venue = "Flemington"`, `filter: unknown field "venue", must be one of advertised_start_time, id, meeting_id, name, visible at position 1`},
+		{`meeting_id = "3"`, `filter: cannot compare integer field "meeting_id" with "3" at position 14`},
+		{`visible > true`, `filter: boolean field "visible" only supports = and != at position 9`},
+		{`visible = 1`, `filter: cannot compare boolean field "visible" with "1" at position 11`},
+		{`advertised_start_time > "today"`, `filter: "today" is not an RFC 3339 timestamp at position 25`},
+		{`advertised_start_time < "9999-12-31T23:00:00-02:00"`, `filter: "9999-12-31T23:00:00-02:00" is out of range at position 25`},
+		{`id = 99999999999999999999`, `filter: "99999999999999999999" is out of range at position 6`},
+		{`id IN (1 2)`, `filter: expected "," or ")", got "2" at position 10`},
+		{`(id = 1`, `filter: expected ")", got end of filter at position 8`},
+		{`id = 1 AND`, `filter: expected a field, got end of filter at position 11`},
+		{`id = 1)`, `filter: unexpected ")" at position 7`},
+		{`name = "open`, `filter: unterminated string at position 8`},
+		{`id ! 1`, `filter: unexpected "!", did you mean "!=" at position 4`},
+		{`-id = 1`, `filter: unexpected "-", use NOT to negate at position 1`},
+		{`id ~ 1`, `filter: unexpected '~' at position 4`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr, schema)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestParseDepth(t *testing.T) {
+	expr := ""
+	for i := 0; i < maxDepth+1; i++ {
+		expr += "NOT "
+	}
+
+	_, err := Parse(expr+"id = 1", schema)
+	assert.Error(t, err)
+}
@@ -0,0 +1,97 @@
+package filtering
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Match evaluates the expression in memory, reporting whether a row with the
+// given field values matches it as it would the SQL compiled from it. value
+// returns a field's value as the type its schema declares: an int64, string,
+// bool or time.Time. Timestamps compare to the second. A nil expression
+// matches every row.
+func Match(e Expr, value func(field string) interface{}) bool {
+	switch e := e.(type) {
+	case nil:
+		return true
+	case And:
+		for _, term := range e.Terms {
+			if !Match(term, value) {
+				return false
+			}
+		}
+		return true
+	case Or:
+		for _, term := range e.Terms {
+			if Match(term, value) {
+				return true
+			}
+		}
+		return false
+	case Not:
+		return !Match(e.Term, value)
+	case Compare:
+		c := CompareValues(value(e.Field), e.Value)
+		switch e.Op {
+		case Eq:
+			return c == 0
+		case Ne:
+			return c != 0
+		case Lt:
+			return c < 0
+		case Le:
+			return c <= 0
+		case Gt:
+			return c > 0
+		case Ge:
+			return c >= 0
+		}
+	case In:
+		for _, v := range e.Values {
+			if CompareValues(value(e.Field), v) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	panic(fmt.Sprintf("filtering: unexpected expression %#v", e))
+}
+
+// CompareValues compares two field values of the same type the way SQLite
+// does, returning -1, 0 or 1. Strings compare bytewise, false orders before
+// true and times compare to the second.
+func CompareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case int64:
+		return compareInts(a, b.(int64))
+	case string:
+		return strings.Compare(a, b.(string))
+	case bool:
+		return compareInts(boolInt(a), boolInt(b.(bool)))
+	case time.Time:
+		return compareInts(a.Unix(), b.(time.Time).Unix())
+	}
+
+	panic(fmt.Sprintf("filtering: unexpected value %#v", a))
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,51 @@
+package filtering
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	row := map[string]interface{}{
+		"id":                    int64(3),
+		"meeting_id":            int64(7),
+		"name":                  "Flemington Cup",
+		"visible":               true,
+		"advertised_start_time": time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC),
+	}
+	value := func(field string) interface{} { return row[field] }
+
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{``, true},
+		{`id = 3`, true},
+		{`id != 3`, false},
+		{`id < 3 OR id >= 3`, true},
+		{`meeting_id IN (1, 7)`, true},
+		{`meeting_id IN (1, 2)`, false},
+		{`name = "Flemington Cup"`, true},
+		{`name = "flemington cup"`, false},
+		{`name > "Flemington"`, true},
+		{`visible = true AND NOT visible = false`, true},
+		{`visible != true`, false},
+		{`advertised_start_time = "2026-10-18T22:00:00+10:00"`, true},
+		{`advertised_start_time >= "2026-10-18T12:00:00.9Z"`, true},
+		{`advertised_start_time > "2026-10-18T12:00:00.9Z"`, false},
+		{`id = 3 AND (name = "Randwick" OR meeting_id = 7)`, true},
+		{`id = 4 meeting_id = 7`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Parse(tt.expr, schema)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.match, Match(e, value))
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package filtering
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxDepth bounds the nesting of parentheses and NOT.
+const maxDepth = 32
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of filter"
+	}
+
+	return strconv.Quote(t.text)
+}
+
+// lex splits the expression into tokens. String tokens hold their unquoted
+// value.
+func lex(input string) ([]token, error) {
+	var tokens []token
+
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r, pos := runes[i], i+1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "(", pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")", pos})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{tokComma, ",", pos})
+			i++
+		case r == '=':
+			tokens = append(tokens, token{tokOp, "=", pos})
+			i++
+		case r == '!' || r == '<' || r == '>':
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, &Error{Pos: pos, Reason: `unexpected "!", did you mean "!="`}
+			}
+			tokens = append(tokens, token{tokOp, op, pos})
+			i += len(op)
+		case r == '"' || r == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				b.WriteRune(runes[j])
+			}
+			if j == len(runes) {
+				return nil, &Error{Pos: pos, Reason: "unterminated string"}
+			}
+			tokens = append(tokens, token{tokString, b.String(), pos})
+			i = j + 1
+		case r == '-' || unicode.IsDigit(r):
+			j := i + 1
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			if r == '-' && j == i+1 {
+				return nil, &Error{Pos: pos, Reason: `unexpected "-", use NOT to negate`}
+			}
+			tokens = append(tokens, token{tokNumber, string(runes[i:j]), pos})
+			i = j
+		case r == '_' || unicode.IsLetter(r):
+			j := i + 1
+			for j < len(runes) && (runes[j] == '_' || runes[j] == '.' || unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+				j++
+			}
+			tokens = append(tokens, token{tokIdent, string(runes[i:j]), pos})
+			i = j
+		default:
+			return nil, &Error{Pos: pos, Reason: fmt.Sprintf("unexpected %q", r)}
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(runes) + 1}), nil
+}
+
+// Parse parses an expression and type checks it against schema. An empty
+// expression gives a nil Expr.
+//
+// AND binds looser than OR, as AIP-160 specifies, so "a AND b OR c" means
+// "a AND (b OR c)"; terms separated by whitespace alone are ANDed.
+func Parse(input string, schema Schema) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return nil, nil
+	}
+
+	p := &parser{tokens: tokens, schema: schema}
+	e, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.unexpected(t)
+	}
+
+	return e, nil
+}
+
+type parser struct {
+	tokens []token
+	next   int
+	depth  int
+	schema Schema
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != tokEOF {
+		p.next++
+	}
+
+	return t
+}
+
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && t.text == word
+}
+
+func (p *parser) unexpected(t token) error {
+	return &Error{Pos: t.pos, Reason: "unexpected " + t.String()}
+}
+
+// expression = sequence { "AND" sequence }
+func (p *parser) expression() (Expr, error) {
+	var terms []Expr
+	for {
+		e, err := p.sequence()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, e)
+
+		if !p.keyword("AND") {
+			break
+		}
+		p.take()
+	}
+
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return And{Terms: terms}, nil
+}
+
+// sequence = factor { factor }
+func (p *parser) sequence() (Expr, error) {
+	var terms []Expr
+	for {
+		e, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, e)
+
+		t := p.peek()
+		if t.kind == tokEOF || t.kind == tokRParen || p.keyword("AND") {
+			break
+		}
+	}
+
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return And{Terms: terms}, nil
+}
+
+// factor = term { "OR" term }
+func (p *parser) factor() (Expr, error) {
+	var terms []Expr
+	for {
+		e, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, e)
+
+		if !p.keyword("OR") {
+			break
+		}
+		p.take()
+	}
+
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return Or{Terms: terms}, nil
+}
+
+// term = [ "NOT" ] ( "(" expression ")" | restriction )
+func (p *parser) term() (Expr, error) {
+	if p.depth++; p.depth > maxDepth {
+		return nil, &Error{Pos: p.peek().pos, Reason: fmt.Sprintf("nested more than %d levels deep", maxDepth)}
+	}
+	defer func() { p.depth-- }()
+
+	if p.keyword("NOT") {
+		p.take()
+		e, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Term: e}, nil
+	}
+
+	if p.peek().kind == tokLParen {
+		p.take()
+		e, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.take(); t.kind != tokRParen {
+			return nil, &Error{Pos: t.pos, Reason: "expected \")\", got " + t.String()}
+		}
+		return e, nil
+	}
+
+	return p.restriction()
+}
+
+// restriction = field comparator value | field "IN" "(" value { "," value } ")"
+func (p *parser) restriction() (Expr, error) {
+	name := p.take()
+	if name.kind != tokIdent || isKeyword(name.text) {
+		return nil, &Error{Pos: name.pos, Reason: "expected a field, got " + name.String()}
+	}
+	field, ok := p.schema[name.text]
+	if !ok {
+		return nil, &Error{Pos: name.pos, Reason: fmt.Sprintf("unknown field %q, must be one of %s", name.text, p.fieldNames())}
+	}
+
+	if p.keyword("IN") {
+		p.take()
+		return p.in(name.text, field)
+	}
+
+	op := p.take()
+	if op.kind != tokOp {
+		return nil, &Error{Pos: op.pos, Reason: "expected a comparison after " + strconv.Quote(name.text) + ", got " + op.String()}
+	}
+	if field.Type == Bool && op.text != string(Eq) && op.text != string(Ne) {
+		return nil, &Error{Pos: op.pos, Reason: fmt.Sprintf("boolean field %q only supports = and !=", name.text)}
+	}
+
+	value, err := p.value(name.text, field)
+	if err != nil {
+		return nil, err
+	}
+
+	return Compare{Field: name.text, Op: Op(op.text), Value: value}, nil
+}
+
+func (p *parser) in(name string, field Field) (Expr, error) {
+	if t := p.take(); t.kind != tokLParen {
+		return nil, &Error{Pos: t.pos, Reason: "expected \"(\" after IN, got " + t.String()}
+	}
+
+	var values []interface{}
+	for {
+		value, err := p.value(name, field)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		t := p.take()
+		if t.kind == tokRParen {
+			break
+		}
+		if t.kind != tokComma {
+			return nil, &Error{Pos: t.pos, Reason: "expected \",\" or \")\", got " + t.String()}
+		}
+	}
+
+	return In{Field: name, Values: values}, nil
+}
+
+// value reads a literal, checking it suits the field.
+func (p *parser) value(name string, field Field) (interface{}, error) {
+	t := p.take()
+	mismatch := &Error{Pos: t.pos, Reason: fmt.Sprintf("cannot compare %s field %q with %s", field.Type, name, t)}
+
+	switch field.Type {
+	case Int:
+		if t.kind != tokNumber {
+			return nil, mismatch
+		}
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, &Error{Pos: t.pos, Reason: fmt.Sprintf("%s is out of range", t)}
+		}
+		return n, nil
+	case String:
+		if t.kind != tokString {
+			return nil, mismatch
+		}
+		return t.text, nil
+	case Bool:
+		if t.kind != tokIdent || (t.text != "true" && t.text != "false") {
+			return nil, mismatch
+		}
+		return t.text == "true", nil
+	case Timestamp:
+		if t.kind != tokString {
+			return nil, mismatch
+		}
+		ts, err := time.Parse(time.RFC3339, t.text)
+		if err != nil {
+			return nil, &Error{Pos: t.pos, Reason: fmt.Sprintf("%s is not an RFC 3339 timestamp", t)}
+		}
+		// FormatTime only gives values that compare correctly with four
+		// digit years, which an offset can push a timestamp out of
+		if year := ts.UTC().Year(); year < 0 || year > 9999 {
+			return nil, &Error{Pos: t.pos, Reason: fmt.Sprintf("%s is out of range", t)}
+		}
+		return ts, nil
+	}
+
+	return nil, mismatch
+}
+
+func (p *parser) fieldNames() string {
+	names := make([]string, 0, len(p.schema))
+	for name := range p.schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+func isKeyword(s string) bool {
+	switch s {
+	case "AND", "OR", "NOT", "IN":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,101 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"git.neds.sh/matty/entain/common/logging"
+)
+
+// Checker drives the gRPC health status of a service from the state of its
+// database: the service reports SERVING only once the repository has finished
+// its migrations/seeding and while the database answers pings.
+type Checker struct {
+	server   *health.Server
+	db       *sql.DB
+	services []string
+	interval time.Duration
+
+	ready   int32
+	serving int32
+}
+
+// NewChecker creates a Checker reporting on the overall server health ("") and
+// on each of the given services. Everything starts out NOT_SERVING. A nil db,
+// for services keeping their data in memory, is always reachable.
+func NewChecker(server *health.Server, db *sql.DB, interval time.Duration, services ...string) *Checker {
+	c := &Checker{
+		server:   server,
+		db:       db,
+		services: append([]string{""}, services...),
+		interval: interval,
+	}
+	c.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+
+	return c
+}
+
+// SetReady marks migrations and seeding as completed, and immediately
+// re-evaluates the health status.
+func (c *Checker) SetReady(ctx context.Context) {
+	atomic.StoreInt32(&c.ready, 1)
+	c.check(ctx)
+}
+
+// Run pings the database every interval until ctx is done. Status changes are
+// logged through the log entry carried by ctx.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.check(ctx)
+		}
+	}
+}
+
+// Shutdown permanently reports NOT_SERVING, so load balancers stop routing new
+// requests here while in-flight ones drain.
+func (c *Checker) Shutdown() {
+	c.server.Shutdown()
+}
+
+func (c *Checker) check(ctx context.Context) {
+	if atomic.LoadInt32(&c.ready) == 0 {
+		return
+	}
+
+	var err error
+	if c.db != nil {
+		pingCtx, cancel := context.WithTimeout(ctx, c.interval)
+		err = c.db.PingContext(pingCtx)
+		cancel()
+	}
+	if err != nil {
+		c.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+		if atomic.SwapInt32(&c.serving, 0) == 1 {
+			logging.FromContext(ctx).WithError(err).Error("database ping failed, reporting NOT_SERVING")
+		}
+		return
+	}
+
+	c.setStatus(healthpb.HealthCheckResponse_SERVING)
+	if atomic.SwapInt32(&c.serving, 1) == 0 {
+		logging.FromContext(ctx).Info("database reachable, reporting SERVING")
+	}
+}
+
+func (c *Checker) setStatus(status healthpb.HealthCheckResponse_ServingStatus) {
+	for _, service := range c.services {
+		c.server.SetServingStatus(service, status)
+	}
+}
@@ -0,0 +1,60 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestChecker(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := health.NewServer()
+	checker := NewChecker(server, db, time.Second, "racing.Racing")
+
+	status := func() healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "racing.Racing"})
+		assert.NoError(t, err)
+		return resp.Status
+	}
+
+	t.Run("NotServingBeforeReady", func(t *testing.T) {
+		checker.check(context.Background())
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status())
+	})
+
+	t.Run("ServingOnceReady", func(t *testing.T) {
+		mock.ExpectPing()
+		checker.SetReady(context.Background())
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status())
+	})
+
+	t.Run("NotServingWhenPingFails", func(t *testing.T) {
+		mock.ExpectPing().WillReturnError(errors.New("disk I/O error"))
+		checker.check(context.Background())
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCheckerWithoutDB(t *testing.T) {
+	server := health.NewServer()
+	checker := NewChecker(server, nil, time.Second, "racing.Racing")
+
+	checker.SetReady(context.Background())
+
+	resp, err := server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "racing.Racing"})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+}
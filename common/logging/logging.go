@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDKey is the gRPC metadata key carrying the request correlation ID.
+const RequestIDKey = "x-request-id"
+
+type ctxKey struct{}
+
+// New creates a logger writing at the given level ("debug", "info", ...) in
+// the given format ("json" or "text").
+func New(level, format string) (*logrus.Logger, error) {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(lvl)
+
+	switch format {
+	case "json":
+		logger.SetFormatter(&logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano})
+	case "text":
+		logger.SetFormatter(&logrus.TextFormatter{})
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+
+	return logger, nil
+}
+
+// WithEntry returns a copy of ctx carrying the given log entry.
+func WithEntry(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, ctxKey{}, entry)
+}
+
+// FromContext returns the request scoped log entry stored in ctx, falling back
+// to the standard logger when there is none.
+func FromContext(ctx context.Context) *logrus.Entry {
+	if entry, ok := ctx.Value(ctxKey{}).(*logrus.Entry); ok {
+		return entry
+	}
+
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+// NewRequestID generates a random request correlation ID.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// UnaryServerInterceptor attaches a log entry carrying the request ID (taken
+// from the incoming metadata, or generated if absent) to the context of every
+// call, and logs the outcome of the call once the handler returns.
+func UnaryServerInterceptor(logger *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var requestID string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if ids := md.Get(RequestIDKey); len(ids) > 0 {
+				requestID = ids[0]
+			}
+		}
+		if requestID == "" {
+			requestID = NewRequestID()
+		}
+
+		entry := logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"method":     info.FullMethod,
+		})
+		ctx = WithEntry(ctx, entry)
+
+		// echo the ID back so callers can correlate even when we generated it
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, requestID))
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		entry = entry.WithFields(logrus.Fields{
+			"code":     status.Code(err).String(),
+			"duration": time.Since(start).String(),
+		})
+		if err != nil {
+			entry.WithError(err).Warn("request failed")
+		} else {
+			entry.Info("request completed")
+		}
+
+		return resp, err
+	}
+}
@@ -0,0 +1,79 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// ndjsonType is the content type batches are posted with.
+const ndjsonType = "application/x-ndjson"
+
+// HTTPSink publishes batches to a message broker's HTTP endpoint, e.g. a
+// bridge producing them to Kafka, POSTing each as NDJSON. A 2xx response
+// acknowledges the whole batch. Locally, a server running NewReceiver stands
+// in for the broker.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPSink returns a sink posting batches to url with client.
+func NewHTTPSink(url string, client *http.Client) *HTTPSink {
+	return &HTTPSink{url: url, client: client}
+}
+
+// Publish posts msgs to the broker.
+func (s *HTTPSink) Publish(ctx context.Context, msgs []Message) error {
+	var body bytes.Buffer
+	if err := writeNDJSON(&body, msgs); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ndjsonType)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("broker answered HTTP %d: %s", res.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
+
+// NewReceiver returns a handler accepting the batches an HTTPSink posts and
+// publishing them to sink, e.g. a FileSink: a stand-in for the broker in
+// development and tests.
+func NewReceiver(sink Sink) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		msgs, err := readNDJSON(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid batch: %s", err), http.StatusBadRequest)
+			return
+		}
+		if err := sink.Publish(r.Context(), msgs); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
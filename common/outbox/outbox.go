@@ -0,0 +1,87 @@
+// Package outbox implements a transactional outbox. Services record every
+// change they make as a Message in the outbox table, in the same transaction
+// as the change, and a Relay publishes the messages to a Sink once committed.
+//
+// The relay only records how far it got once the sink has accepted a batch,
+// so every message is published at least once: after a failure or a restart
+// some may be published again, and consumers should skip offsets they have
+// already seen.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Message is a change recorded in the outbox.
+type Message struct {
+	// Offset orders the messages of an outbox, from 1. It is assigned when
+	// the message is appended and never reused.
+	Offset int64 `json:"offset"`
+	// Topic is the kind of resource changed, e.g. "racing.races".
+	Topic string `json:"topic"`
+	// Key identifies the resource changed within its topic, e.g. its ID.
+	Key string `json:"key"`
+	// Type is what happened to the resource, e.g. "race.created".
+	Type string `json:"type"`
+	// Payload is the resource after the change, as JSON.
+	Payload json.RawMessage `json:"payload"`
+	// CreatedAt is when the change was made.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// schema creates the outbox and the offsets relays have published up to, per
+// sink. AUTOINCREMENT keeps offsets from being reused once messages are
+// deleted.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS outbox (id INTEGER PRIMARY KEY AUTOINCREMENT, topic TEXT NOT NULL, key TEXT NOT NULL, type TEXT NOT NULL, payload BLOB NOT NULL, created_at DATETIME NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS outbox_offsets (sink TEXT PRIMARY KEY, next_offset INTEGER NOT NULL)`,
+}
+
+// Init creates the outbox tables in db, unless they exist.
+func Init(ctx context.Context, db *sql.DB) error {
+	for _, statement := range schema {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewMessage returns a message of typ about the resource m, keyed by its id
+// within topic, made at now.
+func NewMessage(topic, typ string, id int64, m proto.Message, now time.Time) (Message, error) {
+	payload, err := protojson.Marshal(m)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Topic:     topic,
+		Key:       strconv.FormatInt(id, 10),
+		Type:      typ,
+		Payload:   payload,
+		CreatedAt: now,
+	}, nil
+}
+
+// Append records msg in the outbox within tx, the transaction making the
+// change msg is about, so it is published if and only if tx commits.
+func Append(ctx context.Context, tx *sql.Tx, msg Message) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO outbox(topic, key, type, payload, created_at) VALUES (?,?,?,?,?)`,
+		msg.Topic,
+		msg.Key,
+		msg.Type,
+		[]byte(msg.Payload),
+		msg.CreatedAt.UTC().Format(time.RFC3339Nano),
+	)
+
+	return err
+}
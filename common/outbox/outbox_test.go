@@ -0,0 +1,100 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"git.neds.sh/matty/entain/common/sqlrepo/sqlrepotest"
+)
+
+var now = time.Date(2021, 2, 3, 4, 5, 6, 7000, time.UTC)
+
+// openOutbox returns a database with the outbox tables.
+func openOutbox(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db := sqlrepotest.OpenSQLite(t)
+	require.NoError(t, Init(context.Background(), db))
+
+	return db
+}
+
+// appendMessages appends a message keyed 1 to n to the outbox of db, in a
+// transaction each.
+func appendMessages(t *testing.T, db *sql.DB, n int) {
+	t.Helper()
+
+	for i := 1; i <= n; i++ {
+		msg, err := NewMessage("things", "thing.created", int64(i), wrapperspb.String("thing"), now)
+		require.NoError(t, err)
+
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		require.NoError(t, Append(context.Background(), tx, msg))
+		require.NoError(t, tx.Commit())
+	}
+}
+
+func TestNewMessage(t *testing.T) {
+	msg, err := NewMessage("things", "thing.created", 42, wrapperspb.String("thing"), now)
+	require.NoError(t, err)
+
+	assert.Equal(t, Message{
+		Topic:     "things",
+		Key:       "42",
+		Type:      "thing.created",
+		Payload:   json.RawMessage(`"thing"`),
+		CreatedAt: now,
+	}, msg)
+}
+
+func TestInit(t *testing.T) {
+	db := openOutbox(t)
+
+	// Init is safe to run on every start.
+	assert.NoError(t, Init(context.Background(), db))
+}
+
+func TestAppend(t *testing.T) {
+	ctx := context.Background()
+	db := openOutbox(t)
+	appendMessages(t, db, 2)
+
+	// A message appended in a transaction rolled back is never published.
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	require.NoError(t, Append(ctx, tx, Message{Topic: "things", Key: "3", Type: "thing.created", Payload: json.RawMessage(`{}`), CreatedAt: now}))
+	require.NoError(t, tx.Rollback())
+	appendMessages(t, db, 1)
+
+	batch, err := NewRelay(db, "test", NewBus(), 10).batch(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+	assert.Equal(t, Message{
+		Offset:    1,
+		Topic:     "things",
+		Key:       "1",
+		Type:      "thing.created",
+		Payload:   json.RawMessage(`"thing"`),
+		CreatedAt: now,
+	}, batch[0])
+	assert.Equal(t, []int64{1, 2, 3}, offsets(batch))
+	assert.Equal(t, "1", batch[2].Key)
+}
+
+// offsets returns the offsets of msgs.
+func offsets(msgs []Message) []int64 {
+	list := make([]int64, 0, len(msgs))
+	for _, msg := range msgs {
+		list = append(list, msg.Offset)
+	}
+
+	return list
+}
@@ -0,0 +1,110 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"git.neds.sh/matty/entain/common/logging"
+)
+
+// Relay publishes the messages of an outbox to a sink in offset order,
+// remembering under the sink's name the offset to continue from.
+type Relay struct {
+	db        *sql.DB
+	name      string
+	sink      Sink
+	batchSize int
+}
+
+// NewRelay returns a relay publishing the outbox in db to sink, batchSize
+// messages at a time. Relays of the same name share their progress.
+func NewRelay(db *sql.DB, name string, sink Sink, batchSize int) *Relay {
+	return &Relay{db: db, name: name, sink: sink, batchSize: batchSize}
+}
+
+// Run publishes new messages every interval until ctx is done. Failures are
+// logged and the messages retried on the next round.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Flush(ctx); err != nil && ctx.Err() == nil {
+				logging.FromContext(ctx).WithError(err).Warn("publishing outbox failed, retrying")
+			}
+		}
+	}
+}
+
+// Flush publishes every message not published yet, returning how many were.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	next, err := r.Offset(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for {
+		batch, err := r.batch(ctx, next)
+		if err != nil || len(batch) == 0 {
+			return published, err
+		}
+
+		if err := r.sink.Publish(ctx, batch); err != nil {
+			return published, err
+		}
+		// A failure from here on publishes the batch again next time.
+		next = batch[len(batch)-1].Offset + 1
+		if err := r.Seek(ctx, next); err != nil {
+			return published, err
+		}
+		published += len(batch)
+	}
+}
+
+// Offset returns the offset of the next message to publish.
+func (r *Relay) Offset(ctx context.Context) (int64, error) {
+	var next int64
+	err := r.db.QueryRowContext(ctx, `SELECT next_offset FROM outbox_offsets WHERE sink = ?`, r.name).Scan(&next)
+	if err == sql.ErrNoRows {
+		return 1, nil
+	}
+
+	return next, err
+}
+
+// Seek makes the relay continue from offset, e.g. to publish again the
+// messages from there on for a consumer that lost them.
+func (r *Relay) Seek(ctx context.Context, offset int64) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO outbox_offsets(sink, next_offset) VALUES (?,?)
+		ON CONFLICT(sink) DO UPDATE SET next_offset = excluded.next_offset`, r.name, offset)
+
+	return err
+}
+
+// batch returns up to batchSize messages from offset on.
+func (r *Relay) batch(ctx context.Context, offset int64) ([]Message, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, topic, key, type, payload, created_at FROM outbox WHERE id >= ? ORDER BY id LIMIT ?`, offset, r.batchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batch []Message
+	for rows.Next() {
+		var msg Message
+		var payload []byte
+		if err := rows.Scan(&msg.Offset, &msg.Topic, &msg.Key, &msg.Type, &payload, &msg.CreatedAt); err != nil {
+			return nil, err
+		}
+		msg.Payload = payload
+		batch = append(batch, msg)
+	}
+
+	return batch, rows.Err()
+}
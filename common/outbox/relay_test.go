@@ -0,0 +1,131 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a sink recording the offsets published to it, failing while err
+// is set.
+type recorder struct {
+	published []int64
+	err       error
+}
+
+func (r *recorder) Publish(ctx context.Context, msgs []Message) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.published = append(r.published, offsets(msgs)...)
+
+	return nil
+}
+
+func TestRelayFlush(t *testing.T) {
+	ctx := context.Background()
+	db := openOutbox(t)
+	sink := &recorder{}
+	relay := NewRelay(db, "test", sink, 2)
+
+	n, err := relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	appendMessages(t, db, 5)
+	n, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, sink.published)
+
+	// Only new messages are published by the next flush, even by another
+	// relay of the same name.
+	appendMessages(t, db, 1)
+	n, err = NewRelay(db, "test", sink, 2).Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5, 6}, sink.published)
+
+	next, err := relay.Offset(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), next)
+}
+
+func TestRelayAtLeastOnce(t *testing.T) {
+	ctx := context.Background()
+	db := openOutbox(t)
+	appendMessages(t, db, 3)
+
+	sink := &recorder{err: errors.New("broker down")}
+	relay := NewRelay(db, "test", sink, 10)
+
+	n, err := relay.Flush(ctx)
+	assert.EqualError(t, err, "broker down")
+	assert.Zero(t, n)
+
+	// The failed batch is published once the sink is back.
+	sink.err = nil
+	n, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []int64{1, 2, 3}, sink.published)
+}
+
+func TestRelaySeek(t *testing.T) {
+	ctx := context.Background()
+	db := openOutbox(t)
+	appendMessages(t, db, 4)
+
+	sink := &recorder{}
+	relay := NewRelay(db, "test", sink, 10)
+	_, err := relay.Flush(ctx)
+	require.NoError(t, err)
+
+	// Seeking back replays the messages from there on.
+	require.NoError(t, relay.Seek(ctx, 3))
+	n, err := relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []int64{1, 2, 3, 4, 3, 4}, sink.published)
+
+	// Relays of other names keep their own offsets.
+	other := &recorder{}
+	_, err = NewRelay(db, "other", other, 10).Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3, 4}, other.published)
+}
+
+func TestRelayRun(t *testing.T) {
+	db := openOutbox(t)
+	appendMessages(t, db, 2)
+
+	published := make(chan Message, 2)
+	bus := NewBus()
+	bus.Subscribe(func(ctx context.Context, msg Message) error {
+		published <- msg
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewRelay(db, "test", bus, 10).Run(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	for _, offset := range []int64{1, 2} {
+		select {
+		case msg := <-published:
+			assert.Equal(t, offset, msg.Offset)
+		case <-time.After(5 * time.Second):
+			t.Fatal("message not published")
+		}
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,127 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Sink is where a Relay publishes messages. Publish must only succeed once
+// every message of the batch is safely stored or handled: on failure the
+// relay publishes the whole batch again, so a sink may see a message more
+// than once.
+type Sink interface {
+	Publish(ctx context.Context, msgs []Message) error
+}
+
+// FileSink appends messages to a file as NDJSON, one message per line.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens path for appending messages, creating it if need be.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSink{file: file}, nil
+}
+
+// Publish appends msgs to the file, syncing it to disk.
+func (s *FileSink) Publish(ctx context.Context, msgs []Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := bufio.NewWriter(s.file)
+	if err := writeNDJSON(w, msgs); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return s.file.Sync()
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// Subscriber handles a message published on a Bus. An error has the message
+// published again later.
+type Subscriber func(ctx context.Context, msg Message) error
+
+// Bus hands messages to the subscribers in the same process, e.g. consumers
+// embedded in a service or tests.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers []Subscriber
+}
+
+// NewBus returns a bus without subscribers.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe adds a subscriber of every message published from now on.
+func (b *Bus) Subscribe(subscriber Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers = append(b.subscribers, subscriber)
+}
+
+// Publish hands every message, in order, to every subscriber, stopping at the
+// first that fails.
+func (b *Bus) Publish(ctx context.Context, msgs []Message) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, msg := range msgs {
+		for _, subscriber := range b.subscribers {
+			if err := subscriber(ctx, msg); err != nil {
+				return fmt.Errorf("message %d: %w", msg.Offset, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeNDJSON writes msgs to w, one JSON object per line.
+func writeNDJSON(w io.Writer, msgs []Message) error {
+	enc := json.NewEncoder(w)
+	for _, msg := range msgs {
+		if err := enc.Encode(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readNDJSON reads the messages written by writeNDJSON from r.
+func readNDJSON(r io.Reader) ([]Message, error) {
+	var msgs []Message
+
+	dec := json.NewDecoder(r)
+	for {
+		var msg Message
+		err := dec.Decode(&msg)
+		if err == io.EOF {
+			return msgs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+}
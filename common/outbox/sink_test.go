@@ -0,0 +1,106 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var batch = []Message{
+	{Offset: 1, Topic: "things", Key: "1", Type: "thing.created", Payload: []byte(`{"name":"one"}`), CreatedAt: now},
+	{Offset: 2, Topic: "things", Key: "2", Type: "thing.created", Payload: []byte(`{"name":"two"}`), CreatedAt: now},
+}
+
+const batchNDJSON = `{"offset":1,"topic":"things","key":"1","type":"thing.created","payload":{"name":"one"},"created_at":"2021-02-03T04:05:06.000007Z"}
+{"offset":2,"topic":"things","key":"2","type":"thing.created","payload":{"name":"two"},"created_at":"2021-02-03T04:05:06.000007Z"}
+`
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.ndjson")
+
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+	require.NoError(t, sink.Publish(context.Background(), batch[:1]))
+	require.NoError(t, sink.Close())
+
+	// Messages are appended to the file of an earlier run.
+	sink, err = NewFileSink(path)
+	require.NoError(t, err)
+	require.NoError(t, sink.Publish(context.Background(), batch[1:]))
+	require.NoError(t, sink.Close())
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, batchNDJSON, string(b))
+}
+
+func TestBus(t *testing.T) {
+	ctx := context.Background()
+	bus := NewBus()
+	assert.NoError(t, bus.Publish(ctx, batch))
+
+	var first, second []int64
+	bus.Subscribe(func(ctx context.Context, msg Message) error {
+		first = append(first, msg.Offset)
+		return nil
+	})
+	bus.Subscribe(func(ctx context.Context, msg Message) error {
+		if msg.Offset == 2 {
+			return errors.New("full")
+		}
+		second = append(second, msg.Offset)
+		return nil
+	})
+
+	assert.EqualError(t, bus.Publish(ctx, batch), "message 2: full")
+	assert.Equal(t, []int64{1, 2}, first)
+	assert.Equal(t, []int64{1}, second)
+}
+
+func TestHTTPSink(t *testing.T) {
+	ctx := context.Background()
+	received := &recorder{}
+	broker := httptest.NewServer(NewReceiver(received))
+	defer broker.Close()
+
+	sink := NewHTTPSink(broker.URL, broker.Client())
+	require.NoError(t, sink.Publish(ctx, batch))
+	assert.Equal(t, []int64{1, 2}, received.published)
+
+	received.err = errors.New("disk full")
+	assert.EqualError(t, sink.Publish(ctx, batch), "broker answered HTTP 500: disk full")
+}
+
+func TestReceiver(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+		offset []int64
+	}{
+		{name: "Batch", method: http.MethodPost, body: batchNDJSON, status: http.StatusNoContent, offset: []int64{1, 2}},
+		{name: "Empty", method: http.MethodPost, status: http.StatusNoContent},
+		{name: "Invalid", method: http.MethodPost, body: "{", status: http.StatusBadRequest},
+		{name: "Get", method: http.MethodGet, status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := &recorder{}
+			rec := httptest.NewRecorder()
+			NewReceiver(received).ServeHTTP(rec, httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.offset, received.published)
+		})
+	}
+}
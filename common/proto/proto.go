@@ -0,0 +1,3 @@
+package proto
+
+//go:generate protoc --go_out=. --go_opt=module=git.neds.sh/matty/entain/common/proto validate/validate.proto validatetest/validatetest.proto --experimental_allow_proto3_optional
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"git.neds.sh/matty/entain/common/configload"
+)
+
+// Storage backends a service can keep its data in.
+const (
+	// StorageSQLite keeps data in the SQLite database file at DBPath.
+	StorageSQLite = "sqlite"
+	// StorageMemory keeps data in memory, seeded on start and lost on exit.
+	StorageMemory = "memory"
+)
+
+// Sinks the outbox relay can publish domain events to.
+const (
+	// OutboxFile appends events to the NDJSON file at OutboxPath.
+	OutboxFile = "file"
+	// OutboxHTTP posts events to the message broker endpoint at OutboxURL.
+	OutboxHTTP = "http"
+)
+
+// Secrets names the settings whose values are redacted when the configuration
+// is printed: the outbox URL may carry the broker's credentials.
+var Secrets = map[string]bool{"outbox-url": true}
+
+// Config holds the settings every gRPC service shares. Services embed it,
+// inlined, in their own configuration.
+type Config struct {
+	GRPCEndpoint        string        `yaml:"grpc_endpoint" toml:"grpc_endpoint"`
+	Storage             string        `yaml:"storage" toml:"storage"`
+	DBPath              string        `yaml:"db_path" toml:"db_path"`
+	LogLevel            string        `yaml:"log_level" toml:"log_level"`
+	LogFormat           string        `yaml:"log_format" toml:"log_format"`
+	SlowQueryThreshold  time.Duration `yaml:"slow_query_threshold" toml:"slow_query_threshold"`
+	HealthCheckInterval time.Duration `yaml:"health_check_interval" toml:"health_check_interval"`
+	ShutdownDelay       time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay"`
+	ShutdownTimeout     time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	TLSCertFile         string        `yaml:"tls_cert_file" toml:"tls_cert_file"`
+	TLSKeyFile          string        `yaml:"tls_key_file" toml:"tls_key_file"`
+	TLSClientCAFile     string        `yaml:"tls_client_ca_file" toml:"tls_client_ca_file"`
+	TLSReloadInterval   time.Duration `yaml:"tls_reload_interval" toml:"tls_reload_interval"`
+	AuthEnabled         bool          `yaml:"auth_enabled" toml:"auth_enabled"`
+	SimulatedClock      bool          `yaml:"simulated_clock" toml:"simulated_clock"`
+	Reflection          bool          `yaml:"reflection" toml:"reflection"`
+	AdminEndpoint       string        `yaml:"admin_endpoint" toml:"admin_endpoint"`
+	OutboxSink          string        `yaml:"outbox_sink" toml:"outbox_sink"`
+	OutboxPath          string        `yaml:"outbox_path" toml:"outbox_path"`
+	OutboxURL           string        `yaml:"outbox_url" toml:"outbox_url"`
+	OutboxInterval      time.Duration `yaml:"outbox_interval" toml:"outbox_interval"`
+	OutboxBatchSize     int           `yaml:"outbox_batch_size" toml:"outbox_batch_size"`
+	// OutboxReplayFrom can only be set as a flag, so a replay is not done
+	// again on every restart.
+	OutboxReplayFrom int64 `yaml:"-" toml:"-"`
+}
+
+// DefaultConfig returns the shared defaults of a service listening on
+// endpoint and storing its data in dbPath.
+func DefaultConfig(endpoint, dbPath string) Config {
+	return Config{
+		GRPCEndpoint:        endpoint,
+		Storage:             StorageSQLite,
+		DBPath:              dbPath,
+		LogLevel:            "info",
+		LogFormat:           "json",
+		SlowQueryThreshold:  100 * time.Millisecond,
+		HealthCheckInterval: 5 * time.Second,
+		ShutdownTimeout:     15 * time.Second,
+		TLSReloadInterval:   30 * time.Second,
+		OutboxInterval:      time.Second,
+		OutboxBatchSize:     100,
+	}
+}
+
+// Bind registers a flag for every setting, defaulting to its current value.
+func (c *Config) Bind(fs *flag.FlagSet) {
+	fs.StringVar(&c.GRPCEndpoint, "grpc-endpoint", c.GRPCEndpoint, "gRPC server endpoint")
+	fs.StringVar(&c.Storage, "storage", c.Storage, "Where data is kept: sqlite, in the --db-path file, or memory, lost on exit")
+	fs.StringVar(&c.DBPath, "db-path", c.DBPath, "Path of the SQLite database file")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "Log level (debug, info, warn, error)")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "Log format (json or text)")
+	fs.DurationVar(&c.SlowQueryThreshold, "slow-query-threshold", c.SlowQueryThreshold, "Log a warning for DB queries slower than this (0 disables)")
+	fs.DurationVar(&c.HealthCheckInterval, "health-check-interval", c.HealthCheckInterval, "How often the DB is pinged to drive the gRPC health status")
+	fs.DurationVar(&c.ShutdownDelay, "shutdown-delay", c.ShutdownDelay, "How long to keep serving after reporting NOT_SERVING on shutdown, so load balancers can react")
+	fs.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout, "Deadline for in-flight requests to drain on shutdown before they are cancelled")
+	fs.StringVar(&c.TLSCertFile, "tls-cert-file", c.TLSCertFile, "PEM certificate to serve TLS with (plaintext if empty)")
+	fs.StringVar(&c.TLSKeyFile, "tls-key-file", c.TLSKeyFile, "PEM private key of --tls-cert-file")
+	fs.StringVar(&c.TLSClientCAFile, "tls-client-ca-file", c.TLSClientCAFile, "PEM CA bundle to verify client certificates against; requires clients to present one (mutual TLS)")
+	fs.DurationVar(&c.TLSReloadInterval, "tls-reload-interval", c.TLSReloadInterval, "How often TLS files are checked for changes and reloaded")
+	fs.BoolVar(&c.AuthEnabled, "auth-enabled", c.AuthEnabled, "Enforce the scopes each RPC requires, using the principal forwarded by the API gateway")
+	fs.BoolVar(&c.SimulatedClock, "simulated-clock", c.SimulatedClock, "Let admins set the time the service takes as now with SetClock, e.g. to replay a day against seeded data; requires --auth-enabled")
+	fs.BoolVar(&c.Reflection, "reflection", c.Reflection, "Register gRPC server reflection and channelz, e.g. for grpcurl; open to any client")
+	fs.StringVar(&c.AdminEndpoint, "admin-endpoint", c.AdminEndpoint, "HTTP endpoint serving build info, effective config, DB stats and pprof (disabled if empty)")
+	fs.StringVar(&c.OutboxSink, "outbox-sink", c.OutboxSink, "Where domain events are published from the outbox: file, to --outbox-path, or http, to the broker at --outbox-url (disabled if empty)")
+	fs.StringVar(&c.OutboxPath, "outbox-path", c.OutboxPath, "NDJSON file the file outbox sink appends domain events to")
+	fs.StringVar(&c.OutboxURL, "outbox-url", c.OutboxURL, "Message broker endpoint the http outbox sink posts domain events to")
+	fs.DurationVar(&c.OutboxInterval, "outbox-interval", c.OutboxInterval, "How often new domain events are published from the outbox")
+	fs.IntVar(&c.OutboxBatchSize, "outbox-batch-size", c.OutboxBatchSize, "How many domain events are published at a time")
+	fs.Int64Var(&c.OutboxReplayFrom, "outbox-replay-from", c.OutboxReplayFrom, "Publish domain events again from this offset on start (0 continues where the sink left off); command line only, and replays on every start it is given, so drop it once done")
+	configload.FlagOnly(fs, "outbox-replay-from")
+}
+
+// Validate checks the shared settings are usable, returning one message per
+// problem.
+func (c *Config) Validate() []string {
+	var errs []string
+
+	if _, _, err := net.SplitHostPort(c.GRPCEndpoint); err != nil {
+		errs = append(errs, fmt.Sprintf("grpc_endpoint: %s", err))
+	}
+	switch c.Storage {
+	case StorageSQLite:
+		if c.DBPath == "" {
+			errs = append(errs, "db_path: must not be empty")
+		}
+	case StorageMemory:
+	default:
+		errs = append(errs, fmt.Sprintf("storage: must be %s or %s, got %q", StorageSQLite, StorageMemory, c.Storage))
+	}
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Sprintf("log_level: %s", err))
+	}
+	if c.LogFormat != "json" && c.LogFormat != "text" {
+		errs = append(errs, fmt.Sprintf("log_format: must be json or text, got %q", c.LogFormat))
+	}
+	if c.SlowQueryThreshold < 0 {
+		errs = append(errs, "slow_query_threshold: must not be negative")
+	}
+	if c.HealthCheckInterval <= 0 {
+		errs = append(errs, "health_check_interval: must be positive")
+	}
+	if c.ShutdownDelay < 0 {
+		errs = append(errs, "shutdown_delay: must not be negative")
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, "shutdown_timeout: must be positive")
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, "tls_cert_file, tls_key_file: must be set together")
+	}
+	if c.TLSClientCAFile != "" && c.TLSCertFile == "" {
+		errs = append(errs, "tls_client_ca_file: requires tls_cert_file and tls_key_file")
+	}
+	if c.TLSReloadInterval <= 0 {
+		errs = append(errs, "tls_reload_interval: must be positive")
+	}
+	// Without auth any caller could move the clock, not just admins.
+	if c.SimulatedClock && !c.AuthEnabled {
+		errs = append(errs, "simulated_clock: requires auth_enabled")
+	}
+	if c.AdminEndpoint != "" {
+		if _, _, err := net.SplitHostPort(c.AdminEndpoint); err != nil {
+			errs = append(errs, fmt.Sprintf("admin_endpoint: %s", err))
+		}
+	}
+
+	switch c.OutboxSink {
+	case "":
+	case OutboxFile:
+		if c.OutboxPath == "" {
+			errs = append(errs, "outbox_path: must not be empty with the file sink")
+		}
+	case OutboxHTTP:
+		if u, err := url.Parse(c.OutboxURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, fmt.Sprintf("outbox_url: must be an http or https URL, got %q", c.OutboxURL))
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("outbox_sink: must be empty, %s or %s, got %q", OutboxFile, OutboxHTTP, c.OutboxSink))
+	}
+	if c.OutboxSink != "" && c.Storage != StorageSQLite {
+		errs = append(errs, "outbox_sink: requires sqlite storage")
+	}
+	if c.OutboxInterval <= 0 {
+		errs = append(errs, "outbox_interval: must be positive")
+	}
+	if c.OutboxBatchSize <= 0 {
+		errs = append(errs, "outbox_batch_size: must be positive")
+	}
+	if c.OutboxReplayFrom < 0 {
+		errs = append(errs, "outbox_replay_from: must not be negative")
+	}
+
+	return errs
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"git.neds.sh/matty/entain/common/filtering"
+	"git.neds.sh/matty/entain/common/sqlrepo"
+	"git.neds.sh/matty/entain/common/validation"
+)
+
+// StatusError maps the errors of sqlrepo based repositories to gRPC statuses:
+// bad order_by, filter expressions and page tokens become InvalidArgument
+// with the offending field, missing rows NotFound. Other errors are returned
+// as is.
+func StatusError(err error) error {
+	var (
+		orderErr  *sqlrepo.OrderByError
+		filterErr *filtering.Error
+	)
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &orderErr):
+		return validation.Error([]*errdetails.BadRequest_FieldViolation{
+			{Field: "order_by", Description: orderErr.Reason},
+		})
+	case errors.As(err, &filterErr):
+		return validation.Error([]*errdetails.BadRequest_FieldViolation{
+			{Field: "filter.expression", Description: fmt.Sprintf("%s at position %d", filterErr.Reason, filterErr.Pos)},
+		})
+	case errors.Is(err, sqlrepo.ErrInvalidPageToken):
+		return validation.Error([]*errdetails.BadRequest_FieldViolation{
+			{Field: "page_token", Description: "must be the next_page_token of a previous page"},
+		})
+	case errors.Is(err, sqlrepo.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	}
+
+	return err
+}
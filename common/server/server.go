@@ -0,0 +1,387 @@
+// Package server bootstraps the gRPC services: listening, TLS, the logging,
+// auth and validation interceptors, health reporting, reflection, the admin
+// listener, the outbox relay and graceful shutdown.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	channelzsvc "google.golang.org/grpc/channelz/service"
+	"google.golang.org/grpc/credentials"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"git.neds.sh/matty/entain/common/admin"
+	"git.neds.sh/matty/entain/common/auth"
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/health"
+	"git.neds.sh/matty/entain/common/logging"
+	"git.neds.sh/matty/entain/common/outbox"
+	"git.neds.sh/matty/entain/common/tlsutil"
+	"git.neds.sh/matty/entain/common/validation"
+)
+
+// Service describes a gRPC service to serve.
+type Service struct {
+	// Name is the fully qualified name of the service, e.g. "racing.Racing",
+	// under which its health is reported.
+	Name string
+
+	// Scopes lists the scope each RPC requires when auth is enabled. Any RPC
+	// missing here is denied.
+	Scopes map[string]string
+
+	// DB is the database the service depends on. It drives the health status.
+	// It is nil for a service keeping its data in memory.
+	DB *sql.DB
+
+	// Register registers the service implementation with the server.
+	Register func(*grpc.Server)
+
+	// Init prepares the service's data once the server is listening. The
+	// service reports NOT_SERVING until it returns.
+	Init func() error
+
+	// PrintConfig writes the effective configuration of the service, secrets
+	// redacted, for the admin listener.
+	PrintConfig func(io.Writer) error
+}
+
+// introspectionScopes opens the reflection and channelz RPCs to any client
+// when they are enabled: they are debugging aids, gated by the reflection
+// setting rather than by scopes.
+var introspectionScopes = map[string]string{
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo": auth.Public,
+	"/grpc.channelz.v1.Channelz/GetTopChannels":                      auth.Public,
+	"/grpc.channelz.v1.Channelz/GetServers":                          auth.Public,
+	"/grpc.channelz.v1.Channelz/GetServer":                           auth.Public,
+	"/grpc.channelz.v1.Channelz/GetServerSockets":                    auth.Public,
+	"/grpc.channelz.v1.Channelz/GetChannel":                          auth.Public,
+	"/grpc.channelz.v1.Channelz/GetSubchannel":                       auth.Public,
+	"/grpc.channelz.v1.Channelz/GetSocket":                           auth.Public,
+}
+
+// Run serves svc on the configured endpoint until ctx is done, then drains
+// in-flight requests and stops.
+func Run(ctx context.Context, cfg *Config, logger *logrus.Logger, svc Service) error {
+	conn, err := net.Listen("tcp", cfg.GRPCEndpoint)
+	if err != nil {
+		return err
+	}
+
+	return Serve(ctx, conn, cfg, logger, svc)
+}
+
+// Serve is Run on a listener of the caller's, e.g. an in-memory one in tests.
+// The listener is closed when Serve returns.
+func Serve(ctx context.Context, conn net.Listener, cfg *Config, logger *logrus.Logger, svc Service) error {
+	unary := []grpc.UnaryServerInterceptor{logging.UnaryServerInterceptor(logger)}
+	var stream []grpc.StreamServerInterceptor
+	if cfg.AuthEnabled {
+		if cfg.TLSClientCAFile == "" {
+			logger.Warn("auth is enabled without mutual TLS, any client can claim any principal")
+		}
+		scopes := svc.Scopes
+		if cfg.Reflection {
+			logger.Warn("reflection is enabled, any client can list the services and inspect channelz")
+			scopes = withIntrospection(svc.Scopes)
+		}
+		unary = append(unary, auth.UnaryServerInterceptor(scopes))
+		stream = append(stream, auth.StreamServerInterceptor(scopes))
+	}
+	// Validation runs after authorization so only permitted callers learn
+	// about the request rules.
+	unary = append(unary, validation.UnaryServerInterceptor())
+
+	serverOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+
+	if cfg.TLSCertFile != "" {
+		creds, err := serverCredentials(ctx, cfg, logger)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		serverOpts = append(serverOpts, grpc.Creds(creds))
+	}
+
+	adminServer, err := serveAdmin(cfg, logger, svc)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer adminServer.Close()
+
+	relay, err := newOutboxRelay(cfg, svc)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer relay.Close()
+
+	grpcServer := grpc.NewServer(serverOpts...)
+	svc.Register(grpcServer)
+	if cfg.Reflection {
+		reflection.Register(grpcServer)
+		channelzsvc.RegisterChannelzServiceToServer(grpcServer)
+	}
+
+	// The health service reports NOT_SERVING until the service has been
+	// initialised, and from then on follows the reachability of the DB.
+	healthServer := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	checker := health.NewChecker(healthServer, svc.DB, cfg.HealthCheckInterval, svc.Name)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- grpcServer.Serve(conn)
+	}()
+
+	logger.Infof("gRPC server listening on: %s", conn.Addr())
+
+	if svc.Init != nil {
+		if err := svc.Init(); err != nil {
+			grpcServer.Stop()
+			return err
+		}
+	}
+	healthCtx := logging.WithEntry(ctx, logger.WithField("component", "health"))
+	checker.SetReady(healthCtx)
+	go checker.Run(healthCtx)
+	if err := relay.Start(ctx, cfg, logger); err != nil {
+		grpcServer.Stop()
+		return err
+	}
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Flip readiness first so no new traffic is routed here, then drain.
+	logger.Info("shutdown signal received, reporting NOT_SERVING")
+	checker.Shutdown()
+	time.Sleep(cfg.ShutdownDelay)
+
+	logger.Infof("draining in-flight requests (timeout %s)", cfg.ShutdownTimeout)
+	if !gracefulStop(grpcServer, cfg.ShutdownTimeout) {
+		logger.Warn("shutdown timeout exceeded, cancelled remaining requests")
+	}
+	logger.Info("gRPC server stopped")
+	relay.Stop(logger, cfg.ShutdownTimeout)
+
+	return nil
+}
+
+// NewClock returns the clock the service tells the time by: the system clock,
+// or one admins can set if the simulated clock is enabled.
+func NewClock(cfg *Config, logger *logrus.Logger) clock.Clock {
+	var clk clock.Clock = clock.Real{}
+	if cfg.SimulatedClock {
+		logger.Warn("simulated clock is enabled, admins can change the time the service takes as now")
+		clk = clock.NewSimulated(clk)
+	}
+
+	return clk
+}
+
+// withIntrospection returns a copy of scopes opening the reflection and
+// channelz RPCs.
+func withIntrospection(scopes map[string]string) map[string]string {
+	merged := make(map[string]string, len(scopes)+len(introspectionScopes))
+	for method, scope := range scopes {
+		merged[method] = scope
+	}
+	for method, scope := range introspectionScopes {
+		merged[method] = scope
+	}
+
+	return merged
+}
+
+// adminServer is the admin HTTP listener of a service, a no-op when disabled.
+type adminServer struct {
+	server *http.Server
+}
+
+// serveAdmin starts the admin listener on the configured endpoint, if any.
+func serveAdmin(cfg *Config, logger *logrus.Logger, svc Service) (*adminServer, error) {
+	if cfg.AdminEndpoint == "" {
+		return &adminServer{}, nil
+	}
+
+	conn, err := net.Listen("tcp", cfg.AdminEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &http.Server{
+		Handler: admin.NewHandler(admin.Info{
+			Name:        svc.Name,
+			PrintConfig: svc.PrintConfig,
+			DB:          svc.DB,
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(conn); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.WithError(err).Error("admin server failed")
+		}
+	}()
+	logger.Infof("admin server listening on: %s", conn.Addr())
+
+	return &adminServer{server: server}, nil
+}
+
+// Close stops the admin listener. Profiles being captured are cut short.
+func (a *adminServer) Close() {
+	if a.server != nil {
+		a.server.Close()
+	}
+}
+
+// outboxRelay publishes the outbox of a service's database while it serves, a
+// no-op when disabled.
+type outboxRelay struct {
+	relay  *outbox.Relay
+	closer io.Closer
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// newOutboxRelay opens the configured sink, if any.
+func newOutboxRelay(cfg *Config, svc Service) (*outboxRelay, error) {
+	if cfg.OutboxSink == "" || svc.DB == nil {
+		return &outboxRelay{}, nil
+	}
+
+	var sink outbox.Sink
+	var closer io.Closer
+	switch cfg.OutboxSink {
+	case OutboxFile:
+		fileSink, err := outbox.NewFileSink(cfg.OutboxPath)
+		if err != nil {
+			return nil, err
+		}
+		sink, closer = fileSink, fileSink
+	case OutboxHTTP:
+		sink = outbox.NewHTTPSink(cfg.OutboxURL, &http.Client{Timeout: 10 * time.Second})
+	default:
+		return nil, fmt.Errorf("unknown outbox sink %q", cfg.OutboxSink)
+	}
+
+	// The relay is named after the sink, so switching sinks publishes the
+	// outbox to the new one from the start.
+	return &outboxRelay{
+		relay:  outbox.NewRelay(svc.DB, cfg.OutboxSink, sink, cfg.OutboxBatchSize),
+		closer: closer,
+	}, nil
+}
+
+// Start publishes new messages every configured interval, first seeking to
+// the configured replay offset, if any. The service must have initialised
+// the outbox.
+func (o *outboxRelay) Start(ctx context.Context, cfg *Config, logger *logrus.Logger) error {
+	if o.relay == nil {
+		return nil
+	}
+
+	if cfg.OutboxReplayFrom > 0 {
+		if err := o.relay.Seek(ctx, cfg.OutboxReplayFrom); err != nil {
+			return err
+		}
+		logger.Infof("replaying outbox from offset %d", cfg.OutboxReplayFrom)
+	}
+
+	ctx, o.cancel = context.WithCancel(logging.WithEntry(ctx, logger.WithField("component", "outbox")))
+	o.done = make(chan struct{})
+	go func() {
+		o.relay.Run(ctx, cfg.OutboxInterval)
+		close(o.done)
+	}()
+	logger.Infof("publishing outbox to %s sink every %s", cfg.OutboxSink, cfg.OutboxInterval)
+
+	return nil
+}
+
+// Stop stops publishing once the messages written since the last round are,
+// giving up after timeout. Those left are published on the next start.
+func (o *outboxRelay) Stop(logger *logrus.Logger, timeout time.Duration) {
+	if o.cancel == nil {
+		return
+	}
+	o.cancel()
+	<-o.done
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if _, err := o.relay.Flush(ctx); err != nil {
+		logger.WithError(err).Warn("publishing outbox on shutdown failed, left for the next start")
+	}
+}
+
+// Close stops publishing, if still running, and closes the sink.
+func (o *outboxRelay) Close() {
+	if o.cancel != nil {
+		o.cancel()
+		<-o.done
+	}
+	if o.closer != nil {
+		o.closer.Close()
+	}
+}
+
+// serverCredentials loads the configured TLS files, reloading them on change
+// for as long as ctx lives. Clients must present a certificate signed by the
+// client CA if one is configured.
+func serverCredentials(ctx context.Context, cfg *Config, logger *logrus.Logger) (credentials.TransportCredentials, error) {
+	reloader, err := tlsutil.NewReloader(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	go reloader.Run(logging.WithEntry(ctx, logger.WithField("component", "tls")), cfg.TLSReloadInterval)
+
+	clientAuth := tls.NoClientCert
+	if cfg.TLSClientCAFile != "" {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return credentials.NewTLS(reloader.ServerConfig(clientAuth, "h2")), nil
+}
+
+// gracefulStop stops the server once in-flight requests complete, forcing it
+// to stop if that takes longer than timeout. It reports whether all requests
+// drained in time.
+func gracefulStop(server *grpc.Server, timeout time.Duration) bool {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-stopped:
+		return true
+	case <-timer.C:
+		server.Stop()
+		<-stopped
+		return false
+	}
+}
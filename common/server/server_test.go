@@ -0,0 +1,335 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"git.neds.sh/matty/entain/common/auth"
+	"git.neds.sh/matty/entain/common/filtering"
+	"git.neds.sh/matty/entain/common/outbox"
+	"git.neds.sh/matty/entain/common/sqlrepo"
+	"git.neds.sh/matty/entain/common/sqlrepo/sqlrepotest"
+)
+
+func TestConfigValidate(t *testing.T) {
+	cfg := DefaultConfig("localhost:9000", "./db/test.db")
+	assert.Empty(t, cfg.Validate())
+
+	cfg.GRPCEndpoint = "9000"
+	cfg.TLSClientCAFile = "ca.pem"
+	cfg.ShutdownTimeout = 0
+	cfg.AdminEndpoint = "localhost"
+	cfg.OutboxBatchSize = 0
+	assert.Len(t, cfg.Validate(), 5)
+}
+
+func TestConfigValidateOutbox(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *Config)
+		errs   []string
+	}{
+		{name: "Disabled", modify: func(cfg *Config) {}},
+		{
+			name:   "File",
+			modify: func(cfg *Config) { cfg.OutboxSink, cfg.OutboxPath = OutboxFile, "events.ndjson" },
+		},
+		{
+			name:   "FileWithoutPath",
+			modify: func(cfg *Config) { cfg.OutboxSink = OutboxFile },
+			errs:   []string{"outbox_path: must not be empty with the file sink"},
+		},
+		{
+			name:   "HTTP",
+			modify: func(cfg *Config) { cfg.OutboxSink, cfg.OutboxURL = OutboxHTTP, "http://localhost:9200/events" },
+		},
+		{
+			name:   "HTTPWithoutURL",
+			modify: func(cfg *Config) { cfg.OutboxSink, cfg.OutboxURL = OutboxHTTP, "localhost:9200" },
+			errs:   []string{`outbox_url: must be an http or https URL, got "localhost:9200"`},
+		},
+		{
+			name:   "UnknownSink",
+			modify: func(cfg *Config) { cfg.OutboxSink = "kafka" },
+			errs:   []string{`outbox_sink: must be empty, file or http, got "kafka"`},
+		},
+		{
+			name: "MemoryStorage",
+			modify: func(cfg *Config) {
+				cfg.Storage = StorageMemory
+				cfg.OutboxSink, cfg.OutboxPath = OutboxFile, "events.ndjson"
+			},
+			errs: []string{"outbox_sink: requires sqlite storage"},
+		},
+		{
+			name:   "Negative",
+			modify: func(cfg *Config) { cfg.OutboxInterval, cfg.OutboxReplayFrom = 0, -1 },
+			errs:   []string{"outbox_interval: must be positive", "outbox_replay_from: must not be negative"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig("localhost:9000", "./db/test.db")
+			tt.modify(&cfg)
+			assert.Equal(t, tt.errs, cfg.Validate())
+		})
+	}
+}
+
+func TestConfigValidateStorage(t *testing.T) {
+	cfg := DefaultConfig("localhost:9000", "")
+	assert.Equal(t, []string{"db_path: must not be empty"}, cfg.Validate())
+
+	cfg.Storage = StorageMemory
+	assert.Empty(t, cfg.Validate(), "memory storage needs no db_path")
+
+	cfg.Storage = "postgres"
+	assert.Equal(t, []string{`storage: must be sqlite or memory, got "postgres"`}, cfg.Validate())
+}
+
+func TestConfigValidateSimulatedClock(t *testing.T) {
+	cfg := DefaultConfig("localhost:9000", "./db/test.db")
+	cfg.SimulatedClock = true
+	assert.Equal(t, []string{"simulated_clock: requires auth_enabled"}, cfg.Validate())
+
+	cfg.AuthEnabled = true
+	assert.Empty(t, cfg.Validate(), "only admins can set the clock")
+}
+
+func TestConfigBind(t *testing.T) {
+	cfg := DefaultConfig("localhost:9000", "./db/test.db")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.Bind(fs)
+
+	require.NoError(t, fs.Parse([]string{"--db-path", "/var/lib/test.db", "--simulated-clock", "--reflection", "--admin-endpoint", "localhost:9100", "--outbox-sink", "file", "--outbox-replay-from", "42"}))
+	assert.Equal(t, "/var/lib/test.db", cfg.DBPath)
+	assert.True(t, cfg.SimulatedClock)
+	assert.True(t, cfg.Reflection)
+	assert.Equal(t, "localhost:9100", cfg.AdminEndpoint)
+	assert.Equal(t, OutboxFile, cfg.OutboxSink)
+	assert.Equal(t, int64(42), cfg.OutboxReplayFrom)
+	assert.Equal(t, 100, cfg.OutboxBatchSize, "default kept")
+	assert.Equal(t, "localhost:9000", cfg.GRPCEndpoint, "default kept")
+}
+
+// serve runs Serve with cfg on an in-memory listener until the test ends,
+// returning a client connection to it once it reports SERVING.
+func serve(t *testing.T, cfg Config, svc Service) *grpc.ClientConn {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	cfg.ShutdownTimeout = time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lis := bufconn.Listen(1 << 20)
+	var (
+		serveErr error
+		started  bool
+	)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		serveErr = Serve(ctx, lis, &cfg, logger, svc)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+		// failing to start has been reported already
+		if started {
+			assert.NoError(t, serveErr)
+		}
+	})
+
+	conn, err := grpc.DialContext(ctx, "bufconn",
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	health := healthpb.NewHealthClient(conn)
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		select {
+		case <-stopped:
+			t.Fatalf("stopped while starting: %v", serveErr)
+		default:
+		}
+
+		// each check is bounded, as one waiting for a server that never
+		// comes up would otherwise wait for good
+		checkCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		resp, err := health.Check(checkCtx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+		cancel()
+		if err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING {
+			started = true
+			return conn
+		}
+		require.True(t, time.Now().Before(deadline), "not serving: %v", err)
+	}
+}
+
+func TestServeReflection(t *testing.T) {
+	cfg := DefaultConfig("bufconn:0", "")
+	cfg.AuthEnabled = true
+	svc := Service{
+		Name:     "test.Test",
+		Scopes:   map[string]string{"/grpc.health.v1.Health/Check": auth.Public},
+		Register: func(*grpc.Server) {},
+	}
+
+	listServices := func(conn *grpc.ClientConn) ([]string, error) {
+		stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background(), grpc.WaitForReady(true))
+		if err != nil {
+			return nil, err
+		}
+		err = stream.Send(&rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_ListServices{},
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, s := range resp.GetListServicesResponse().GetService() {
+			names = append(names, s.Name)
+		}
+		return names, nil
+	}
+
+	_, err := listServices(serve(t, cfg, svc))
+	assert.Equal(t, codes.Unimplemented, status.Code(err), "disabled by default")
+
+	cfg.Reflection = true
+	names, err := listServices(serve(t, cfg, svc))
+	require.NoError(t, err, "open to clients without a principal")
+	assert.Contains(t, names, "grpc.health.v1.Health")
+	assert.Contains(t, names, "grpc.channelz.v1.Channelz")
+	assert.Len(t, svc.Scopes, 1, "service scopes left untouched")
+}
+
+func TestServeAdmin(t *testing.T) {
+	// Reserve a free port for the admin listener.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	lis.Close()
+
+	cfg := DefaultConfig("bufconn:0", "")
+	cfg.AdminEndpoint = addr
+	serve(t, cfg, Service{Name: "test.Test", Register: func(*grpc.Server) {}})
+
+	resp, err := http.Get("http://" + addr + "/buildinfo")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get("http://" + addr + "/dbstats")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "no database in memory storage")
+}
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		code  codes.Code
+		field string
+	}{
+		{name: "OrderBy", err: &sqlrepo.OrderByError{Reason: "bad"}, code: codes.InvalidArgument, field: "order_by"},
+		{name: "Filter", err: &filtering.Error{Reason: "bad", Pos: 3}, code: codes.InvalidArgument, field: "filter.expression"},
+		{name: "PageToken", err: sqlrepo.ErrInvalidPageToken, code: codes.InvalidArgument, field: "page_token"},
+		{name: "NotFound", err: fmt.Errorf("race 7: %w", sqlrepo.ErrNotFound), code: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(StatusError(tt.err))
+			assert.Equal(t, tt.code, st.Code())
+
+			if tt.field == "" {
+				return
+			}
+			require.Len(t, st.Details(), 1)
+			badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+			require.True(t, ok)
+			assert.Equal(t, tt.field, badRequest.FieldViolations[0].Field)
+		})
+	}
+
+	assert.NoError(t, StatusError(nil))
+
+	other := errors.New("disk on fire")
+	assert.Equal(t, other, StatusError(other), "unknown errors are kept")
+}
+
+func TestServeOutbox(t *testing.T) {
+	db := sqlrepotest.OpenSQLite(t)
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	appendEvent := func(key string) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		err = outbox.Append(context.Background(), tx, outbox.Message{Topic: "things", Key: key, Type: "thing.created", Payload: []byte(`{}`), CreatedAt: time.Now()})
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}
+
+	t.Run("Serve", func(t *testing.T) {
+		cfg := DefaultConfig("bufconn:0", "")
+		cfg.OutboxSink, cfg.OutboxPath = OutboxFile, path
+		// Nothing is published while serving, only on shutdown.
+		cfg.OutboxInterval = time.Hour
+		serve(t, cfg, Service{
+			Name:     "test.Test",
+			DB:       db,
+			Register: func(*grpc.Server) {},
+			Init: func() error {
+				if err := outbox.Init(context.Background(), db); err != nil {
+					return err
+				}
+				return appendEvent("1")
+			},
+		})
+
+		require.NoError(t, appendEvent("2"))
+	})
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"offset":1,"topic":"things","key":"1"`)
+	assert.Contains(t, lines[1], `"offset":2,"topic":"things","key":"2"`)
+}
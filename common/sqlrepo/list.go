@@ -0,0 +1,180 @@
+package sqlrepo
+
+import (
+	"strings"
+	"time"
+
+	"git.neds.sh/matty/entain/common/filtering"
+)
+
+// ListBuilder selects the rows of a list request: ListQuery from a SQL table,
+// MemoryQuery from rows held in memory. Repositories apply a request's filter
+// through it, so both kinds list the same rows.
+type ListBuilder interface {
+	// Equal restricts column to value.
+	Equal(column string, value interface{})
+	// In restricts column to values. Empty values add no condition.
+	In(column string, values []int64)
+	// InStrings restricts column to values. Empty values add no condition.
+	InStrings(column string, values []string)
+	// From restricts the time in column to t or later.
+	From(column string, t time.Time)
+	// Until restricts the time in column to before t.
+	Until(column string, t time.Time)
+	// Status restricts rows to those with the given status at now, derived
+	// from the start time in column.
+	Status(column, status string, now time.Time)
+	// Filter adds an AIP-160 filter expression over the fields of schema.
+	Filter(expression string, schema filtering.Schema) error
+	// OrderBy sorts the rows by an AIP-132 order_by expression over the
+	// fields whitelisted in columns.
+	OrderBy(expression string, columns map[string]string) error
+	// Page limits the rows to the page of size continuing where the page
+	// token left off.
+	Page(size int32, token string) error
+}
+
+// ListQuery builds a list query from a base SELECT statement: conditions are
+// ANDed into a WHERE clause, followed by the order and the page.
+type ListQuery struct {
+	base    string
+	clauses []string
+	args    []interface{}
+	order   string
+	page    *page
+}
+
+// NewListQuery starts a list query selecting rows with base, a SELECT
+// statement without WHERE, ORDER BY or LIMIT clauses.
+func NewListQuery(base string) *ListQuery {
+	return &ListQuery{base: base}
+}
+
+// Where adds a condition, with the arguments of its placeholders.
+func (q *ListQuery) Where(clause string, args ...interface{}) {
+	q.clauses = append(q.clauses, clause)
+	q.args = append(q.args, args...)
+}
+
+// Equal restricts column to value.
+func (q *ListQuery) Equal(column string, value interface{}) {
+	q.Where(column+" = ?", value)
+}
+
+// In restricts column to values. Empty values add no condition.
+func (q *ListQuery) In(column string, values []int64) {
+	args := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		args = append(args, value)
+	}
+	q.in(column, args)
+}
+
+// InStrings restricts column to values. Empty values add no condition.
+func (q *ListQuery) InStrings(column string, values []string) {
+	args := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		args = append(args, value)
+	}
+	q.in(column, args)
+}
+
+// in restricts column to the values in args, if any.
+func (q *ListQuery) in(column string, args []interface{}) {
+	if len(args) == 0 {
+		return
+	}
+
+	q.Where(column+" IN ("+strings.Repeat("?,", len(args)-1)+"?)", args...)
+}
+
+// From restricts the time in column to t or later.
+func (q *ListQuery) From(column string, t time.Time) {
+	q.Where(filtering.TimeColumn(column)+" >= ?", filtering.FormatTime(t))
+}
+
+// Until restricts the time in column to before t.
+func (q *ListQuery) Until(column string, t time.Time) {
+	q.Where(filtering.TimeColumn(column)+" < ?", filtering.FormatTime(t))
+}
+
+// Status restricts rows to those with the given status at now, derived from
+// the start time in column as Status does. Other statuses add no condition.
+func (q *ListQuery) Status(column, status string, now time.Time) {
+	switch status {
+	case Open:
+		q.From(column, now)
+	case Closed:
+		q.Until(column, now)
+	}
+}
+
+// Filter adds an AIP-160 filter expression over the fields of schema. An
+// invalid expression gives a *filtering.Error; an empty or blank one adds
+// nothing.
+func (q *ListQuery) Filter(expression string, schema filtering.Schema) error {
+	expr, err := filtering.Parse(expression, schema)
+	if err != nil || expr == nil {
+		return err
+	}
+
+	clause, args := filtering.SQL(expr, schema)
+	q.Where(clause, args...)
+	return nil
+}
+
+// OrderBy sorts the rows by an AIP-132 order_by expression over the fields
+// whitelisted in columns, which must include "id". An invalid expression
+// gives an *OrderByError; an empty one sorts by id.
+func (q *ListQuery) OrderBy(expression string, columns map[string]string) error {
+	order, err := orderBy(expression, columns)
+	if err != nil {
+		return err
+	}
+
+	q.order = order
+	return nil
+}
+
+// Page limits the query to the page of size rows continuing where the page
+// token left off. A zero size lists every row. A token that was not handed
+// out by NextPageToken gives ErrInvalidPageToken.
+func (q *ListQuery) Page(size int32, token string) error {
+	offset, err := decodePageToken(token)
+	if err != nil {
+		return err
+	}
+	if size > 0 {
+		q.page = &page{size: int(size), offset: offset}
+	}
+
+	return nil
+}
+
+// SQL returns the query and its arguments.
+func (q *ListQuery) SQL() (string, []interface{}) {
+	query := q.base
+	if len(q.clauses) != 0 {
+		query += " WHERE " + strings.Join(q.clauses, " AND ")
+	}
+	query += q.order
+
+	args := q.args
+	if q.page != nil {
+		// one row beyond the page tells whether there is another
+		query += " LIMIT ? OFFSET ?"
+		args = append(args[:len(args):len(args)], q.page.size+1, q.page.offset)
+	}
+
+	return query, args
+}
+
+// NextPageToken trims the n rows the query returned to the page, returning
+// how many to keep and the token of the next page, empty on the last one.
+func (q *ListQuery) NextPageToken(n int) (int, string) {
+	if q.page == nil || n <= q.page.size {
+		return n, ""
+	}
+
+	return q.page.size, encodePageToken(q.page.offset + q.page.size)
+}
@@ -0,0 +1,189 @@
+package sqlrepo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/filtering"
+)
+
+var now = time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+
+var schema = filtering.Schema{
+	"id":   {Column: "id", Type: filtering.Int},
+	"name": {Column: "name", Type: filtering.String},
+}
+
+var columns = map[string]string{
+	"id":   "id",
+	"name": "name",
+}
+
+func TestListQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(q *ListQuery) error
+		query string
+		args  []interface{}
+	}{
+		{
+			name:  "Empty",
+			build: func(q *ListQuery) error { return nil },
+			query: "SELECT id FROM things",
+		},
+		{
+			name: "In",
+			build: func(q *ListQuery) error {
+				q.In("meeting_id", []int64{1, 2})
+				q.In("venue_id", nil)
+				return nil
+			},
+			query: "SELECT id FROM things WHERE meeting_id IN (?,?)",
+			args:  []interface{}{int64(1), int64(2)},
+		},
+		{
+			name: "InStrings",
+			build: func(q *ListQuery) error {
+				q.InStrings("sports_type", []string{"Tennis"})
+				q.InStrings("name", nil)
+				return nil
+			},
+			query: "SELECT id FROM things WHERE sports_type IN (?)",
+			args:  []interface{}{"Tennis"},
+		},
+		{
+			name: "TimeWindow",
+			build: func(q *ListQuery) error {
+				q.From("starts_at", now)
+				q.Until("starts_at", now.Add(time.Hour))
+				return nil
+			},
+			query: "SELECT id FROM things WHERE datetime(starts_at) >= ? AND datetime(starts_at) < ?",
+			args:  []interface{}{"2026-10-18 12:00:00", "2026-10-18 13:00:00"},
+		},
+		{
+			name: "Status",
+			build: func(q *ListQuery) error {
+				q.Status("starts_at", Open, now)
+				q.Status("starts_at", Closed, now)
+				q.Status("starts_at", "", now)
+				return nil
+			},
+			query: "SELECT id FROM things WHERE datetime(starts_at) >= ? AND datetime(starts_at) < ?",
+			args:  []interface{}{"2026-10-18 12:00:00", "2026-10-18 12:00:00"},
+		},
+		{
+			name: "FilterAndOrder",
+			build: func(q *ListQuery) error {
+				q.Where("visible = true")
+				if err := q.Filter(`name = "Flemington" OR id > 3`, schema); err != nil {
+					return err
+				}
+				return q.OrderBy("name desc", columns)
+			},
+			query: "SELECT id FROM things WHERE visible = true AND (name = ? OR id > ?) ORDER BY name DESC, id",
+			args:  []interface{}{"Flemington", int64(3)},
+		},
+		{
+			name: "BlankFilter",
+			build: func(q *ListQuery) error {
+				return q.Filter(" \t", schema)
+			},
+			query: "SELECT id FROM things",
+		},
+		{
+			name: "FirstPage",
+			build: func(q *ListQuery) error {
+				q.Where("visible = ?", true)
+				return q.Page(10, "")
+			},
+			query: "SELECT id FROM things WHERE visible = ? LIMIT ? OFFSET ?",
+			args:  []interface{}{true, 11, 0},
+		},
+		{
+			name: "NextPage",
+			build: func(q *ListQuery) error {
+				return q.Page(10, encodePageToken(20))
+			},
+			query: "SELECT id FROM things LIMIT ? OFFSET ?",
+			args:  []interface{}{11, 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewListQuery("SELECT id FROM things")
+			require.NoError(t, tt.build(q))
+
+			query, args := q.SQL()
+			assert.Equal(t, tt.query, query)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+func TestListQueryErrors(t *testing.T) {
+	q := NewListQuery("SELECT id FROM things")
+
+	var filterErr *filtering.Error
+	assert.ErrorAs(t, q.Filter(`venue = "Flemington"`, schema), &filterErr)
+
+	var orderErr *OrderByError
+	assert.ErrorAs(t, q.OrderBy("venue", columns), &orderErr)
+
+	for _, token := range []string{"not base64!", "b2ZmOjEw", encodePageToken(0)} {
+		assert.ErrorIs(t, q.Page(10, token), ErrInvalidPageToken, token)
+	}
+
+	query, args := q.SQL()
+	assert.Equal(t, "SELECT id FROM things", query, "failed conditions are not added")
+	assert.Empty(t, args)
+}
+
+func TestPageWithoutOrder(t *testing.T) {
+	q := NewListQuery("SELECT id FROM things")
+	require.NoError(t, q.OrderBy("", columns))
+	require.NoError(t, q.Page(2, ""))
+
+	query, _ := q.SQL()
+	assert.Equal(t, "SELECT id FROM things ORDER BY id LIMIT ? OFFSET ?", query, "pages are only stable in a defined order")
+}
+
+func TestNextPageToken(t *testing.T) {
+	q := NewListQuery("SELECT id FROM things")
+	require.NoError(t, q.Page(2, ""))
+
+	keep, token := q.NextPageToken(3)
+	assert.Equal(t, 2, keep)
+	assert.NotEmpty(t, token)
+
+	require.NoError(t, q.Page(2, token))
+	_, args := q.SQL()
+	assert.Equal(t, []interface{}{3, 2}, args)
+
+	keep, token = q.NextPageToken(2)
+	assert.Equal(t, 2, keep)
+	assert.Empty(t, token, "last page")
+
+	unpaged := NewListQuery("SELECT id FROM things")
+	keep, token = unpaged.NextPageToken(100)
+	assert.Equal(t, 100, keep)
+	assert.Empty(t, token)
+}
+
+func TestStatus(t *testing.T) {
+	assert.Equal(t, Open, Status(now.Add(time.Minute), now))
+	assert.Equal(t, Open, Status(now, now))
+	assert.Equal(t, Closed, Status(now.Add(-time.Minute), now))
+}
+
+func TestNow(t *testing.T) {
+	truncated := Now(clock.NewFake(now.Add(999 * time.Millisecond)))
+
+	assert.Equal(t, now, truncated)
+	assert.Equal(t, Open, Status(now, truncated), "open until the second is over")
+}
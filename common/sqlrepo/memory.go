@@ -0,0 +1,189 @@
+package sqlrepo
+
+import (
+	"sort"
+	"time"
+
+	"git.neds.sh/matty/entain/common/filtering"
+)
+
+// MemoryQuery is the in-memory counterpart of ListQuery: it selects, sorts
+// and pages rows held in memory the way ListQuery does rows of a table. Rows
+// are identified by their index, and their fields read through a function
+// returning them as the types filtering.Match expects. Where ListQuery takes
+// columns, MemoryQuery takes the names of fields.
+type MemoryQuery struct {
+	n       int
+	value   func(i int, field string) interface{}
+	matches []func(i int) bool
+	order   []orderTerm
+	page    *page
+}
+
+var (
+	_ ListBuilder = (*ListQuery)(nil)
+	_ ListBuilder = (*MemoryQuery)(nil)
+)
+
+// NewMemoryQuery starts a query over n rows, whose fields value returns.
+func NewMemoryQuery(n int, value func(i int, field string) interface{}) *MemoryQuery {
+	return &MemoryQuery{n: n, value: value}
+}
+
+// Where adds a condition rows must match.
+func (q *MemoryQuery) Where(match func(i int) bool) {
+	q.matches = append(q.matches, match)
+}
+
+// Equal restricts field to value.
+func (q *MemoryQuery) Equal(field string, value interface{}) {
+	q.Where(func(i int) bool {
+		return filtering.CompareValues(q.value(i, field), value) == 0
+	})
+}
+
+// In restricts field to values. Empty values add no condition.
+func (q *MemoryQuery) In(field string, values []int64) {
+	if len(values) == 0 {
+		return
+	}
+
+	set := make(map[int64]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	q.Where(func(i int) bool {
+		return set[q.value(i, field).(int64)]
+	})
+}
+
+// InStrings restricts field to values. Empty values add no condition.
+func (q *MemoryQuery) InStrings(field string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	q.Where(func(i int) bool {
+		return set[q.value(i, field).(string)]
+	})
+}
+
+// From restricts the time in field to t or later, to the second.
+func (q *MemoryQuery) From(field string, t time.Time) {
+	q.Where(func(i int) bool {
+		return q.value(i, field).(time.Time).Unix() >= t.Unix()
+	})
+}
+
+// Until restricts the time in field to before t, to the second.
+func (q *MemoryQuery) Until(field string, t time.Time) {
+	q.Where(func(i int) bool {
+		return q.value(i, field).(time.Time).Unix() < t.Unix()
+	})
+}
+
+// Status restricts rows to those with the given status at now, derived from
+// the start time in field as Status does. Other statuses add no condition.
+func (q *MemoryQuery) Status(field, status string, now time.Time) {
+	switch status {
+	case Open:
+		q.From(field, now)
+	case Closed:
+		q.Until(field, now)
+	}
+}
+
+// Filter adds an AIP-160 filter expression over the fields of schema. An
+// invalid expression gives a *filtering.Error; an empty or blank one adds
+// nothing.
+func (q *MemoryQuery) Filter(expression string, schema filtering.Schema) error {
+	expr, err := filtering.Parse(expression, schema)
+	if err != nil || expr == nil {
+		return err
+	}
+
+	q.Where(func(i int) bool {
+		return filtering.Match(expr, func(field string) interface{} { return q.value(i, field) })
+	})
+	return nil
+}
+
+// OrderBy sorts the rows by an AIP-132 order_by expression over the fields
+// whitelisted in columns, which must include "id". An invalid expression
+// gives an *OrderByError; an empty one sorts by id, as ListQuery does.
+func (q *MemoryQuery) OrderBy(expression string, columns map[string]string) error {
+	order, err := parseOrderBy(expression, columns)
+	if err != nil {
+		return err
+	}
+
+	q.order = order
+	return nil
+}
+
+// Page limits the query to the page of size rows continuing where the page
+// token left off. A zero size lists every row. A token that was not handed
+// out by Rows gives ErrInvalidPageToken.
+func (q *MemoryQuery) Page(size int32, token string) error {
+	offset, err := decodePageToken(token)
+	if err != nil {
+		return err
+	}
+	if size > 0 {
+		q.page = &page{size: int(size), offset: offset}
+	}
+
+	return nil
+}
+
+// Rows returns the indexes of the rows on the page, in order, and the token
+// of the next page, empty on the last one.
+func (q *MemoryQuery) Rows() ([]int, string) {
+	var rows []int
+	for i := 0; i < q.n; i++ {
+		if q.match(i) {
+			rows = append(rows, i)
+		}
+	}
+
+	sort.SliceStable(rows, func(a, b int) bool {
+		for _, term := range q.order {
+			c := filtering.CompareValues(q.value(rows[a], term.field), q.value(rows[b], term.field))
+			if term.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	if q.page == nil {
+		return rows, ""
+	}
+	if q.page.offset >= len(rows) {
+		return nil, ""
+	}
+
+	rows = rows[q.page.offset:]
+	if len(rows) <= q.page.size {
+		return rows, ""
+	}
+
+	return rows[:q.page.size], encodePageToken(q.page.offset + q.page.size)
+}
+
+func (q *MemoryQuery) match(i int) bool {
+	for _, match := range q.matches {
+		if !match(i) {
+			return false
+		}
+	}
+
+	return true
+}
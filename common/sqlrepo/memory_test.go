@@ -0,0 +1,168 @@
+package sqlrepo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type thing struct {
+	id       int64
+	name     string
+	startsAt time.Time
+}
+
+var things = []thing{
+	{id: 1, name: "Flemington", startsAt: now.Add(-time.Hour)},
+	{id: 2, name: "Randwick", startsAt: now},
+	{id: 3, name: "Flemington", startsAt: now.Add(time.Hour)},
+	{id: 4, name: "Caulfield", startsAt: now.Add(2 * time.Hour)},
+}
+
+func newThingsQuery() *MemoryQuery {
+	return NewMemoryQuery(len(things), func(i int, field string) interface{} {
+		switch field {
+		case "id":
+			return things[i].id
+		case "name":
+			return things[i].name
+		case "starts_at":
+			return things[i].startsAt
+		}
+		panic("unknown field " + field)
+	})
+}
+
+func TestMemoryQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(q *MemoryQuery) error
+		rows  []int
+	}{
+		{
+			name:  "Empty",
+			build: func(q *MemoryQuery) error { return nil },
+			rows:  []int{0, 1, 2, 3},
+		},
+		{
+			name: "InAndEqual",
+			build: func(q *MemoryQuery) error {
+				q.In("id", []int64{1, 2, 3})
+				q.In("id", nil)
+				q.Equal("name", "Flemington")
+				return nil
+			},
+			rows: []int{0, 2},
+		},
+		{
+			name: "InStrings",
+			build: func(q *MemoryQuery) error {
+				q.InStrings("name", []string{"Randwick", "Caulfield"})
+				q.InStrings("name", nil)
+				return nil
+			},
+			rows: []int{1, 3},
+		},
+		{
+			name: "TimeWindow",
+			build: func(q *MemoryQuery) error {
+				q.From("starts_at", now)
+				q.Until("starts_at", now.Add(2*time.Hour))
+				return nil
+			},
+			rows: []int{1, 2},
+		},
+		{
+			name: "Status",
+			build: func(q *MemoryQuery) error {
+				q.Status("starts_at", Closed, now)
+				q.Status("starts_at", "", now)
+				return nil
+			},
+			rows: []int{0},
+		},
+		{
+			name: "FilterAndOrder",
+			build: func(q *MemoryQuery) error {
+				if err := q.Filter(`name = "Flemington" OR id > 3`, schema); err != nil {
+					return err
+				}
+				return q.OrderBy("name desc", columns)
+			},
+			rows: []int{0, 2, 3},
+		},
+		{
+			name: "BlankFilter",
+			build: func(q *MemoryQuery) error {
+				return q.Filter(" \t", schema)
+			},
+			rows: []int{0, 1, 2, 3},
+		},
+		{
+			name: "OrderTiesByID",
+			build: func(q *MemoryQuery) error {
+				return q.OrderBy("name", columns)
+			},
+			rows: []int{3, 0, 2, 1},
+		},
+		{
+			name: "NextPage",
+			build: func(q *MemoryQuery) error {
+				return q.Page(2, encodePageToken(2))
+			},
+			rows: []int{2, 3},
+		},
+		{
+			name: "PastLastPage",
+			build: func(q *MemoryQuery) error {
+				return q.Page(2, encodePageToken(10))
+			},
+			rows: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newThingsQuery()
+			require.NoError(t, tt.build(q))
+
+			rows, token := q.Rows()
+			assert.Equal(t, tt.rows, rows)
+			assert.Empty(t, token)
+		})
+	}
+}
+
+func TestMemoryQueryPages(t *testing.T) {
+	q := newThingsQuery()
+	require.NoError(t, q.OrderBy("id desc", columns))
+	require.NoError(t, q.Page(3, ""))
+
+	rows, token := q.Rows()
+	assert.Equal(t, []int{3, 2, 1}, rows)
+	assert.Equal(t, encodePageToken(3), token)
+}
+
+func TestMemoryQueryPagesWithoutOrder(t *testing.T) {
+	// rows kept out of id order are still paged through by id
+	ids := []int64{3, 1, 4, 2}
+	q := NewMemoryQuery(len(ids), func(i int, field string) interface{} { return ids[i] })
+	require.NoError(t, q.OrderBy("", columns))
+	require.NoError(t, q.Page(2, ""))
+
+	rows, token := q.Rows()
+	assert.Equal(t, []int{1, 3}, rows)
+	assert.Equal(t, encodePageToken(2), token)
+}
+
+func TestMemoryQueryErrors(t *testing.T) {
+	q := newThingsQuery()
+
+	assert.ErrorIs(t, q.Page(10, "bogus"), ErrInvalidPageToken)
+	assert.Error(t, q.Filter(`venue = "Flemington"`, schema))
+
+	var orderErr *OrderByError
+	assert.ErrorAs(t, q.OrderBy("venue", columns), &orderErr)
+}
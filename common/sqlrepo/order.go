@@ -0,0 +1,106 @@
+package sqlrepo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// OrderByError reports an order_by expression that cannot be applied.
+type OrderByError struct {
+	Reason string
+}
+
+func (e *OrderByError) Error() string {
+	return "order_by: " + e.Reason
+}
+
+// orderTerm is a field to sort by.
+type orderTerm struct {
+	field  string
+	column string
+	desc   bool
+}
+
+// orderBy turns an AIP-132 order_by expression, a comma separated list of
+// fields each optionally followed by "asc" or "desc" (e.g.
+// "advertised_start_time desc, number"), into an ORDER BY clause over the
+// fields whitelisted in columns, which maps them to their SQL column. Ties are
+// broken by id so the order is stable, and an empty expression orders by id
+// alone: without an order SQLite may return rows in any, and pages would skip
+// or repeat rows.
+func orderBy(expr string, columns map[string]string) (string, error) {
+	order, err := parseOrderBy(expr, columns)
+	if err != nil {
+		return "", err
+	}
+
+	terms := make([]string, 0, len(order))
+	for _, term := range order {
+		if term.desc {
+			terms = append(terms, term.column+" DESC")
+		} else {
+			terms = append(terms, term.column)
+		}
+	}
+
+	return " ORDER BY " + strings.Join(terms, ", "), nil
+}
+
+// parseOrderBy parses an order_by expression as orderBy describes, ending
+// with the id tie break.
+func parseOrderBy(expr string, columns map[string]string) ([]orderTerm, error) {
+	var (
+		terms []orderTerm
+		seen  = make(map[string]bool)
+		items []string
+	)
+	if strings.TrimSpace(expr) != "" {
+		items = strings.Split(expr, ",")
+	}
+	for _, item := range items {
+		parts := strings.Fields(item)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, &OrderByError{Reason: fmt.Sprintf("malformed term %q", strings.TrimSpace(item))}
+		}
+
+		field := parts[0]
+		column, ok := columns[field]
+		if !ok {
+			return nil, &OrderByError{Reason: fmt.Sprintf("cannot sort by %q, must be one of %s", field, fieldNames(columns))}
+		}
+		if seen[field] {
+			return nil, &OrderByError{Reason: fmt.Sprintf("%q is listed more than once", field)}
+		}
+		seen[field] = true
+
+		term := orderTerm{field: field, column: column}
+		if len(parts) == 2 {
+			switch strings.ToLower(parts[1]) {
+			case "asc":
+			case "desc":
+				term.desc = true
+			default:
+				return nil, &OrderByError{Reason: fmt.Sprintf("%q must be followed by asc or desc, got %q", field, parts[1])}
+			}
+		}
+		terms = append(terms, term)
+	}
+
+	if !seen["id"] {
+		terms = append(terms, orderTerm{field: "id", column: columns["id"]})
+	}
+
+	return terms, nil
+}
+
+// fieldNames lists the fields of columns in alphabetical order.
+func fieldNames(columns map[string]string) string {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
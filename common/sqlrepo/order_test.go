@@ -0,0 +1,46 @@
+package sqlrepo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderBy(t *testing.T) {
+	columns := map[string]string{
+		"id":     "id",
+		"name":   "name",
+		"number": "number",
+	}
+
+	tests := []struct {
+		name   string
+		expr   string
+		clause string
+		err    string
+	}{
+		{name: "Empty", expr: " ", clause: " ORDER BY id"},
+		{name: "SingleField", expr: "name", clause: " ORDER BY name, id"},
+		{name: "Descending", expr: "number desc", clause: " ORDER BY number DESC, id"},
+		{name: "MultipleFields", expr: " number DESC ,name asc", clause: " ORDER BY number DESC, name, id"},
+		{name: "ExplicitID", expr: "id desc, name", clause: " ORDER BY id DESC, name"},
+		{name: "UnknownField", expr: "name, venue", err: `order_by: cannot sort by "venue", must be one of id, name, number`},
+		{name: "Duplicate", expr: "name, name desc", err: `order_by: "name" is listed more than once`},
+		{name: "BadDirection", expr: "name descending", err: `order_by: "name" must be followed by asc or desc, got "descending"`},
+		{name: "EmptyTerm", expr: "name,,number", err: `order_by: malformed term ""`},
+		{name: "TooManyWords", expr: "name desc number", err: `order_by: malformed term "name desc number"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clause, err := orderBy(tt.expr, columns)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.clause, clause)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package sqlrepo
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPageToken is returned for page tokens that were not handed out
+// by a previous page.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// pageTokenPrefix versions page tokens, so their format can change without
+// misreading tokens clients still hold.
+const pageTokenPrefix = "o1:"
+
+// page is a window of rows.
+type page struct {
+	size   int
+	offset int
+}
+
+// encodePageToken makes an opaque token for the page starting at offset.
+func encodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(pageTokenPrefix + strconv.Itoa(offset)))
+}
+
+// decodePageToken returns the offset of the page token, 0 for the empty
+// token of the first page.
+func decodePageToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !strings.HasPrefix(string(data), pageTokenPrefix) {
+		return 0, ErrInvalidPageToken
+	}
+
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(data), pageTokenPrefix))
+	if err != nil || offset <= 0 {
+		return 0, ErrInvalidPageToken
+	}
+
+	return offset, nil
+}
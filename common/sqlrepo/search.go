@@ -0,0 +1,152 @@
+package sqlrepo
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// TextIndex is an FTS5 full-text index over text columns of a table with an
+// integer id primary key, named after the table with an _fts suffix.
+type TextIndex struct {
+	Table   string
+	Columns []string
+}
+
+// Name is the name of the index's virtual table.
+func (x TextIndex) Name() string {
+	return x.Table + "_fts"
+}
+
+// Init creates the index, kept in sync with the table by triggers, and
+// reports whether it is available. FTS5 is only compiled into the SQLite
+// driver with the sqlite_fts5 build tag; without it Init does nothing and
+// searches have to fall back to LIKE matching.
+func (x TextIndex) Init(db *sql.DB) (bool, error) {
+	var available bool
+	if err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&available); err != nil || !available {
+		return false, err
+	}
+
+	var (
+		name    = x.Name()
+		columns = strings.Join(x.Columns, ", ")
+		newRow  = "new.id, new." + strings.Join(x.Columns, ", new.")
+		oldRow  = "old.id, old." + strings.Join(x.Columns, ", old.")
+	)
+	for _, statement := range []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS ` + name + ` USING fts5(` + columns + `, content='` + x.Table + `', content_rowid='id')`,
+		`CREATE TRIGGER IF NOT EXISTS ` + name + `_insert AFTER INSERT ON ` + x.Table + ` BEGIN
+			INSERT INTO ` + name + `(rowid, ` + columns + `) VALUES (` + newRow + `);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS ` + name + `_delete AFTER DELETE ON ` + x.Table + ` BEGIN
+			INSERT INTO ` + name + `(` + name + `, rowid, ` + columns + `) VALUES ('delete', ` + oldRow + `);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS ` + name + `_update AFTER UPDATE OF ` + columns + ` ON ` + x.Table + ` BEGIN
+			INSERT INTO ` + name + `(` + name + `, rowid, ` + columns + `) VALUES ('delete', ` + oldRow + `);
+			INSERT INTO ` + name + `(rowid, ` + columns + `) VALUES (` + newRow + `);
+		END`,
+		// catch up with rows written while the triggers were missing
+		`INSERT INTO ` + name + `(` + name + `) VALUES ('rebuild')`,
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// DropTriggers drops the triggers maintaining the index. Left behind by a
+// build with FTS5, they would fail every write to the table in one without
+// it, so they should be dropped before seeding and recreated by Init.
+func (x TextIndex) DropTriggers(db *sql.DB) error {
+	for _, suffix := range []string{"_insert", "_delete", "_update"} {
+		if _, err := db.Exec(`DROP TRIGGER IF EXISTS ` + x.Name() + suffix); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Like adds a condition to q for every term, matching rows where one of the
+// indexed columns contains it. It is the fallback when the index is not
+// available.
+func (x TextIndex) Like(q *ListQuery, terms []string) {
+	for _, term := range terms {
+		var (
+			clauses = make([]string, 0, len(x.Columns))
+			args    = make([]interface{}, 0, len(x.Columns))
+			pattern = "%" + EscapeLike(term) + "%"
+		)
+		for _, column := range x.Columns {
+			clauses = append(clauses, column+` LIKE ? ESCAPE '\'`)
+			args = append(args, pattern)
+		}
+
+		q.Where("("+strings.Join(clauses, " OR ")+")", args...)
+	}
+}
+
+// FTSQuery turns search terms into an FTS5 query matching rows with words
+// prefixed by every term. Terms are quoted so FTS5 operators in them are
+// taken literally.
+func FTSQuery(terms []string) string {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted = append(quoted, `"`+strings.ReplaceAll(term, `"`, `""`)+`"*`)
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// EscapeLike escapes the LIKE wildcards in s, for use with ESCAPE '\'.
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// MatchFields reports whether every term prefixes a word of one of the
+// fields, returning the fields with matching words wrapped in <mark></mark>
+// and the share of all their words that matched.
+func MatchFields(fields map[string]string, terms []string) (map[string]string, float64, bool) {
+	words := make(map[string][]string, len(fields))
+	matched := make(map[string][]bool, len(fields))
+	total := 0
+	for field, text := range fields {
+		words[field] = strings.Fields(text)
+		matched[field] = make([]bool, len(words[field]))
+		total += len(words[field])
+	}
+
+	for _, term := range terms {
+		found := false
+		for field := range fields {
+			for i, word := range words[field] {
+				if strings.HasPrefix(strings.ToLower(word), strings.ToLower(term)) {
+					matched[field][i], found = true, true
+				}
+			}
+		}
+		if !found {
+			return nil, 0, false
+		}
+	}
+
+	highlights := make(map[string]string)
+	count := 0
+	for field := range fields {
+		marked := false
+		for i, word := range words[field] {
+			if matched[field][i] {
+				words[field][i] = "<mark>" + word + "</mark>"
+				marked = true
+				count++
+			}
+		}
+		if marked {
+			highlights[field] = strings.Join(words[field], " ")
+		}
+	}
+
+	return highlights, float64(count) / float64(total), true
+}
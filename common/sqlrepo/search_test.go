@@ -0,0 +1,66 @@
+package sqlrepo_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.neds.sh/matty/entain/common/sqlrepo"
+	"git.neds.sh/matty/entain/common/sqlrepo/sqlrepotest"
+)
+
+var index = sqlrepo.TextIndex{Table: "things", Columns: []string{"name", "kind"}}
+
+func TestTextIndex(t *testing.T) {
+	db := sqlrepotest.OpenSQLite(t)
+	_, err := db.Exec(`CREATE TABLE things (id INTEGER PRIMARY KEY, name TEXT, kind TEXT)`)
+	require.NoError(t, err)
+
+	require.NoError(t, index.DropTriggers(db), "nothing to drop yet")
+
+	fts, err := index.Init(db)
+	require.NoError(t, err)
+	if !fts {
+		t.Skip("SQLite driver built without FTS5, see the sqlite_fts5 build tag")
+	}
+
+	_, err = db.Exec(`INSERT INTO things(id, name, kind) VALUES (1, 'Flemington Cup', 'Horse'), (2, 'Grand Final', 'Football')`)
+	require.NoError(t, err)
+
+	var id int64
+	require.NoError(t, db.QueryRow(`SELECT rowid FROM things_fts WHERE things_fts MATCH ?`, sqlrepo.FTSQuery([]string{"foot"})).Scan(&id))
+	assert.Equal(t, int64(2), id)
+
+	// once the triggers are dropped, writes no longer touch the index
+	require.NoError(t, index.DropTriggers(db))
+	_, err = db.Exec(`UPDATE things SET name = 'Melbourne Cup' WHERE id = 1`)
+	require.NoError(t, err)
+}
+
+func TestLike(t *testing.T) {
+	q := sqlrepo.NewListQuery("SELECT id FROM things")
+	index.Like(q, []string{"cup", "50%"})
+
+	query, args := q.SQL()
+	assert.Equal(t, `SELECT id FROM things WHERE (name LIKE ? ESCAPE '\' OR kind LIKE ? ESCAPE '\') AND (name LIKE ? ESCAPE '\' OR kind LIKE ? ESCAPE '\')`, query)
+	assert.Equal(t, []interface{}{"%cup%", "%cup%", `%50\%%`, `%50\%%`}, args)
+}
+
+func TestFTSQuery(t *testing.T) {
+	assert.Equal(t, `"grand"* "a""b"* "OR"*`, sqlrepo.FTSQuery([]string{"grand", `a"b`, "OR"}))
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `50\% off\_now \\o/`, sqlrepo.EscapeLike(`50% off_now \o/`))
+}
+
+func TestMatchFields(t *testing.T) {
+	highlights, score, ok := sqlrepo.MatchFields(map[string]string{"name": "Grand Final Day", "kind": "Football"}, []string{"fin", "FOOT"})
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"name": "Grand <mark>Final</mark> Day", "kind": "<mark>Football</mark>"}, highlights)
+	assert.Equal(t, 0.5, score)
+
+	_, _, ok = sqlrepo.MatchFields(map[string]string{"name": "Grand Final Day"}, []string{"final", "cup"})
+	assert.False(t, ok, "every term must match")
+}
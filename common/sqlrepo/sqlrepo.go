@@ -0,0 +1,37 @@
+// Package sqlrepo holds the SQL plumbing shared by the service repositories:
+// building list queries from filters, sorting and pagination, deriving
+// statuses, logging slow queries and full-text search. MemoryQuery lists rows
+// held in memory the same way, for repositories without a database.
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"git.neds.sh/matty/entain/common/logging"
+)
+
+// ErrNotFound is returned when the requested row does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Query runs the given query, logging a warning with the request's log fields
+// if it took longer than slowThreshold. A zero threshold disables the warning.
+func Query(ctx context.Context, db *sql.DB, slowThreshold time.Duration, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, query, args...)
+
+	if elapsed := time.Since(start); slowThreshold > 0 && elapsed > slowThreshold {
+		logging.FromContext(ctx).WithFields(logrus.Fields{
+			"query":    strings.Join(strings.Fields(query), " "),
+			"args":     args,
+			"duration": elapsed.String(),
+		}).Warn("slow query")
+	}
+
+	return rows, err
+}
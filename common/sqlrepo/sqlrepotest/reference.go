@@ -0,0 +1,157 @@
+package sqlrepotest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"git.neds.sh/matty/entain/common/filtering"
+)
+
+// Row gives the value of a row's field by name: an int64, string, bool or
+// time.Time, as filter expressions compare them.
+type Row func(field string) interface{}
+
+// Match is the reference implementation of filter expressions: it reports
+// whether row matches e the way the SQL compiled from e would, comparing
+// timestamps to the second. A nil e matches every row.
+func Match(e filtering.Expr, row Row) bool {
+	switch e := e.(type) {
+	case nil:
+		return true
+	case filtering.And:
+		for _, term := range e.Terms {
+			if !Match(term, row) {
+				return false
+			}
+		}
+		return true
+	case filtering.Or:
+		for _, term := range e.Terms {
+			if Match(term, row) {
+				return true
+			}
+		}
+		return false
+	case filtering.Not:
+		return !Match(e.Term, row)
+	case filtering.Compare:
+		c := Compare(row(e.Field), e.Value)
+		switch e.Op {
+		case filtering.Eq:
+			return c == 0
+		case filtering.Ne:
+			return c != 0
+		case filtering.Lt:
+			return c < 0
+		case filtering.Le:
+			return c <= 0
+		case filtering.Gt:
+			return c > 0
+		case filtering.Ge:
+			return c >= 0
+		}
+	case filtering.In:
+		for _, v := range e.Values {
+			if Compare(row(e.Field), v) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	panic(fmt.Sprintf("unexpected expression %#v", e))
+}
+
+// Compare compares two values of the same type, returning -1, 0 or 1.
+// Booleans order false first; times compare to the second.
+func Compare(a, b interface{}) int {
+	switch a := a.(type) {
+	case int64:
+		return compareInts(a, b.(int64))
+	case string:
+		return strings.Compare(a, b.(string))
+	case bool:
+		return compareInts(boolInt(a), boolInt(b.(bool)))
+	case time.Time:
+		return compareInts(a.Unix(), b.(time.Time).Unix())
+	}
+
+	panic(fmt.Sprintf("unexpected value %#v", a))
+}
+
+// Sort is the reference implementation of AIP-132 order_by expressions: it
+// sorts rows by the fields of order the way sqlrepo.ListQuery.OrderBy would,
+// breaking ties by id. It reports false, leaving rows as they are, for an
+// expression OrderBy rejects. An empty expression sorts by id.
+func Sort(rows []Row, order string, columns map[string]string) bool {
+	type key struct {
+		field string
+		desc  bool
+	}
+
+	var keys []key
+	seen := make(map[string]bool)
+	if strings.TrimSpace(order) != "" {
+		for _, item := range strings.Split(order, ",") {
+			parts := strings.Fields(item)
+			if len(parts) == 0 || len(parts) > 2 {
+				return false
+			}
+			if _, ok := columns[parts[0]]; !ok || seen[parts[0]] {
+				return false
+			}
+			seen[parts[0]] = true
+
+			k := key{field: parts[0]}
+			if len(parts) == 2 {
+				switch strings.ToLower(parts[1]) {
+				case "asc":
+				case "desc":
+					k.desc = true
+				default:
+					return false
+				}
+			}
+			keys = append(keys, k)
+		}
+	}
+	if !seen["id"] {
+		keys = append(keys, key{field: "id"})
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, k := range keys {
+			c := Compare(rows[i](k.field), rows[j](k.field))
+			if k.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	return true
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
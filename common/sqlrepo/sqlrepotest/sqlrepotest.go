@@ -0,0 +1,44 @@
+// Package sqlrepotest helps testing repositories built on sqlrepo.
+package sqlrepotest
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// NewMock returns a mock database and its controller. Once the test ends the
+// database is closed and the test fails if an expectation was not met.
+func NewMock(t testing.TB) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("creating mock database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+
+	return db, mock
+}
+
+// OpenSQLite opens an empty SQLite database in a file removed once the test
+// ends.
+func OpenSQLite(t testing.TB) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
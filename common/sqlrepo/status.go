@@ -0,0 +1,31 @@
+package sqlrepo
+
+import (
+	"time"
+
+	"git.neds.sh/matty/entain/common/clock"
+)
+
+// Statuses derived from a start time.
+const (
+	Open   = "OPEN"
+	Closed = "CLOSED"
+)
+
+// Status derives the status of something starting at start: CLOSED once it
+// has passed now, OPEN until then.
+func Status(start, now time.Time) string {
+	if start.Before(now) {
+		return Closed
+	}
+
+	return Open
+}
+
+// Now returns the time statuses and time filters are evaluated at: the time
+// of clk to the second, the precision start times are stored at. Take it once
+// per request and use it for both, so the status of a row always agrees with
+// the status filter it was listed by.
+func Now(clk clock.Clock) time.Time {
+	return clk.Now().Truncate(time.Second)
+}
@@ -0,0 +1,224 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"git.neds.sh/matty/entain/common/logging"
+)
+
+// Reloader holds a certificate/key pair and a CA bundle loaded from disk and
+// reloads them whenever the files change, so certificates can be rotated
+// without restarting. Either part is optional.
+type Reloader struct {
+	certFile, keyFile, caFile string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	stamps  map[string]stamp
+	sysPool *x509.CertPool
+}
+
+// stamp identifies a version of a file on disk.
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewReloader loads the given files. certFile and keyFile must be given
+// together; caFile may be empty to trust the system roots.
+func NewReloader(certFile, keyFile, caFile string) (*Reloader, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("certificate and key files must be given together")
+	}
+
+	r := &Reloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Run checks the files for changes every interval until ctx is done. Failed
+// reloads are logged and the previously loaded certificates stay in use.
+func (r *Reloader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+
+			if err := r.reload(); err != nil {
+				logging.FromContext(ctx).WithError(err).Error("failed reloading TLS certificates, keeping previous ones")
+			} else {
+				logging.FromContext(ctx).Info("reloaded TLS certificates")
+			}
+		}
+	}
+}
+
+// ServerConfig returns a server side TLS config always presenting the most
+// recently loaded certificate. Client certificates are verified against the
+// loaded CA bundle according to clientAuth.
+func (r *Reloader) ServerConfig(clientAuth tls.ClientAuthType, nextProtos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: nextProtos,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.cert, nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+
+			cfg := &tls.Config{
+				MinVersion: tls.VersionTLS12,
+				NextProtos: nextProtos,
+				ClientAuth: clientAuth,
+				ClientCAs:  r.pool,
+			}
+			if r.cert != nil {
+				cfg.Certificates = []tls.Certificate{*r.cert}
+			}
+
+			return cfg, nil
+		},
+	}
+}
+
+// ClientConfig returns a client side TLS config presenting the most recently
+// loaded certificate (if any) and verifying servers against the most recently
+// loaded CA bundle. serverName overrides the name expected on the server
+// certificate; if empty the dialled host name is used.
+func (r *Reloader) ClientConfig(serverName string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		// The standard verification can only use a fixed set of roots, so it
+		// is replaced by VerifyConnection which uses the current CA bundle.
+		InsecureSkipVerify: true,
+		VerifyConnection:   r.verifyServer,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+
+			if r.cert == nil {
+				return &tls.Certificate{}, nil
+			}
+			return r.cert, nil
+		},
+	}
+}
+
+func (r *Reloader) verifyServer(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+
+	r.mu.RLock()
+	roots := r.pool
+	if roots == nil {
+		roots = r.sysPool
+	}
+	r.mu.RUnlock()
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       cs.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// reload loads all files, replacing the current certificates only if every
+// file could be loaded.
+func (r *Reloader) reload() error {
+	stamps := make(map[string]stamp)
+	for _, path := range []string{r.certFile, r.keyFile, r.caFile} {
+		if path == "" {
+			continue
+		}
+		st, err := statFile(path)
+		if err != nil {
+			return err
+		}
+		stamps[path] = st
+	}
+
+	var cert *tls.Certificate
+	if r.certFile != "" {
+		c, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("loading key pair: %w", err)
+		}
+		cert = &c
+	}
+
+	var pool, sysPool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("loading CA bundle: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("loading CA bundle: no certificates found in %s", r.caFile)
+		}
+	} else {
+		var err error
+		if sysPool, err = x509.SystemCertPool(); err != nil {
+			return fmt.Errorf("loading system CA bundle: %w", err)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.pool, r.sysPool, r.stamps = cert, pool, sysPool, stamps
+	r.mu.Unlock()
+
+	return nil
+}
+
+// changed reports whether any of the files differs from when it was loaded.
+func (r *Reloader) changed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for path, loaded := range r.stamps {
+		current, err := statFile(path)
+		if err != nil || current != loaded {
+			return true
+		}
+	}
+
+	return false
+}
+
+func statFile(path string) (stamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return stamp{}, err
+	}
+
+	return stamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
@@ -0,0 +1,197 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA issues certificates for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes a leaf certificate for name and its key into dir, returning
+// the file paths.
+func (ca *testCA) issue(t *testing.T, dir, name string, serial int64) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certFile, keyFile
+}
+
+func (ca *testCA) write(t *testing.T, dir, name string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, ca.pem, 0o600))
+	return path
+}
+
+// handshake runs a TLS handshake between the given configs over a loopback
+// connection, returning the certificate the server presented.
+func handshake(t *testing.T, server, client *tls.Config) (*x509.Certificate, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		tlsConn := tls.Server(conn, server)
+		serverErr <- tlsConn.Handshake()
+		tlsConn.Close()
+	}()
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), client)
+	if err != nil {
+		<-serverErr
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := <-serverErr; err != nil {
+		return nil, err
+	}
+
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := ca.write(t, dir, "ca.crt")
+	serverCert, serverKey := ca.issue(t, dir, "racing", 2)
+	clientCert, clientKey := ca.issue(t, dir, "api", 3)
+
+	server, err := NewReloader(serverCert, serverKey, caFile)
+	require.NoError(t, err)
+	serverConfig := server.ServerConfig(tls.RequireAndVerifyClientCert)
+
+	t.Run("ValidClient", func(t *testing.T) {
+		client, err := NewReloader(clientCert, clientKey, caFile)
+		require.NoError(t, err)
+
+		_, err = handshake(t, serverConfig, client.ClientConfig("racing"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("MissingClientCertificate", func(t *testing.T) {
+		client, err := NewReloader("", "", caFile)
+		require.NoError(t, err)
+
+		_, err = handshake(t, serverConfig, client.ClientConfig("racing"))
+		assert.Error(t, err)
+	})
+
+	t.Run("UntrustedServer", func(t *testing.T) {
+		otherCA := newTestCA(t)
+		client, err := NewReloader(clientCert, clientKey, otherCA.write(t, t.TempDir(), "other.crt"))
+		require.NoError(t, err)
+
+		_, err = handshake(t, serverConfig, client.ClientConfig("racing"))
+		assert.Error(t, err)
+	})
+
+	t.Run("WrongServerName", func(t *testing.T) {
+		client, err := NewReloader(clientCert, clientKey, caFile)
+		require.NoError(t, err)
+
+		_, err = handshake(t, serverConfig, client.ClientConfig("sports"))
+		assert.Error(t, err)
+	})
+}
+
+func TestReloadOnChange(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := ca.write(t, dir, "ca.crt")
+	serverCert, serverKey := ca.issue(t, dir, "racing", 10)
+
+	server, err := NewReloader(serverCert, serverKey, "")
+	require.NoError(t, err)
+	client, err := NewReloader("", "", caFile)
+	require.NoError(t, err)
+
+	presented, err := handshake(t, server.ServerConfig(tls.NoClientCert), client.ClientConfig("racing"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), presented.SerialNumber.Int64())
+	assert.False(t, server.changed())
+
+	// rotate the certificate in place, as a secret manager would
+	later := time.Now().Add(time.Minute)
+	ca.issue(t, dir, "racing", 11)
+	require.NoError(t, os.Chtimes(serverCert, later, later))
+	require.NoError(t, os.Chtimes(serverKey, later, later))
+
+	assert.True(t, server.changed())
+	require.NoError(t, server.reload())
+
+	presented, err = handshake(t, server.ServerConfig(tls.NoClientCert), client.ClientConfig("racing"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), presented.SerialNumber.Int64())
+}
+
+func TestNewReloaderErrors(t *testing.T) {
+	_, err := NewReloader("server.crt", "", "")
+	assert.Error(t, err)
+
+	_, err = NewReloader("", "", filepath.Join(t.TempDir(), "missing.crt"))
+	assert.Error(t, err)
+}
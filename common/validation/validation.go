@@ -0,0 +1,221 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"git.neds.sh/matty/entain/common/proto/validate"
+)
+
+// UnaryServerInterceptor rejects requests breaking the rules declared on
+// their fields with (validate.field) options, answering INVALID_ARGUMENT with
+// a google.rpc.BadRequest detail listing every violation.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if msg, ok := req.(proto.Message); ok {
+			if err := Error(Validate(msg)); err != nil {
+				return nil, err
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Error returns the INVALID_ARGUMENT status reporting violations, or nil if
+// there are none.
+func Error(violations []*errdetails.BadRequest_FieldViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, 0, len(violations))
+	for _, v := range violations {
+		msgs = append(msgs, v.Field+": "+v.Description)
+	}
+
+	st := status.New(codes.InvalidArgument, "invalid request: "+strings.Join(msgs, "; "))
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		st = detailed
+	}
+
+	return st.Err()
+}
+
+// Validate checks msg, and every message set within it, against the rules
+// declared on their fields. Fields are named by their path from msg, e.g.
+// "filter.meeting_ids[2]".
+func Validate(msg proto.Message) []*errdetails.BadRequest_FieldViolation {
+	var v validator
+	v.message("", msg.ProtoReflect())
+
+	return v.violations
+}
+
+type validator struct {
+	violations []*errdetails.BadRequest_FieldViolation
+}
+
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.violations = append(v.violations, &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) message(prefix string, m protoreflect.Message) {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := prefix + string(fd.Name())
+
+		if rules := fieldRules(fd); rules != nil && !(rules.IgnoreEmpty && !m.Has(fd)) {
+			v.field(path, fd, m.Get(fd), rules)
+		}
+
+		if fd.Kind() != protoreflect.MessageKind && fd.Kind() != protoreflect.GroupKind {
+			continue
+		}
+		switch {
+		case fd.IsMap():
+			// no rules are declared within map values
+		case fd.IsList():
+			list := m.Get(fd).List()
+			for j := 0; j < list.Len(); j++ {
+				v.message(fmt.Sprintf("%s[%d].", path, j), list.Get(j).Message())
+			}
+		case m.Has(fd):
+			v.message(path+".", m.Get(fd).Message())
+		}
+	}
+}
+
+// fieldRules returns the rules declared on fd, if any.
+func fieldRules(fd protoreflect.FieldDescriptor) *validate.FieldRules {
+	opts := fd.Options()
+	if opts == nil || !proto.HasExtension(opts, validate.E_Field) {
+		return nil
+	}
+
+	rules, _ := proto.GetExtension(opts, validate.E_Field).(*validate.FieldRules)
+	return rules
+}
+
+func (v *validator) field(path string, fd protoreflect.FieldDescriptor, value protoreflect.Value, rules *validate.FieldRules) {
+	if fd.IsList() {
+		list := value.List()
+		v.repeated(path, list, rules.GetRepeated())
+		for i := 0; i < list.Len(); i++ {
+			v.scalar(fmt.Sprintf("%s[%d]", path, i), fd, list.Get(i), rules)
+		}
+		return
+	}
+
+	if !fd.IsMap() {
+		v.scalar(path, fd, value, rules)
+	}
+}
+
+func (v *validator) repeated(path string, list protoreflect.List, rules *validate.RepeatedRules) {
+	if rules == nil {
+		return
+	}
+
+	if rules.MaxItems != nil && uint64(list.Len()) > rules.GetMaxItems() {
+		v.add(path, "must have at most %d items", rules.GetMaxItems())
+	}
+
+	if rules.Unique {
+		seen := make(map[interface{}]bool, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			item := list.Get(i).Interface()
+			if seen[item] {
+				v.add(path, "must not contain duplicates")
+				return
+			}
+			seen[item] = true
+		}
+	}
+}
+
+func (v *validator) scalar(path string, fd protoreflect.FieldDescriptor, value protoreflect.Value, rules *validate.FieldRules) {
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v.int(path, value.Int(), rules.GetInt())
+	case protoreflect.StringKind:
+		v.string(path, value.String(), rules.GetString_())
+	case protoreflect.EnumKind:
+		if rules.GetEnum().GetDefinedOnly() && fd.Enum().Values().ByNumber(value.Enum()) == nil {
+			v.add(path, "must be a defined %s value", fd.Enum().Name())
+		}
+	case protoreflect.MessageKind:
+		if d, ok := value.Message().Interface().(*durationpb.Duration); ok && value.Message().IsValid() {
+			v.duration(path, d.AsDuration(), rules.GetDuration())
+		}
+	}
+}
+
+func (v *validator) duration(path string, d time.Duration, rules *validate.DurationRules) {
+	if rules == nil {
+		return
+	}
+
+	switch {
+	case rules.Gt != nil && d <= rules.Gt.AsDuration():
+		v.add(path, "must be longer than %s", rules.Gt.AsDuration())
+	case rules.Lte != nil && d > rules.Lte.AsDuration():
+		v.add(path, "must be at most %s", rules.Lte.AsDuration())
+	}
+}
+
+func (v *validator) int(path string, n int64, rules *validate.IntRules) {
+	if rules == nil {
+		return
+	}
+
+	switch {
+	case rules.Gt != nil && n <= rules.GetGt():
+		v.add(path, "must be greater than %d", rules.GetGt())
+	case rules.Gte != nil && n < rules.GetGte():
+		v.add(path, "must be greater than or equal to %d", rules.GetGte())
+	case rules.Lt != nil && n >= rules.GetLt():
+		v.add(path, "must be less than %d", rules.GetLt())
+	case rules.Lte != nil && n > rules.GetLte():
+		v.add(path, "must be less than or equal to %d", rules.GetLte())
+	}
+}
+
+func (v *validator) string(path, s string, rules *validate.StringRules) {
+	if rules == nil {
+		return
+	}
+
+	if len(rules.In) > 0 {
+		allowed := false
+		for _, in := range rules.In {
+			allowed = allowed || s == in
+		}
+		if !allowed {
+			v.add(path, "must be one of %s", strings.Join(rules.In, ", "))
+		}
+	}
+
+	if rules.MinLen != nil && uint64(utf8.RuneCountInString(strings.TrimSpace(s))) < rules.GetMinLen() {
+		v.add(path, "must be at least %d characters", rules.GetMinLen())
+	}
+	if rules.MaxLen != nil && uint64(utf8.RuneCountInString(s)) > rules.GetMaxLen() {
+		v.add(path, "must be at most %d characters", rules.GetMaxLen())
+	}
+}
@@ -0,0 +1,110 @@
+package validation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"git.neds.sh/matty/entain/common/proto/validatetest"
+)
+
+func fields(violations []*errdetails.BadRequest_FieldViolation) map[string]string {
+	m := make(map[string]string, len(violations))
+	for _, v := range violations {
+		m[v.Field] = v.Description
+	}
+
+	return m
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		assert.Empty(t, Validate(&validatetest.Request{Id: 1, Query: "kings"}))
+		assert.Empty(t, Validate(&validatetest.Request{Id: 1, Query: "kings", SortBy: "name", Filter: &validatetest.Filter{
+			Ids:    []int64{1, 2, 3},
+			Kind:   validatetest.Kind_KIND_A,
+			Within: durationpb.New(time.Hour),
+			Order:  1,
+		}}))
+	})
+
+	t.Run("Int", func(t *testing.T) {
+		assert.Equal(t, map[string]string{"id": "must be greater than 0"}, fields(Validate(&validatetest.Request{Id: -3, Query: "x"})))
+		assert.Equal(t, map[string]string{
+			"filter.order": "must be less than or equal to 1",
+		}, fields(Validate(&validatetest.Request{Id: 1, Query: "x", Filter: &validatetest.Filter{Order: 2}})))
+	})
+
+	t.Run("String", func(t *testing.T) {
+		assert.Equal(t, map[string]string{
+			"query":   "must be at least 1 characters",
+			"sort_by": "must be one of name, number",
+		}, fields(Validate(&validatetest.Request{Id: 1, Query: "  ", SortBy: "id"})))
+		assert.Equal(t, map[string]string{
+			"query": "must be at most 10 characters",
+		}, fields(Validate(&validatetest.Request{Id: 1, Query: "kings of the north"})))
+	})
+
+	t.Run("Repeated", func(t *testing.T) {
+		violations := Validate(&validatetest.Request{Id: 1, Query: "x", Filter: &validatetest.Filter{Ids: []int64{1, 2, -3, 4}}})
+
+		assert.Equal(t, map[string]string{
+			"filter.ids":    "must have at most 3 items",
+			"filter.ids[2]": "must be greater than 0",
+		}, fields(violations))
+
+		violations = Validate(&validatetest.Request{Id: 1, Query: "x", Filter: &validatetest.Filter{Ids: []int64{1, 1}}})
+		assert.Equal(t, map[string]string{"filter.ids": "must not contain duplicates"}, fields(violations))
+	})
+
+	t.Run("Enum", func(t *testing.T) {
+		violations := Validate(&validatetest.Request{Id: 1, Query: "x", Filter: &validatetest.Filter{Kind: 7}})
+		assert.Equal(t, map[string]string{"filter.kind": "must be a defined Kind value"}, fields(violations))
+	})
+
+	t.Run("Duration", func(t *testing.T) {
+		for d, want := range map[time.Duration]string{
+			0:             "must be longer than 0s",
+			2 * time.Hour: "must be at most 1h0m0s",
+		} {
+			violations := Validate(&validatetest.Request{Id: 1, Query: "x", Filter: &validatetest.Filter{Within: durationpb.New(d)}})
+			assert.Equal(t, map[string]string{"filter.within": want}, fields(violations), d)
+		}
+	})
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/validatetest.Test/Get"}
+
+	called := false
+	handler := func(context.Context, interface{}) (interface{}, error) {
+		called = true
+		return &validatetest.Request{}, nil
+	}
+
+	_, err := interceptor(context.Background(), &validatetest.Request{Id: 1, Query: "x"}, info, handler)
+	require.NoError(t, err)
+	assert.True(t, called)
+
+	called = false
+	_, err = interceptor(context.Background(), &validatetest.Request{Id: -1, Query: "x"}, info, handler)
+	assert.False(t, called)
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "invalid request: id: must be greater than 0", st.Message())
+	require.Len(t, st.Details(), 1)
+
+	badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"id": "must be greater than 0"}, fields(badRequest.FieldViolations))
+}
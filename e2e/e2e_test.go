@@ -0,0 +1,281 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// now is when the tests run, as far as the services can tell.
+var now = time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+
+var fixtures = Fixtures{
+	Races: []Race{
+		{ID: 1, MeetingID: 1, Name: "Flemington Cup", Number: 3, Visible: true, AdvertisedStart: now.Add(-2 * time.Hour)},
+		{ID: 2, MeetingID: 1, Name: "Flemington Sprint", Number: 1, Visible: true, AdvertisedStart: now.Add(30 * time.Minute)},
+		{ID: 3, MeetingID: 2, Name: "Randwick Plate", Number: 2, Visible: false, AdvertisedStart: now.Add(90 * time.Minute)},
+		{ID: 4, MeetingID: 3, Name: "Caulfield Guineas", Number: 5, Visible: true, AdvertisedStart: now.Add(26 * time.Hour)},
+	},
+	Events: []Event{
+		{ID: 1, EventID: 10, SportsType: "Tennis", Name: "Wimbledon Final", Number: 1, AdvertisedStart: now.Add(-time.Hour)},
+		{ID: 2, EventID: 10, SportsType: "Tennis", Name: "Wimbledon Semi Final", Number: 2, AdvertisedStart: now.Add(time.Hour)},
+		{ID: 3, EventID: 20, SportsType: "Football", Name: "Grand Final", Number: 1, AdvertisedStart: now.Add(3 * time.Hour)},
+	},
+}
+
+type race struct {
+	ID                  string    `json:"id"`
+	MeetingID           string    `json:"meetingId"`
+	Name                string    `json:"name"`
+	Visible             bool      `json:"visible"`
+	AdvertisedStartTime time.Time `json:"advertisedStartTime"`
+	Status              string    `json:"status"`
+}
+
+type listRacesResponse struct {
+	Races         []race `json:"races"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+type event struct {
+	ID         string `json:"id"`
+	EventID    string `json:"eventId"`
+	SportsType string `json:"sportsType"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+}
+
+type listEventsResponse struct {
+	Events        []event `json:"events"`
+	NextPageToken string  `json:"nextPageToken"`
+}
+
+type errorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Details []struct {
+		FieldViolations []struct {
+			Field string `json:"field"`
+		} `json:"fieldViolations"`
+	} `json:"details"`
+}
+
+func raceIDs(races []race) []string {
+	ids := make([]string, 0, len(races))
+	for _, r := range races {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func eventIDs(events []event) []string {
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestListRaces(t *testing.T) {
+	h := Start(t, now, fixtures)
+
+	tests := []struct {
+		name string
+		body string
+		ids  []string
+	}{
+		{name: "All", body: `{}`, ids: []string{"1", "2", "3", "4"}},
+		{name: "MeetingIDs", body: `{"filter":{"meeting_ids":[1,3]}}`, ids: []string{"1", "2", "4"}},
+		{name: "Visible", body: `{"filter":{"visibility":"VISIBILE"}}`, ids: []string{"1", "2", "4"}},
+		{name: "Hidden", body: `{"filter":{"visibility":"HIDDEN"}}`, ids: []string{"3"}},
+		{name: "Open", body: `{"filter":{"status":"OPEN"}}`, ids: []string{"2", "3", "4"}},
+		{name: "Closed", body: `{"filter":{"status":"CLOSED"}}`, ids: []string{"1"}},
+		{name: "StartsWithin", body: `{"filter":{"starts_within":"7200s"}}`, ids: []string{"2", "3"}},
+		{name: "StartTimeWindow", body: `{"filter":{"start_time_from":"2026-10-18T10:00:00Z","start_time_to":"2026-10-18T12:30:00Z"}}`, ids: []string{"1"}},
+		{name: "Expression", body: `{"filter":{"expression":"meeting_id = 1 AND name != \"Flemington Cup\""}}`, ids: []string{"2"}},
+		{name: "OrderBy", body: `{"order_by":"advertised_start_time desc"}`, ids: []string{"4", "3", "2", "1"}},
+		{name: "OrderByMultipleFields", body: `{"order_by":"meeting_id desc, number"}`, ids: []string{"4", "3", "2", "1"}},
+		{name: "LegacySortBy", body: `{"filter":{"sort_by":"number","order_by":1}}`, ids: []string{"4", "1", "3", "2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp listRacesResponse
+			status := h.DoJSON(http.MethodPost, "/v1/list-races", tt.body, &resp)
+
+			require.Equal(t, http.StatusOK, status)
+			assert.Equal(t, tt.ids, raceIDs(resp.Races))
+		})
+	}
+}
+
+func TestListRacesPages(t *testing.T) {
+	h := Start(t, now, fixtures)
+
+	var ids []string
+	token := ""
+	for page := 0; page < 3; page++ {
+		var resp listRacesResponse
+		status := h.DoJSON(http.MethodPost, "/v1/list-races", `{"order_by":"name","page_size":3,"page_token":"`+token+`"}`, &resp)
+		require.Equal(t, http.StatusOK, status)
+
+		ids = append(ids, raceIDs(resp.Races)...)
+		if token = resp.NextPageToken; token == "" {
+			break
+		}
+	}
+
+	assert.Equal(t, []string{"4", "1", "2", "3"}, ids)
+}
+
+func TestListRacesInvalid(t *testing.T) {
+	h := Start(t, now, fixtures)
+
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{name: "OrderBy", body: `{"order_by":"venue"}`, field: "order_by"},
+		{name: "Expression", body: `{"filter":{"expression":"venue = 1"}}`, field: "filter.expression"},
+		{name: "PageToken", body: `{"page_size":1,"page_token":"bogus"}`, field: "page_token"},
+		{name: "Rule", body: `{"filter":{"meeting_ids":[0]}}`, field: "filter.meeting_ids[0]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp errorResponse
+			status := h.DoJSON(http.MethodPost, "/v1/list-races", tt.body, &resp)
+
+			assert.Equal(t, http.StatusBadRequest, status)
+			require.Len(t, resp.Details, 1)
+			require.NotEmpty(t, resp.Details[0].FieldViolations)
+			assert.Equal(t, tt.field, resp.Details[0].FieldViolations[0].Field)
+		})
+	}
+}
+
+func TestGetRace(t *testing.T) {
+	h := Start(t, now, fixtures)
+
+	var got race
+	status := h.DoJSON(http.MethodPost, "/v1/race/2", "", &got)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, race{
+		ID:                  "2",
+		MeetingID:           "1",
+		Name:                "Flemington Sprint",
+		Visible:             true,
+		AdvertisedStartTime: now.Add(30 * time.Minute),
+		Status:              "OPEN",
+	}, got)
+
+	var notFound errorResponse
+	status = h.DoJSON(http.MethodPost, "/v1/race/99", "", &notFound)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, 5, notFound.Code)
+}
+
+func TestRaceStatusFollowsClock(t *testing.T) {
+	h := Start(t, now, fixtures)
+
+	var got race
+	h.DoJSON(http.MethodPost, "/v1/race/2", "", &got)
+	assert.Equal(t, "OPEN", got.Status)
+
+	// once the race has started it is CLOSED
+	h.Clock.Advance(time.Hour)
+	h.DoJSON(http.MethodPost, "/v1/race/2", "", &got)
+	assert.Equal(t, "CLOSED", got.Status)
+
+	var resp listRacesResponse
+	h.DoJSON(http.MethodPost, "/v1/list-races", `{"filter":{"status":"OPEN"}}`, &resp)
+	assert.Equal(t, []string{"3", "4"}, raceIDs(resp.Races))
+}
+
+func TestListEvents(t *testing.T) {
+	h := Start(t, now, fixtures)
+
+	tests := []struct {
+		name string
+		body string
+		ids  []string
+	}{
+		{name: "All", body: `{}`, ids: []string{"1", "2", "3"}},
+		{name: "EventIDs", body: `{"filter":{"event_ids":[20]}}`, ids: []string{"3"}},
+		{name: "Open", body: `{"filter":{"status":"OPEN"}}`, ids: []string{"2", "3"}},
+		{name: "Expression", body: `{"filter":{"expression":"sports_type = \"Tennis\""}}`, ids: []string{"1", "2"}},
+		{name: "SportTypes", body: `{"filter":{"sport_types":["Football","Boxing"]}}`, ids: []string{"3"}},
+		{name: "OrderBy", body: `{"order_by":"sports_type, number desc"}`, ids: []string{"3", "2", "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp listEventsResponse
+			status := h.DoJSON(http.MethodPost, "/v1/list-events", tt.body, &resp)
+
+			require.Equal(t, http.StatusOK, status)
+			assert.Equal(t, tt.ids, eventIDs(resp.Events))
+		})
+	}
+}
+
+func TestListSportTypes(t *testing.T) {
+	h := Start(t, now, fixtures)
+
+	var resp struct {
+		SportTypes []struct {
+			Name     string `json:"name"`
+			Category string `json:"category"`
+			Icon     string `json:"icon"`
+			Active   bool   `json:"active"`
+		} `json:"sportTypes"`
+	}
+	status := h.DoJSON(http.MethodGet, "/v1/sport-types", "", &resp)
+	require.Equal(t, http.StatusOK, status)
+	require.NotEmpty(t, resp.SportTypes)
+	assert.Equal(t, "Basketball", resp.SportTypes[0].Name)
+	assert.Equal(t, "TRADITIONAL", resp.SportTypes[0].Category)
+	for _, sportType := range resp.SportTypes {
+		assert.True(t, sportType.Active, sportType.Name)
+	}
+
+	active := len(resp.SportTypes)
+	status = h.DoJSON(http.MethodGet, "/v1/sport-types?include_inactive=true", "", &resp)
+	require.Equal(t, http.StatusOK, status)
+	assert.Len(t, resp.SportTypes, active, "every sport type offered is active")
+}
+
+func TestGetEvent(t *testing.T) {
+	h := Start(t, now, fixtures)
+
+	var got event
+	status := h.DoJSON(http.MethodPost, "/v1/event/1", "", &got)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, event{ID: "1", EventID: "10", SportsType: "Tennis", Name: "Wimbledon Final", Status: "CLOSED"}, got)
+
+	status, _ = h.Do(http.MethodPost, "/v1/event/99", "")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestHandlerChain(t *testing.T) {
+	h := Start(t, now, fixtures)
+
+	req, err := http.NewRequest(http.MethodPost, h.URL+"/v1/race/1", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Request-ID", "e2e-request")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "e2e-request", resp.Header.Get("X-Request-ID"), "request ID echoed")
+	assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"), "security headers set")
+
+	status, _ := h.Do(http.MethodGet, "/readyz", "")
+	assert.Equal(t, http.StatusOK, status, "services reported ready")
+}
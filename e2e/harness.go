@@ -0,0 +1,275 @@
+// Package e2e runs the racing and sports services and the API gateway in one
+// process, so tests can make REST calls against the whole stack. The services
+// listen on in-memory connections and store their data in temporary SQLite
+// files; the gateway, with the handler chain the api serves, is served by an
+// httptest server.
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+
+	"git.neds.sh/matty/entain/api/config"
+	"git.neds.sh/matty/entain/api/gateway"
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/logging"
+	"git.neds.sh/matty/entain/common/server"
+	"git.neds.sh/matty/entain/common/sqlrepo/sqlrepotest"
+	// Lets the api's and the services' copies of the protos register side
+	// by side, see the package documentation.
+	_ "git.neds.sh/matty/entain/e2e/internal/protoconflict"
+	racingdb "git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	racingservice "git.neds.sh/matty/entain/racing/service"
+	sportsdb "git.neds.sh/matty/entain/sports/db"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+	sportsservice "git.neds.sh/matty/entain/sports/service"
+)
+
+// Race is a row of the racing service's races table.
+type Race struct {
+	ID              int64
+	MeetingID       int64
+	Name            string
+	Number          int64
+	Visible         bool
+	AdvertisedStart time.Time
+}
+
+// Event is a row of the sports service's sports table.
+type Event struct {
+	ID              int64
+	EventID         int64
+	SportsType      string
+	Name            string
+	Number          int64
+	AdvertisedStart time.Time
+}
+
+// Fixtures is the data the services are started with, replacing the rows
+// they seed.
+type Fixtures struct {
+	Races  []Race
+	Events []Event
+}
+
+// Harness is a running stack.
+type Harness struct {
+	// URL is the base URL of the gateway.
+	URL string
+
+	// Clock is the clock both services tell the time by.
+	Clock *clock.Fake
+
+	t testing.TB
+}
+
+// Start starts the services with fixtures and the gateway in front of them,
+// all telling the time by a fake clock set to now. Everything is stopped
+// once the test ends.
+func Start(t testing.TB, now time.Time, fixtures Fixtures) *Harness {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Harness{Clock: clock.NewFake(now), t: t}
+
+	racingDB := sqlrepotest.OpenSQLite(t)
+	racesRepo := racingdb.NewRacesRepo(racingDB, racingdb.WithClock(h.Clock))
+	if err := racesRepo.Init(); err != nil {
+		t.Fatalf("initialising races: %v", err)
+	}
+	replaceRows(t, racingDB, "races", len(fixtures.Races), func(i int) (string, []interface{}) {
+		r := fixtures.Races[i]
+		return `INSERT INTO races(id, meeting_id, name, number, visible, advertised_start_time) VALUES (?,?,?,?,?,?)`,
+			[]interface{}{r.ID, r.MeetingID, r.Name, r.Number, r.Visible, r.AdvertisedStart.Format(time.RFC3339)}
+	})
+
+	sportsDB := sqlrepotest.OpenSQLite(t)
+	sportsRepo := sportsdb.NewSportsRepo(sportsDB, sportsdb.WithClock(h.Clock))
+	if err := sportsRepo.Init(); err != nil {
+		t.Fatalf("initialising events: %v", err)
+	}
+	replaceRows(t, sportsDB, "sports", len(fixtures.Events), func(i int) (string, []interface{}) {
+		e := fixtures.Events[i]
+		return `INSERT INTO sports(id, event_id, sports_type, name, number, advertised_start_time) VALUES (?,?,?,?,?,?)`,
+			[]interface{}{e.ID, e.EventID, e.SportsType, e.Name, e.Number, e.AdvertisedStart.Format(time.RFC3339)}
+	})
+
+	racingConn := h.serve(ctx, server.Service{
+		Name: "racing.Racing",
+		DB:   racingDB,
+		Register: func(s *grpc.Server) {
+			racing.RegisterRacingServer(s, racingservice.NewRacingService(racesRepo, h.Clock))
+		},
+	})
+	sportsConn := h.serve(ctx, server.Service{
+		Name: "sports.Sports",
+		DB:   sportsDB,
+		Register: func(s *grpc.Server) {
+			sports.RegisterSportsServer(s, sportsservice.NewSportsService(sportsRepo, h.Clock))
+		},
+	})
+
+	handler, err := gateway.NewHandler(ctx, config.Default(), h.logger(), racingConn, sportsConn)
+	if err != nil {
+		t.Fatalf("building gateway: %v", err)
+	}
+
+	api := httptest.NewServer(handler)
+	h.URL = api.URL
+
+	// cleanups run last in, first out: the gateway stops before the services
+	t.Cleanup(cancel)
+	t.Cleanup(api.Close)
+
+	return h
+}
+
+// serve serves svc over an in-memory connection until ctx is done, returning
+// a client connection to it once it reports SERVING.
+func (h *Harness) serve(ctx context.Context, svc server.Service) *grpc.ClientConn {
+	h.t.Helper()
+
+	logger := h.logger()
+
+	cfg := server.DefaultConfig("bufconn:0", "")
+	cfg.HealthCheckInterval = time.Minute
+	cfg.ShutdownTimeout = time.Second
+
+	lis := bufconn.Listen(1 << 20)
+	var (
+		serveErr error
+		started  bool
+	)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		serveErr = server.Serve(ctx, lis, &cfg, logger, svc)
+	}()
+	h.t.Cleanup(func() {
+		<-stopped
+		// failing to start has been reported already
+		if started && serveErr != nil {
+			h.t.Errorf("serving %s: %v", svc.Name, serveErr)
+		}
+	})
+
+	conn, err := grpc.DialContext(ctx, svc.Name,
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	)
+	if err != nil {
+		h.t.Fatalf("dialing %s: %v", svc.Name, err)
+	}
+	h.t.Cleanup(func() { conn.Close() })
+
+	health := healthpb.NewHealthClient(conn)
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		select {
+		case <-stopped:
+			h.t.Fatalf("%s stopped while starting: %v", svc.Name, serveErr)
+		default:
+		}
+
+		// each check is bounded, as one waiting for a server that never
+		// comes up would otherwise wait for good
+		checkCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		resp, err := health.Check(checkCtx, &healthpb.HealthCheckRequest{Service: svc.Name}, grpc.WaitForReady(true))
+		cancel()
+		if err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING {
+			started = true
+			return conn
+		}
+		if time.Now().After(deadline) {
+			h.t.Fatalf("%s not serving: %v", svc.Name, err)
+		}
+	}
+}
+
+// logger returns a logger discarding everything, to keep test output clean.
+func (h *Harness) logger() *logrus.Logger {
+	h.t.Helper()
+
+	logger, err := logging.New("error", "text")
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	logger.SetOutput(ioutil.Discard)
+
+	return logger
+}
+
+// replaceRows empties table, then inserts n rows built by row.
+func replaceRows(t testing.TB, db *sql.DB, table string, n int, row func(i int) (string, []interface{})) {
+	t.Helper()
+
+	if _, err := db.Exec(`DELETE FROM ` + table); err != nil {
+		t.Fatalf("emptying %s: %v", table, err)
+	}
+	for i := 0; i < n; i++ {
+		query, args := row(i)
+		if _, err := db.Exec(query, args...); err != nil {
+			t.Fatalf("inserting into %s: %v", table, err)
+		}
+	}
+}
+
+// Do sends a request with the given JSON body, if any, to the gateway and
+// returns the response status and body.
+func (h *Harness) Do(method, path, body string) (int, []byte) {
+	h.t.Helper()
+
+	var reader io.Reader
+	if body != "" {
+		reader = bytes.NewBufferString(body)
+	}
+
+	req, err := http.NewRequest(method, h.URL+path, reader)
+	if err != nil {
+		h.t.Fatalf("building request: %v", err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		h.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		h.t.Fatalf("%s %s: reading body: %v", method, path, err)
+	}
+
+	return resp.StatusCode, data
+}
+
+// DoJSON is Do, decoding the response body into out.
+func (h *Harness) DoJSON(method, path, body string, out interface{}) int {
+	h.t.Helper()
+
+	status, data := h.Do(method, path, body)
+	if err := json.Unmarshal(data, out); err != nil {
+		h.t.Fatalf("%s %s: decoding %s: %v", method, path, data, err)
+	}
+
+	return status
+}
@@ -0,0 +1,22 @@
+// Package protoconflict lets the api's copies of the racing and sports protos
+// be linked into the same binary as the services' own. Both register under the
+// same file and message names, which the protobuf runtime refuses unless told
+// otherwise through GOLANG_PROTOBUF_REGISTRATION_CONFLICT.
+//
+// The runtime reads the variable as the second copy registers, during package
+// initialisation, so it must be set before then: TestMain runs too late. Since
+// Go 1.21 packages are initialised in import path order, each as soon as the
+// packages it imports are, so where this package is imported does not matter:
+// importing nothing but os, and sorting ahead of google.golang.org/protobuf,
+// it is always initialised before the protobuf runtime, let alone any proto.
+package protoconflict
+
+import "os"
+
+const env = "GOLANG_PROTOBUF_REGISTRATION_CONFLICT"
+
+func init() {
+	if _, ok := os.LookupEnv(env); !ok {
+		os.Setenv(env, "ignore")
+	}
+}
@@ -0,0 +1,180 @@
+// Package client calls the racing and sports services for entainctl, over
+// gRPC or through the API gateway's REST routes. Either way errors are gRPC
+// statuses, so commands handle them alike.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+// Client calls the racing and sports services.
+type Client interface {
+	ListRaces(ctx context.Context, in *racing.ListRacesRequest) (*racing.ListRacesResponse, error)
+	GetRace(ctx context.Context, in *racing.GetRaceRequest) (*racing.Race, error)
+	GetRacingClock(ctx context.Context) (*racing.Clock, error)
+
+	ListEvents(ctx context.Context, in *sports.ListEventsRequest) (*sports.ListEventsResponse, error)
+	GetEvent(ctx context.Context, in *sports.GetEventRequest) (*sports.Event, error)
+	GetSportsClock(ctx context.Context) (*sports.Clock, error)
+	ListSportTypes(ctx context.Context, in *sports.ListSportTypesRequest) (*sports.ListSportTypesResponse, error)
+}
+
+// grpcClient calls the services over gRPC.
+type grpcClient struct {
+	racing racing.RacingClient
+	sports sports.SportsClient
+}
+
+// NewGRPC returns a client calling the services over racingConn and
+// sportsConn.
+func NewGRPC(racingConn, sportsConn grpc.ClientConnInterface) Client {
+	return &grpcClient{racing: racing.NewRacingClient(racingConn), sports: sports.NewSportsClient(sportsConn)}
+}
+
+func (c *grpcClient) ListRaces(ctx context.Context, in *racing.ListRacesRequest) (*racing.ListRacesResponse, error) {
+	return c.racing.ListRaces(ctx, in)
+}
+
+func (c *grpcClient) GetRace(ctx context.Context, in *racing.GetRaceRequest) (*racing.Race, error) {
+	return c.racing.GetRace(ctx, in)
+}
+
+func (c *grpcClient) GetRacingClock(ctx context.Context) (*racing.Clock, error) {
+	return c.racing.GetClock(ctx, &racing.GetClockRequest{})
+}
+
+func (c *grpcClient) ListEvents(ctx context.Context, in *sports.ListEventsRequest) (*sports.ListEventsResponse, error) {
+	return c.sports.ListEvents(ctx, in)
+}
+
+func (c *grpcClient) GetEvent(ctx context.Context, in *sports.GetEventRequest) (*sports.Event, error) {
+	return c.sports.GetEvent(ctx, in)
+}
+
+func (c *grpcClient) GetSportsClock(ctx context.Context) (*sports.Clock, error) {
+	return c.sports.GetClock(ctx, &sports.GetClockRequest{})
+}
+
+func (c *grpcClient) ListSportTypes(ctx context.Context, in *sports.ListSportTypesRequest) (*sports.ListSportTypesResponse, error) {
+	return c.sports.ListSportTypes(ctx, in)
+}
+
+// restClient calls the API gateway.
+type restClient struct {
+	baseURL string
+	client  *http.Client
+	header  http.Header
+}
+
+// NewREST returns a client calling the gateway at baseURL, e.g.
+// "http://localhost:8000", with client and adding header, e.g. the
+// credentials, to every request.
+func NewREST(baseURL string, client *http.Client, header http.Header) Client {
+	return &restClient{baseURL: strings.TrimSuffix(baseURL, "/"), client: client, header: header}
+}
+
+func (c *restClient) ListRaces(ctx context.Context, in *racing.ListRacesRequest) (*racing.ListRacesResponse, error) {
+	out := &racing.ListRacesResponse{}
+	return out, c.do(ctx, http.MethodPost, "/v1/list-races", in, out)
+}
+
+func (c *restClient) GetRace(ctx context.Context, in *racing.GetRaceRequest) (*racing.Race, error) {
+	out := &racing.Race{}
+	return out, c.do(ctx, http.MethodPost, fmt.Sprintf("/v1/race/%d", in.Id), nil, out)
+}
+
+func (c *restClient) GetRacingClock(ctx context.Context) (*racing.Clock, error) {
+	out := &racing.Clock{}
+	return out, c.do(ctx, http.MethodGet, "/v1/racing/clock", nil, out)
+}
+
+func (c *restClient) ListEvents(ctx context.Context, in *sports.ListEventsRequest) (*sports.ListEventsResponse, error) {
+	out := &sports.ListEventsResponse{}
+	return out, c.do(ctx, http.MethodPost, "/v1/list-events", in, out)
+}
+
+func (c *restClient) GetEvent(ctx context.Context, in *sports.GetEventRequest) (*sports.Event, error) {
+	out := &sports.Event{}
+	return out, c.do(ctx, http.MethodPost, fmt.Sprintf("/v1/event/%d", in.Id), nil, out)
+}
+
+func (c *restClient) GetSportsClock(ctx context.Context) (*sports.Clock, error) {
+	out := &sports.Clock{}
+	return out, c.do(ctx, http.MethodGet, "/v1/sports/clock", nil, out)
+}
+
+func (c *restClient) ListSportTypes(ctx context.Context, in *sports.ListSportTypesRequest) (*sports.ListSportTypesResponse, error) {
+	out := &sports.ListSportTypesResponse{}
+	path := "/v1/sport-types"
+	if in.IncludeInactive {
+		path += "?include_inactive=true"
+	}
+	return out, c.do(ctx, http.MethodGet, path, nil, out)
+}
+
+// gatewayError is the body of the gateway's error responses.
+type gatewayError struct {
+	Code    codes.Code `json:"code"`
+	Message string     `json:"message"`
+}
+
+// do sends in, if any, as JSON to path and decodes the response into out.
+// Error responses are turned back into the gRPC status they were made from.
+func (c *restClient) do(ctx context.Context, method, path string, in, out proto.Message) error {
+	var body io.Reader
+	if in != nil {
+		b, err := protojson.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	for name, values := range c.header {
+		req.Header[name] = values
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		var e gatewayError
+		if json.Unmarshal(b, &e) != nil || e.Code == codes.OK {
+			return status.Errorf(codes.Unknown, "%s %s: HTTP %d %s", method, path, res.StatusCode, bytes.TrimSpace(b))
+		}
+		return status.Error(e.Code, e.Message)
+	}
+
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(b, out)
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+var now = time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+
+func TestREST(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		switch r.URL.Path {
+		case "/v1/list-races":
+			w.Write([]byte(`{"races": [{"id": "1", "name": "Flemington Cup", "unknown": true}], "nextPageToken": "bzE6MQ"}`))
+		case "/v1/sport-types":
+			assert.Equal(t, "true", r.URL.Query().Get("include_inactive"))
+			w.Write([]byte(`{"sportTypes": [{"id": "100", "name": "Polo", "category": "TRADITIONAL", "active": false}]}`))
+		case "/v1/sports/clock":
+			w.Write([]byte(`{"now": "2026-10-18T12:00:00Z", "simulated": true}`))
+		case "/v1/event/3":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code": 5, "message": "event 3: not found", "details": []}`))
+		case "/v1/race/4":
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("upstream gone\n"))
+		}
+	}))
+	defer srv.Close()
+
+	c := NewREST(srv.URL, srv.Client(), http.Header{"Authorization": {"Bearer token"}})
+
+	races, err := c.ListRaces(context.Background(), &racing.ListRacesRequest{Filter: &racing.ListRacesRequestFilter{Visibility: racing.ListRacesRequestFilter_VISIBILE}})
+	require.NoError(t, err)
+	require.Len(t, races.Races, 1)
+	assert.Equal(t, "Flemington Cup", races.Races[0].Name)
+	assert.Equal(t, "bzE6MQ", races.NextPageToken)
+
+	clock, err := c.GetSportsClock(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, now, clock.Now.AsTime())
+	assert.True(t, clock.Simulated)
+
+	sportTypes, err := c.ListSportTypes(context.Background(), &sports.ListSportTypesRequest{IncludeInactive: true})
+	require.NoError(t, err)
+	require.Len(t, sportTypes.SportTypes, 1)
+	assert.Equal(t, sports.SportType_TRADITIONAL, sportTypes.SportTypes[0].Category)
+	assert.False(t, sportTypes.SportTypes[0].Active)
+
+	_, err = c.GetEvent(context.Background(), &sports.GetEventRequest{Id: 3})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, "event 3: not found", status.Convert(err).Message())
+
+	_, err = c.GetRace(context.Background(), &racing.GetRaceRequest{Id: 4})
+	assert.Equal(t, codes.Unknown, status.Code(err))
+	assert.Equal(t, "POST /v1/race/4: HTTP 502 upstream gone", status.Convert(err).Message())
+
+	assert.Equal(t, []string{
+		`POST /v1/list-races {"filter":{"visibility":"VISIBILE"}}`,
+		"GET /v1/sports/clock ",
+		"GET /v1/sport-types ",
+		"POST /v1/event/3 ",
+		"POST /v1/race/4 ",
+	}, requests)
+
+	srv.Close()
+	_, err = c.GetRacingClock(context.Background())
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+type fakeRacing struct {
+	racing.UnimplementedRacingServer
+}
+
+func (s *fakeRacing) GetRace(ctx context.Context, in *racing.GetRaceRequest) (*racing.Race, error) {
+	return &racing.Race{Id: int64(in.Id), Name: "Randwick Plate"}, nil
+}
+
+type fakeSports struct {
+	sports.UnimplementedSportsServer
+}
+
+func (s *fakeSports) GetClock(ctx context.Context, in *sports.GetClockRequest) (*sports.Clock, error) {
+	return &sports.Clock{Now: timestamppb.New(now)}, nil
+}
+
+func TestGRPC(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	racing.RegisterRacingServer(srv, &fakeRacing{})
+	sports.RegisterSportsServer(srv, &fakeSports{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	c := NewGRPC(conn, conn)
+
+	race, err := c.GetRace(context.Background(), &racing.GetRaceRequest{Id: 7})
+	require.NoError(t, err)
+	assert.Equal(t, "Randwick Plate", race.Name)
+
+	clock, err := c.GetSportsClock(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, now, clock.Now.AsTime())
+
+	_, err = c.ListEvents(context.Background(), &sports.ListEventsRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
@@ -0,0 +1,125 @@
+// Package config holds the settings every entainctl command shares.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"git.neds.sh/matty/entain/common/configload"
+)
+
+// EnvPrefix prefixes the environment variable of every setting, e.g.
+// ENTAINCTL_API_URL for --api-url.
+const EnvPrefix = "ENTAINCTL"
+
+// The transports entainctl talks to the services over.
+const (
+	TransportREST = "rest"
+	TransportGRPC = "grpc"
+)
+
+// Config is the effective configuration of a command.
+type Config struct {
+	Transport          string        `yaml:"transport" toml:"transport"`
+	APIURL             string        `yaml:"api_url" toml:"api_url"`
+	APIKey             string        `yaml:"api_key" toml:"api_key"`
+	BearerToken        string        `yaml:"bearer_token" toml:"bearer_token"`
+	GRPCEndpointRacing string        `yaml:"grpc_endpoint_racing" toml:"grpc_endpoint_racing"`
+	GRPCEndpointSports string        `yaml:"grpc_endpoint_sports" toml:"grpc_endpoint_sports"`
+	Output             string        `yaml:"output" toml:"output"`
+	Timeout            time.Duration `yaml:"timeout" toml:"timeout"`
+}
+
+// Default returns the configuration used when nothing else is specified.
+func Default() *Config {
+	return &Config{
+		Transport:          TransportREST,
+		APIURL:             "http://localhost:8000",
+		GRPCEndpointRacing: "localhost:9000",
+		GRPCEndpointSports: "localhost:9001",
+		Output:             "table",
+		Timeout:            10 * time.Second,
+	}
+}
+
+// Load builds the configuration of the command named name from, in
+// increasing order of precedence: the defaults, the config file given by
+// --config (or ENTAINCTL_CONFIG), ENTAINCTL_* environment variables and
+// command line flags. bind registers the command's own flags, which are
+// parsed along with the shared ones. Flags may follow the other arguments,
+// e.g. "12 -o json", which are returned.
+func Load(name string, args []string, lookupEnv func(string) (string, bool), bind func(*flag.FlagSet)) (*Config, []string, error) {
+	c := Default()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	err := configload.Load(c, fs, EnvPrefix, args, lookupEnv, func(fs *flag.FlagSet) {
+		c.bind(fs)
+		bind(fs)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rest []string
+	for fs.NArg() > 0 {
+		rest = append(rest, fs.Arg(0))
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	return c, rest, nil
+}
+
+// bind registers a flag for every setting, defaulting to its current value.
+func (c *Config) bind(fs *flag.FlagSet) {
+	fs.StringVar(&c.Transport, "transport", c.Transport, "How to reach the services: rest, through the API gateway, or grpc, straight to them")
+	fs.StringVar(&c.APIURL, "api-url", c.APIURL, "Base URL of the API gateway")
+	fs.StringVar(&c.APIKey, "api-key", c.APIKey, "API key to authenticate to the gateway with")
+	fs.StringVar(&c.BearerToken, "bearer-token", c.BearerToken, "JWT to authenticate to the gateway with")
+	fs.StringVar(&c.GRPCEndpointRacing, "grpc-endpoint-racing", c.GRPCEndpointRacing, "Racing service endpoint, for --transport grpc")
+	fs.StringVar(&c.GRPCEndpointSports, "grpc-endpoint-sports", c.GRPCEndpointSports, "Sports service endpoint, for --transport grpc")
+	fs.StringVar(&c.Output, "output", c.Output, "Output format: table, json or yaml")
+	fs.StringVar(&c.Output, "o", c.Output, "Shorthand for --output")
+	fs.DurationVar(&c.Timeout, "timeout", c.Timeout, "Deadline of every call")
+}
+
+// Validate checks the configuration is usable.
+func (c *Config) Validate() error {
+	var errs []string
+
+	switch c.Transport {
+	case TransportREST:
+		if c.APIURL == "" {
+			errs = append(errs, "api_url: required with transport rest")
+		}
+	case TransportGRPC:
+		if c.GRPCEndpointRacing == "" || c.GRPCEndpointSports == "" {
+			errs = append(errs, "grpc_endpoint_racing, grpc_endpoint_sports: required with transport grpc")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("transport: must be rest or grpc, got %q", c.Transport))
+	}
+
+	switch c.Output {
+	case "table", "json", "yaml":
+	default:
+		errs = append(errs, fmt.Sprintf("output: must be table, json or yaml, got %q", c.Output))
+	}
+
+	if c.Timeout <= 0 {
+		errs = append(errs, "timeout: must be positive")
+	}
+
+	if len(errs) > 0 {
+		return errors.New(configload.FormatErrors(errs))
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	env := map[string]string{"ENTAINCTL_TRANSPORT": "grpc", "ENTAINCTL_TIMEOUT": "3s"}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	var limit int
+	cfg, args, err := Load("races get", []string{"--limit", "5", "12", "-o", "yaml", "13"}, lookupEnv, func(fs *flag.FlagSet) {
+		fs.IntVar(&limit, "limit", 20, "")
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, TransportGRPC, cfg.Transport)
+	assert.Equal(t, 3*time.Second, cfg.Timeout)
+	assert.Equal(t, "yaml", cfg.Output, "flags may follow arguments")
+	assert.Equal(t, "http://localhost:8000", cfg.APIURL)
+	assert.Equal(t, 5, limit)
+	assert.Equal(t, []string{"12", "13"}, args)
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	require.NoError(t, cfg.Validate())
+
+	cfg.Transport = "carrier-pigeon"
+	cfg.Output = "xml"
+	cfg.Timeout = 0
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `transport: must be rest or grpc, got "carrier-pigeon"`)
+	assert.Contains(t, err.Error(), `output: must be table, json or yaml, got "xml"`)
+	assert.Contains(t, err.Error(), "timeout: must be positive")
+
+	cfg = Default()
+	cfg.Transport = TransportGRPC
+	cfg.GRPCEndpointSports = ""
+	assert.Error(t, cfg.Validate())
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"git.neds.sh/matty/entain/entainctl/output"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+// eventFilterFlags are the flags events are filtered with.
+type eventFilterFlags struct {
+	events       idsFlag
+	sports       listFlag
+	status       string
+	startsWithin time.Duration
+	from, to     timeFlag
+	expression   string
+}
+
+func (f *eventFilterFlags) bind(fs *flag.FlagSet) {
+	fs.Var(&f.events, "event", "Only events of these event IDs, comma separated or repeated")
+	fs.Var(&f.sports, "sport", "Only events of these sport types, e.g. Tennis, comma separated or repeated")
+	fs.StringVar(&f.status, "status", "", "Only open or closed events")
+	fs.DurationVar(&f.startsWithin, "starts-within", 0, "Only events starting within this long from now, e.g. 1h")
+	fs.Var(&f.from, "from", "Only events starting at or after this RFC 3339 time")
+	fs.Var(&f.to, "to", "Only events starting before this RFC 3339 time")
+	fs.StringVar(&f.expression, "filter", "", `Filter expression, e.g. 'number <= 4 AND name != "Grand Final"'`)
+}
+
+func (f *eventFilterFlags) filter() (*sports.ListEventsRequestFilter, error) {
+	filter := &sports.ListEventsRequestFilter{
+		EventIds:      f.events,
+		SportTypes:    f.sports,
+		StartTimeFrom: f.from.t,
+		StartTimeTo:   f.to.t,
+		Expression:    f.expression,
+	}
+
+	if f.status != "" {
+		status, ok := sports.ListEventsRequestFilter_EventStatus_value[strings.ToUpper(f.status)]
+		if !ok || status == 0 {
+			return nil, fmt.Errorf("--status must be open or closed, got %q", f.status)
+		}
+		filter.Status = sports.ListEventsRequestFilter_EventStatus(status)
+	}
+
+	if f.startsWithin != 0 {
+		filter.StartsWithin = durationpb.New(f.startsWithin)
+	}
+
+	return filter, nil
+}
+
+func listEvents(ctx context.Context, a *app, args []string) error {
+	var (
+		filter    eventFilterFlags
+		order     string
+		limit     int
+		pageToken string
+		all       bool
+	)
+	s, err := a.start("events list", args, func(fs *flag.FlagSet) {
+		filter.bind(fs)
+		fs.StringVar(&order, "sort", "", `Order of the events, e.g. "sports_type, advertised_start_time"`)
+		fs.IntVar(&limit, "limit", 20, "Events per page; 0 lists them all at once")
+		fs.StringVar(&pageToken, "page-token", "", "Page to list, as handed out by the previous one")
+		fs.BoolVar(&all, "all", false, "List every page")
+	})
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	req := &sports.ListEventsRequest{OrderBy: order, PageSize: int32(limit), PageToken: pageToken}
+	if req.Filter, err = filter.filter(); err != nil {
+		return err
+	}
+
+	resp := &sports.ListEventsResponse{}
+	for {
+		callCtx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+		page, err := s.client.ListEvents(callCtx, req)
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		resp.Events = append(resp.Events, page.Events...)
+		resp.NextPageToken = page.NextPageToken
+		if !all || page.NextPageToken == "" {
+			break
+		}
+		req.PageToken = page.NextPageToken
+	}
+
+	if err := s.printer.Events(resp); err != nil {
+		return err
+	}
+	if resp.NextPageToken != "" && s.cfg.Output == "table" {
+		fmt.Fprintf(a.stderr, "more events: --page-token %s\n", resp.NextPageToken)
+	}
+
+	return nil
+}
+
+func getEvent(ctx context.Context, a *app, args []string) error {
+	s, err := a.start("events get", args, func(*flag.FlagSet) {})
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	id, err := parseID(s.args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	event, err := s.client.GetEvent(ctx, &sports.GetEventRequest{Id: id})
+	if err != nil {
+		return err
+	}
+
+	return s.printer.Event(event)
+}
+
+func watchEvents(ctx context.Context, a *app, args []string) error {
+	var (
+		events  idsFlag
+		types   listFlag
+		limit   int
+		refresh time.Duration
+	)
+	s, err := a.start("events watch", args, func(fs *flag.FlagSet) {
+		fs.Var(&events, "event", "Only events of these event IDs, comma separated or repeated")
+		fs.Var(&types, "sport", "Only events of these sport types, comma separated or repeated")
+		fs.IntVar(&limit, "limit", 10, "How many events to show")
+		fs.DurationVar(&refresh, "refresh", 5*time.Second, "How often the events are fetched again")
+	})
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	req := &sports.ListEventsRequest{
+		Filter: &sports.ListEventsRequestFilter{
+			EventIds:   events,
+			Status:     sports.ListEventsRequestFilter_OPEN,
+			SportTypes: types,
+		},
+		OrderBy:  "advertised_start_time",
+		PageSize: int32(limit),
+	}
+
+	var list []*sports.Event
+	w := &watcher{
+		app:     a,
+		title:   "Next to start",
+		refresh: refresh,
+		timeout: s.cfg.Timeout,
+		fetch: func(ctx context.Context) (time.Time, error) {
+			clock, err := s.client.GetSportsClock(ctx)
+			if err != nil {
+				return time.Time{}, err
+			}
+			resp, err := s.client.ListEvents(ctx, req)
+			if err != nil {
+				return time.Time{}, err
+			}
+
+			list = resp.Events
+			return clock.Now.AsTime(), nil
+		},
+		render: func(w io.Writer, now time.Time) error {
+			return output.NewPrinter(w, output.Table, a.loc).EventCountdown(list, now)
+		},
+	}
+
+	return w.run(ctx, s.cfg.Output)
+}
+
+func listSportTypes(ctx context.Context, a *app, args []string) error {
+	var all bool
+	s, err := a.start("sport-types list", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&all, "all", false, "Include the sport types no longer offered")
+	})
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	resp, err := s.client.ListSportTypes(ctx, &sports.ListSportTypesRequest{IncludeInactive: all})
+	if err != nil {
+		return err
+	}
+
+	return s.printer.SportTypes(resp)
+}
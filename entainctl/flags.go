@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// idsFlag collects IDs given comma separated, repeatedly or both, e.g.
+// --meeting 1,2 --meeting 3.
+type idsFlag []int64
+
+func (f *idsFlag) String() string {
+	ids := make([]string, len(*f))
+	for i, id := range *f {
+		ids[i] = strconv.FormatInt(id, 10)
+	}
+
+	return strings.Join(ids, ",")
+}
+
+func (f *idsFlag) Set(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("%q is not an ID", part)
+		}
+		*f = append(*f, id)
+	}
+
+	return nil
+}
+
+// listFlag collects values given comma separated, repeatedly or both.
+type listFlag []string
+
+func (f *listFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *listFlag) Set(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*f = append(*f, part)
+		}
+	}
+
+	return nil
+}
+
+// timeFlag is an RFC 3339 time, unset by default.
+type timeFlag struct {
+	t *timestamppb.Timestamp
+}
+
+func (f *timeFlag) String() string {
+	if f.t == nil {
+		return ""
+	}
+
+	return f.t.AsTime().Format(time.RFC3339)
+}
+
+func (f *timeFlag) Set(s string) error {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return fmt.Errorf("want an RFC 3339 time, e.g. 2026-10-18T14:00:00+11:00")
+	}
+	f.t = timestamppb.New(t)
+
+	return nil
+}
+
+// parseID parses the ID argument of a get command.
+func parseID(args []string) (int32, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("want exactly one ID, got %d arguments", len(args))
+	}
+
+	id, err := strconv.ParseInt(args[0], 10, 32)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%q is not an ID", args[0])
+	}
+
+	return int32(id), nil
+}
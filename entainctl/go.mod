@@ -0,0 +1,30 @@
+module git.neds.sh/matty/entain/entainctl
+
+go 1.21
+
+require (
+	git.neds.sh/matty/entain/common v0.0.0-00010101000000-000000000000
+	git.neds.sh/matty/entain/racing v0.0.0-00010101000000-000000000000
+	git.neds.sh/matty/entain/sports v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.7.0
+	google.golang.org/grpc v1.36.0
+	google.golang.org/protobuf v1.25.1-0.20201208041424-160c7477e0e8
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/BurntSushi/toml v1.3.2 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/golang/protobuf v1.4.3 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/net v0.0.0-20210226172049-e18ecbb05110 // indirect
+	golang.org/x/sys v0.0.0-20210124154548-22da62e12c0c // indirect
+	golang.org/x/text v0.3.5 // indirect
+	google.golang.org/genproto v0.0.0-20210226172003-ab064af71705 // indirect
+)
+
+replace (
+	git.neds.sh/matty/entain/common => ../common
+	git.neds.sh/matty/entain/racing => ../racing
+	git.neds.sh/matty/entain/sports => ../sports
+)
@@ -0,0 +1,200 @@
+// Command entainctl lists and gets races and sports events from the command
+// line, through the API gateway or straight from the services over gRPC:
+//
+//	entainctl races list --meeting 3 --visible --sort advertised_start_time
+//	entainctl races get 12
+//	entainctl events list --sport Tennis -o yaml
+//	entainctl races watch
+//
+// Run any command with -h for its flags.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"git.neds.sh/matty/entain/entainctl/client"
+	"git.neds.sh/matty/entain/entainctl/config"
+	"git.neds.sh/matty/entain/entainctl/output"
+)
+
+// commands lists the verbs of every resource.
+var commands = map[string]map[string]func(ctx context.Context, a *app, args []string) error{
+	"races": {
+		"list":  listRaces,
+		"get":   getRace,
+		"watch": watchRaces,
+	},
+	"events": {
+		"list":  listEvents,
+		"get":   getEvent,
+		"watch": watchEvents,
+	},
+	"sport-types": {
+		"list": listSportTypes,
+	},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	a := &app{
+		stdout:    os.Stdout,
+		stderr:    os.Stderr,
+		lookupEnv: os.LookupEnv,
+		dial:      dial,
+		loc:       time.Local,
+		now:       time.Now,
+	}
+
+	err := a.run(ctx, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if s, ok := status.FromError(err); ok && err != nil {
+		err = fmt.Errorf("%s: %s", s.Code(), s.Message())
+	}
+	if err != nil {
+		log.SetFlags(0)
+		log.Fatalf("entainctl: %s", err)
+	}
+}
+
+// app is what commands run with.
+type app struct {
+	stdout, stderr io.Writer
+	lookupEnv      func(string) (string, bool)
+
+	// dial returns the client the configuration asks for, and a function
+	// closing it.
+	dial func(cfg *config.Config) (client.Client, func() error, error)
+
+	// loc is where times are shown, and now tells the local time.
+	loc *time.Location
+	now func() time.Time
+}
+
+// run runs the command named by args, e.g. "races list --meeting 3".
+func (a *app) run(ctx context.Context, args []string) error {
+	if len(args) < 2 {
+		a.usage()
+		if len(args) == 1 && isHelp(args[0]) {
+			return flag.ErrHelp
+		}
+		return errors.New("missing command")
+	}
+
+	verbs, ok := commands[args[0]]
+	if !ok {
+		a.usage()
+		return fmt.Errorf("unknown resource %q", args[0])
+	}
+	cmd, ok := verbs[args[1]]
+	if !ok {
+		a.usage()
+		return fmt.Errorf("unknown command %q for %s", args[1], args[0])
+	}
+
+	return cmd(ctx, a, args[2:])
+}
+
+func (a *app) usage() {
+	var lines []string
+	for resource, verbs := range commands {
+		for verb := range verbs {
+			line := fmt.Sprintf("  entainctl %s %s [flags]", resource, verb)
+			if verb == "get" {
+				line += " ID"
+			}
+			lines = append(lines, line)
+		}
+	}
+	sort.Strings(lines)
+
+	fmt.Fprintf(a.stderr, "usage:\n%s\n", strings.Join(lines, "\n"))
+}
+
+// session is a command ready to call the services.
+type session struct {
+	cfg     *config.Config
+	args    []string
+	client  client.Client
+	printer *output.Printer
+	close   func() error
+}
+
+// start loads the configuration of the command named name from args, with
+// the command's own flags registered by bind, and connects to the services.
+func (a *app) start(name string, args []string, bind func(*flag.FlagSet)) (*session, error) {
+	cfg, rest, err := config.Load("entainctl "+name, args, a.lookupEnv, bind)
+	if err != nil {
+		return nil, err
+	}
+
+	c, closeClient, err := a.dial(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session{
+		cfg:     cfg,
+		args:    rest,
+		client:  c,
+		printer: output.NewPrinter(a.stdout, output.Format(cfg.Output), a.loc),
+		close:   closeClient,
+	}, nil
+}
+
+// dial connects to the services as cfg asks.
+func dial(cfg *config.Config) (client.Client, func() error, error) {
+	if cfg.Transport == config.TransportREST {
+		header := make(http.Header)
+		if cfg.APIKey != "" {
+			header.Set("X-API-Key", cfg.APIKey)
+		}
+		if cfg.BearerToken != "" {
+			header.Set("Authorization", "Bearer "+cfg.BearerToken)
+		}
+
+		return client.NewREST(cfg.APIURL, http.DefaultClient, header), func() error { return nil }, nil
+	}
+
+	racingConn, err := grpc.Dial(cfg.GRPCEndpointRacing, grpc.WithInsecure())
+	if err != nil {
+		return nil, nil, err
+	}
+	sportsConn, err := grpc.Dial(cfg.GRPCEndpointSports, grpc.WithInsecure())
+	if err != nil {
+		racingConn.Close()
+		return nil, nil, err
+	}
+
+	return client.NewGRPC(racingConn, sportsConn), func() error {
+		racingConn.Close()
+		return sportsConn.Close()
+	}, nil
+}
+
+func isHelp(arg string) bool {
+	switch arg {
+	case "-h", "-help", "--help", "help":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/entainctl/client"
+	"git.neds.sh/matty/entain/entainctl/config"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+var now = time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+
+// fakeClient serves two pages of two races and two events, records the list
+// requests and tells the time as an hour ahead of now.
+type fakeClient struct {
+	raceRequests  []*racing.ListRacesRequest
+	eventRequests []*sports.ListEventsRequest
+}
+
+func (c *fakeClient) ListRaces(ctx context.Context, in *racing.ListRacesRequest) (*racing.ListRacesResponse, error) {
+	c.raceRequests = append(c.raceRequests, proto.Clone(in).(*racing.ListRacesRequest))
+	if in.PageToken == "" {
+		return &racing.ListRacesResponse{Races: []*racing.Race{race(1), race(2)}, NextPageToken: "page2"}, nil
+	}
+
+	return &racing.ListRacesResponse{Races: []*racing.Race{race(3)}}, nil
+}
+
+func (c *fakeClient) GetRace(ctx context.Context, in *racing.GetRaceRequest) (*racing.Race, error) {
+	if in.Id > 3 {
+		return nil, status.Errorf(codes.NotFound, "race %d: not found", in.Id)
+	}
+
+	return race(int64(in.Id)), nil
+}
+
+func (c *fakeClient) GetRacingClock(ctx context.Context) (*racing.Clock, error) {
+	return &racing.Clock{Now: timestamppb.New(now.Add(time.Hour)), Simulated: true}, nil
+}
+
+func (c *fakeClient) ListEvents(ctx context.Context, in *sports.ListEventsRequest) (*sports.ListEventsResponse, error) {
+	c.eventRequests = append(c.eventRequests, proto.Clone(in).(*sports.ListEventsRequest))
+	return &sports.ListEventsResponse{Events: []*sports.Event{{Id: 1, SportsType: "Tennis", Name: "Grand Final", AdvertisedStartTime: timestamppb.New(now.Add(2 * time.Hour))}}}, nil
+}
+
+func (c *fakeClient) GetEvent(ctx context.Context, in *sports.GetEventRequest) (*sports.Event, error) {
+	return &sports.Event{Id: int64(in.Id), Name: "Grand Final"}, nil
+}
+
+func (c *fakeClient) GetSportsClock(ctx context.Context) (*sports.Clock, error) {
+	return &sports.Clock{Now: timestamppb.New(now)}, nil
+}
+
+func (c *fakeClient) ListSportTypes(ctx context.Context, in *sports.ListSportTypesRequest) (*sports.ListSportTypesResponse, error) {
+	resp := &sports.ListSportTypesResponse{SportTypes: []*sports.SportType{
+		{Id: 3, Name: "Tennis", Category: sports.SportType_TRADITIONAL, Icon: "tennis", Active: true},
+	}}
+	if in.IncludeInactive {
+		// a made up sport type no longer offered
+		resp.SportTypes = append(resp.SportTypes, &sports.SportType{Id: 100, Name: "Polo", Category: sports.SportType_TRADITIONAL, Icon: "polo", Active: false})
+	}
+
+	return resp, nil
+}
+
+func race(id int64) *racing.Race {
+	return &racing.Race{Id: id, MeetingId: 3, Name: "Race " + string(rune('A'+id-1)), Number: id, Visible: true, AdvertisedStartTime: timestamppb.New(now.Add(time.Hour + time.Duration(id)*time.Minute)), Status: "OPEN"}
+}
+
+func newTestApp(c client.Client) (*app, *bytes.Buffer, *bytes.Buffer) {
+	var stdout, stderr bytes.Buffer
+	return &app{
+		stdout:    &stdout,
+		stderr:    &stderr,
+		lookupEnv: func(string) (string, bool) { return "", false },
+		dial: func(*config.Config) (client.Client, func() error, error) {
+			return c, func() error { return nil }, nil
+		},
+		loc: time.UTC,
+		now: func() time.Time { return now },
+	}, &stdout, &stderr
+}
+
+func TestRacesList(t *testing.T) {
+	c := &fakeClient{}
+	a, stdout, stderr := newTestApp(c)
+
+	err := a.run(context.Background(), strings.Fields("races list --meeting 3,4 --meeting 5 --visible --status open --starts-within 1h --sort advertised_start_time --limit 2"))
+	require.NoError(t, err)
+
+	require.Len(t, c.raceRequests, 1)
+	assert.True(t, proto.Equal(&racing.ListRacesRequest{
+		Filter: &racing.ListRacesRequestFilter{
+			MeetingIds:   []int64{3, 4, 5},
+			Visibility:   racing.ListRacesRequestFilter_VISIBILE,
+			Status:       racing.ListRacesRequestFilter_OPEN,
+			StartsWithin: durationpb.New(time.Hour),
+		},
+		OrderBy:  "advertised_start_time",
+		PageSize: 2,
+	}, c.raceRequests[0]), c.raceRequests[0])
+
+	assert.Equal(t, ""+
+		"ID  MEETING  NUMBER  NAME    VISIBLE  STATUS  START\n"+
+		"1   3        1       Race A  true     OPEN    2026-10-18 13:01:00 UTC\n"+
+		"2   3        2       Race B  true     OPEN    2026-10-18 13:02:00 UTC\n", stdout.String())
+	assert.Equal(t, "more races: --page-token page2\n", stderr.String())
+}
+
+func TestRacesListAll(t *testing.T) {
+	c := &fakeClient{}
+	a, stdout, stderr := newTestApp(c)
+
+	require.NoError(t, a.run(context.Background(), []string{"races", "list", "--all", "-o", "json"}))
+
+	require.Len(t, c.raceRequests, 2)
+	assert.Equal(t, "page2", c.raceRequests[1].PageToken)
+	assert.Contains(t, stdout.String(), `"name": "Race C"`)
+	assert.NotContains(t, stdout.String(), "nextPageToken")
+	assert.Empty(t, stderr.String())
+}
+
+func TestRacesListInvalid(t *testing.T) {
+	for _, args := range []string{
+		"races list --visible --hidden",
+		"races list --status started",
+		"races list --meeting x",
+		"races list --from yesterday",
+		"races list -o xml",
+	} {
+		a, _, _ := newTestApp(&fakeClient{})
+		assert.Error(t, a.run(context.Background(), strings.Fields(args)), args)
+	}
+}
+
+func TestRacesGet(t *testing.T) {
+	a, stdout, _ := newTestApp(&fakeClient{})
+	require.NoError(t, a.run(context.Background(), []string{"races", "get", "2", "-o", "yaml"}))
+	assert.Contains(t, stdout.String(), "name: Race B\n")
+
+	err := a.run(context.Background(), []string{"races", "get", "9"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	for _, args := range []string{"races get", "races get two", "races get 1 2", "races get 0"} {
+		assert.Error(t, a.run(context.Background(), strings.Fields(args)), args)
+	}
+}
+
+func TestEventsList(t *testing.T) {
+	c := &fakeClient{}
+	a, stdout, _ := newTestApp(c)
+
+	require.NoError(t, a.run(context.Background(), []string{"events", "list", "--sport", "Tennis", "--event", "2", "--from", "2026-10-18T00:00:00Z"}))
+
+	require.Len(t, c.eventRequests, 1)
+	filter := c.eventRequests[0].Filter
+	assert.Equal(t, []string{"Tennis"}, filter.SportTypes)
+	assert.Empty(t, filter.Expression)
+	assert.Equal(t, []int64{2}, filter.EventIds)
+	assert.Equal(t, time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC), filter.StartTimeFrom.AsTime())
+	assert.Contains(t, stdout.String(), "Tennis")
+}
+
+func TestSportTypesList(t *testing.T) {
+	a, stdout, _ := newTestApp(&fakeClient{})
+
+	require.NoError(t, a.run(context.Background(), []string{"sport-types", "list"}))
+	assert.Contains(t, stdout.String(), "TRADITIONAL")
+	assert.NotContains(t, stdout.String(), "Polo")
+
+	stdout.Reset()
+	require.NoError(t, a.run(context.Background(), []string{"sport-types", "list", "--all"}))
+	assert.Contains(t, stdout.String(), "Polo")
+}
+
+func TestWatch(t *testing.T) {
+	c := &fakeClient{}
+	a, stdout, _ := newTestApp(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, a.run(ctx, []string{"races", "watch", "--meeting", "3", "--limit", "2"}))
+
+	require.Len(t, c.raceRequests, 1)
+	req := c.raceRequests[0]
+	assert.Equal(t, racing.ListRacesRequestFilter_OPEN, req.Filter.Status)
+	assert.Equal(t, racing.ListRacesRequestFilter_VISIBILE, req.Filter.Visibility)
+	assert.Equal(t, []int64{3}, req.Filter.MeetingIds)
+	assert.Equal(t, "advertised_start_time", req.OrderBy)
+	assert.Equal(t, int32(2), req.PageSize)
+
+	// the racing clock is an hour ahead of the local one, so race 1, an hour
+	// and a minute from the local now, jumps in a minute
+	assert.Equal(t, clearScreen+"Next to jump at 13:00:00 UTC, refreshing every 5s\n\n"+
+		"JUMPS IN  MEETING  NUMBER  NAME    START\n"+
+		"1m00s     3        1       Race A  2026-10-18 13:01:00 UTC\n"+
+		"2m00s     3        2       Race B  2026-10-18 13:02:00 UTC\n", stdout.String())
+
+	err := a.run(ctx, []string{"events", "watch", "-o", "json"})
+	assert.EqualError(t, err, "watch only prints tables, not json")
+}
+
+func TestUsage(t *testing.T) {
+	a, _, stderr := newTestApp(&fakeClient{})
+
+	assert.EqualError(t, a.run(context.Background(), []string{"meetings", "list"}), `unknown resource "meetings"`)
+	assert.EqualError(t, a.run(context.Background(), []string{"races", "delete"}), `unknown command "delete" for races`)
+	assert.Contains(t, stderr.String(), "entainctl races get [flags] ID")
+}
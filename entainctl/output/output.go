@@ -0,0 +1,232 @@
+// Package output prints races, events and sport types as entainctl's --output asks: a
+// table for people, or JSON or YAML, shaped like the REST API's, for
+// scripts.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gopkg.in/yaml.v3"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+// Format is an output format.
+type Format string
+
+// The formats a Printer prints in.
+const (
+	Table Format = "table"
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+)
+
+// timeLayout is how start times are shown in tables.
+const timeLayout = "2006-01-02 15:04:05 MST"
+
+// Printer prints results to a writer.
+type Printer struct {
+	w      io.Writer
+	format Format
+	loc    *time.Location
+}
+
+// NewPrinter returns a printer writing to w in format, showing times in loc.
+func NewPrinter(w io.Writer, format Format, loc *time.Location) *Printer {
+	return &Printer{w: w, format: format, loc: loc}
+}
+
+// Races prints a page of races.
+func (p *Printer) Races(resp *racing.ListRacesResponse) error {
+	if p.format != Table {
+		return p.message(resp)
+	}
+
+	return p.table([]string{"ID", "MEETING", "NUMBER", "NAME", "VISIBLE", "STATUS", "START"}, len(resp.Races), func(i int) []string {
+		return p.raceRow(resp.Races[i])
+	})
+}
+
+// Race prints a race.
+func (p *Printer) Race(race *racing.Race) error {
+	if p.format != Table {
+		return p.message(race)
+	}
+
+	return p.Races(&racing.ListRacesResponse{Races: []*racing.Race{race}})
+}
+
+// Events prints a page of events.
+func (p *Printer) Events(resp *sports.ListEventsResponse) error {
+	if p.format != Table {
+		return p.message(resp)
+	}
+
+	return p.table([]string{"ID", "EVENT", "SPORT", "NUMBER", "NAME", "STATUS", "START"}, len(resp.Events), func(i int) []string {
+		return p.eventRow(resp.Events[i])
+	})
+}
+
+// Event prints an event.
+func (p *Printer) Event(event *sports.Event) error {
+	if p.format != Table {
+		return p.message(event)
+	}
+
+	return p.Events(&sports.ListEventsResponse{Events: []*sports.Event{event}})
+}
+
+// SportTypes prints sport types.
+func (p *Printer) SportTypes(resp *sports.ListSportTypesResponse) error {
+	if p.format != Table {
+		return p.message(resp)
+	}
+
+	return p.table([]string{"ID", "NAME", "CATEGORY", "ICON", "ACTIVE"}, len(resp.SportTypes), func(i int) []string {
+		sportType := resp.SportTypes[i]
+		return []string{strconv.FormatInt(sportType.Id, 10), sportType.Name, sportType.Category.String(), sportType.Icon, strconv.FormatBool(sportType.Active)}
+	})
+}
+
+// RaceCountdown prints races next to jump as a table counting down to their
+// start from now, whatever the format.
+func (p *Printer) RaceCountdown(races []*racing.Race, now time.Time) error {
+	return p.table([]string{"JUMPS IN", "MEETING", "NUMBER", "NAME", "START"}, len(races), func(i int) []string {
+		race := races[i]
+		return []string{Countdown(race.AdvertisedStartTime.AsTime().Sub(now)), strconv.FormatInt(race.MeetingId, 10), strconv.FormatInt(race.Number, 10), race.Name, p.time(race.AdvertisedStartTime)}
+	})
+}
+
+// EventCountdown prints events next to start as a table counting down to
+// their start from now, whatever the format.
+func (p *Printer) EventCountdown(events []*sports.Event, now time.Time) error {
+	return p.table([]string{"STARTS IN", "SPORT", "EVENT", "NAME", "START"}, len(events), func(i int) []string {
+		event := events[i]
+		return []string{Countdown(event.AdvertisedStartTime.AsTime().Sub(now)), event.SportsType, strconv.FormatInt(event.EventId, 10), event.Name, p.time(event.AdvertisedStartTime)}
+	})
+}
+
+// Countdown formats the time left until a start: "1h02m", "4m05s" or "35s",
+// or "started" once it has passed.
+func Countdown(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	switch {
+	case d < 0:
+		return "started"
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+
+	return fmt.Sprintf("%ds", int(d.Seconds()))
+}
+
+func (p *Printer) raceRow(race *racing.Race) []string {
+	return []string{
+		strconv.FormatInt(race.Id, 10),
+		strconv.FormatInt(race.MeetingId, 10),
+		strconv.FormatInt(race.Number, 10),
+		race.Name,
+		strconv.FormatBool(race.Visible),
+		race.Status,
+		p.time(race.AdvertisedStartTime),
+	}
+}
+
+func (p *Printer) eventRow(event *sports.Event) []string {
+	return []string{
+		strconv.FormatInt(event.Id, 10),
+		strconv.FormatInt(event.EventId, 10),
+		event.SportsType,
+		strconv.FormatInt(event.Number, 10),
+		event.Name,
+		event.Status,
+		p.time(event.AdvertisedStartTime),
+	}
+}
+
+func (p *Printer) time(ts *timestamppb.Timestamp) string {
+	if ts == nil {
+		return ""
+	}
+
+	return ts.AsTime().In(p.loc).Format(timeLayout)
+}
+
+// table prints n rows under header.
+func (p *Printer) table(header []string, n int, row func(i int) []string) error {
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	writeRow(tw, header)
+	for i := 0; i < n; i++ {
+		writeRow(tw, row(i))
+	}
+
+	return tw.Flush()
+}
+
+func writeRow(w io.Writer, cells []string) {
+	for i, cell := range cells {
+		if i > 0 {
+			io.WriteString(w, "\t")
+		}
+		io.WriteString(w, cell)
+	}
+	io.WriteString(w, "\n")
+}
+
+// message prints m as JSON or YAML, with the field names of the REST API.
+func (p *Printer) message(m proto.Message) error {
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	if p.format == JSON {
+		// protojson varies its whitespace on purpose; indenting again makes
+		// the output the same from one build to the next.
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, b, "", "  "); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(p.w, "%s\n", buf.Bytes())
+		return err
+	}
+
+	// JSON is YAML, so it decodes into YAML nodes keeping the order of the
+	// fields; only the flow style needs dropping.
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	_, err = p.w.Write(buf.Bytes())
+
+	return err
+}
+
+// blockStyle sets node and its children to YAML's block style. Strings keep
+// their tag, so those that would read as another type stay quoted.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
@@ -0,0 +1,83 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+var (
+	now  = time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	race = &racing.Race{Id: 12, MeetingId: 3, Name: "Flemington Cup", Number: 4, Visible: true, AdvertisedStartTime: timestamppb.New(now.Add(90 * time.Second)), Status: "OPEN"}
+)
+
+func printed(t *testing.T, format Format, f func(p *Printer) error) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, f(NewPrinter(&buf, format, time.FixedZone("AEDT", 11*60*60))))
+
+	return buf.String()
+}
+
+func TestTable(t *testing.T) {
+	out := printed(t, Table, func(p *Printer) error { return p.Race(race) })
+	assert.Equal(t, ""+
+		"ID  MEETING  NUMBER  NAME            VISIBLE  STATUS  START\n"+
+		"12  3        4       Flemington Cup  true     OPEN    2026-10-18 23:01:30 AEDT\n", out)
+
+	out = printed(t, Table, func(p *Printer) error {
+		return p.Events(&sports.ListEventsResponse{Events: []*sports.Event{{Id: 1, EventId: 2, SportsType: "Tennis", Name: "Grand Final", Number: 3, Status: "CLOSED"}}})
+	})
+	assert.Equal(t, ""+
+		"ID  EVENT  SPORT   NUMBER  NAME         STATUS  START\n"+
+		"1   2      Tennis  3       Grand Final  CLOSED  \n", out)
+}
+
+func TestJSON(t *testing.T) {
+	out := printed(t, JSON, func(p *Printer) error { return p.Race(race) })
+	assert.JSONEq(t, `{"id": "12", "meetingId": "3", "name": "Flemington Cup", "number": "4", "visible": true, "advertisedStartTime": "2026-10-18T12:01:30Z", "status": "OPEN"}`, out)
+}
+
+func TestYAML(t *testing.T) {
+	out := printed(t, YAML, func(p *Printer) error {
+		return p.Races(&racing.ListRacesResponse{Races: []*racing.Race{race, {Id: 13, Name: "true"}}, NextPageToken: "bzE6Mg"})
+	})
+	assert.Equal(t, `races:
+  - id: "12"
+    meetingId: "3"
+    name: Flemington Cup
+    number: "4"
+    visible: true
+    advertisedStartTime: "2026-10-18T12:01:30Z"
+    status: OPEN
+  - id: "13"
+    name: "true"
+nextPageToken: bzE6Mg
+`, out)
+}
+
+func TestCountdown(t *testing.T) {
+	out := printed(t, JSON, func(p *Printer) error { return p.RaceCountdown([]*racing.Race{race}, now) })
+	assert.Equal(t, ""+
+		"JUMPS IN  MEETING  NUMBER  NAME            START\n"+
+		"1m30s     3        4       Flemington Cup  2026-10-18 23:01:30 AEDT\n", out, "countdowns are always tables")
+
+	for d, want := range map[time.Duration]string{
+		-time.Second:                      "started",
+		0:                                 "0s",
+		59*time.Second + time.Millisecond: "59s",
+		4*time.Minute + 5*time.Second:     "4m05s",
+		62 * time.Minute:                  "1h02m",
+		26 * time.Hour:                    "26h00m",
+	} {
+		assert.Equal(t, want, Countdown(d), d)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"git.neds.sh/matty/entain/entainctl/output"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// raceFilterFlags are the flags races are filtered with.
+type raceFilterFlags struct {
+	meetings        idsFlag
+	visible, hidden bool
+	status          string
+	startsWithin    time.Duration
+	from, to        timeFlag
+	expression      string
+}
+
+func (f *raceFilterFlags) bind(fs *flag.FlagSet) {
+	fs.Var(&f.meetings, "meeting", "Only races of these meeting IDs, comma separated or repeated")
+	fs.BoolVar(&f.visible, "visible", false, "Only visible races")
+	fs.BoolVar(&f.hidden, "hidden", false, "Only hidden races")
+	fs.StringVar(&f.status, "status", "", "Only open or closed races")
+	fs.DurationVar(&f.startsWithin, "starts-within", 0, "Only races starting within this long from now, e.g. 1h")
+	fs.Var(&f.from, "from", "Only races starting at or after this RFC 3339 time")
+	fs.Var(&f.to, "to", "Only races starting before this RFC 3339 time")
+	fs.StringVar(&f.expression, "filter", "", `Filter expression, e.g. 'number <= 4 AND name != "Flemington Cup"'`)
+}
+
+func (f *raceFilterFlags) filter() (*racing.ListRacesRequestFilter, error) {
+	filter := &racing.ListRacesRequestFilter{
+		MeetingIds:    f.meetings,
+		StartTimeFrom: f.from.t,
+		StartTimeTo:   f.to.t,
+		Expression:    f.expression,
+	}
+
+	switch {
+	case f.visible && f.hidden:
+		return nil, errors.New("--visible and --hidden exclude each other")
+	case f.visible:
+		filter.Visibility = racing.ListRacesRequestFilter_VISIBILE
+	case f.hidden:
+		filter.Visibility = racing.ListRacesRequestFilter_HIDDEN
+	}
+
+	if f.status != "" {
+		status, ok := racing.ListRacesRequestFilter_RaceStatus_value[strings.ToUpper(f.status)]
+		if !ok || status == 0 {
+			return nil, fmt.Errorf("--status must be open or closed, got %q", f.status)
+		}
+		filter.Status = racing.ListRacesRequestFilter_RaceStatus(status)
+	}
+
+	if f.startsWithin != 0 {
+		filter.StartsWithin = durationpb.New(f.startsWithin)
+	}
+
+	return filter, nil
+}
+
+func listRaces(ctx context.Context, a *app, args []string) error {
+	var (
+		filter    raceFilterFlags
+		order     string
+		limit     int
+		pageToken string
+		all       bool
+	)
+	s, err := a.start("races list", args, func(fs *flag.FlagSet) {
+		filter.bind(fs)
+		fs.StringVar(&order, "sort", "", `Order of the races, e.g. "advertised_start_time desc, number"`)
+		fs.IntVar(&limit, "limit", 20, "Races per page; 0 lists them all at once")
+		fs.StringVar(&pageToken, "page-token", "", "Page to list, as handed out by the previous one")
+		fs.BoolVar(&all, "all", false, "List every page")
+	})
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	req := &racing.ListRacesRequest{OrderBy: order, PageSize: int32(limit), PageToken: pageToken}
+	if req.Filter, err = filter.filter(); err != nil {
+		return err
+	}
+
+	resp := &racing.ListRacesResponse{}
+	for {
+		callCtx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+		page, err := s.client.ListRaces(callCtx, req)
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		resp.Races = append(resp.Races, page.Races...)
+		resp.NextPageToken = page.NextPageToken
+		if !all || page.NextPageToken == "" {
+			break
+		}
+		req.PageToken = page.NextPageToken
+	}
+
+	if err := s.printer.Races(resp); err != nil {
+		return err
+	}
+	if resp.NextPageToken != "" && s.cfg.Output == "table" {
+		fmt.Fprintf(a.stderr, "more races: --page-token %s\n", resp.NextPageToken)
+	}
+
+	return nil
+}
+
+func getRace(ctx context.Context, a *app, args []string) error {
+	s, err := a.start("races get", args, func(*flag.FlagSet) {})
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	id, err := parseID(s.args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	race, err := s.client.GetRace(ctx, &racing.GetRaceRequest{Id: id})
+	if err != nil {
+		return err
+	}
+
+	return s.printer.Race(race)
+}
+
+func watchRaces(ctx context.Context, a *app, args []string) error {
+	var (
+		meetings idsFlag
+		limit    int
+		refresh  time.Duration
+	)
+	s, err := a.start("races watch", args, func(fs *flag.FlagSet) {
+		fs.Var(&meetings, "meeting", "Only races of these meeting IDs, comma separated or repeated")
+		fs.IntVar(&limit, "limit", 10, "How many races to show")
+		fs.DurationVar(&refresh, "refresh", 5*time.Second, "How often the races are fetched again")
+	})
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	req := &racing.ListRacesRequest{
+		Filter: &racing.ListRacesRequestFilter{
+			MeetingIds: meetings,
+			Visibility: racing.ListRacesRequestFilter_VISIBILE,
+			Status:     racing.ListRacesRequestFilter_OPEN,
+		},
+		OrderBy:  "advertised_start_time",
+		PageSize: int32(limit),
+	}
+
+	var races []*racing.Race
+	w := &watcher{
+		app:     a,
+		title:   "Next to jump",
+		refresh: refresh,
+		timeout: s.cfg.Timeout,
+		fetch: func(ctx context.Context) (time.Time, error) {
+			clock, err := s.client.GetRacingClock(ctx)
+			if err != nil {
+				return time.Time{}, err
+			}
+			resp, err := s.client.ListRaces(ctx, req)
+			if err != nil {
+				return time.Time{}, err
+			}
+
+			races = resp.Races
+			return clock.Now.AsTime(), nil
+		},
+		render: func(w io.Writer, now time.Time) error {
+			return output.NewPrinter(w, output.Table, a.loc).RaceCountdown(races, now)
+		},
+	}
+
+	return w.run(ctx, s.cfg.Output)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\033[H\033[2J"
+
+// watcher redraws a countdown every second, fetching what it counts down to
+// every refresh. Countdowns are told by the service's clock, which may be
+// simulated, assuming it runs at the pace of the local one.
+type watcher struct {
+	app     *app
+	title   string
+	refresh time.Duration
+	timeout time.Duration
+
+	// fetch fetches what to show, returning the service's time.
+	fetch func(ctx context.Context) (time.Time, error)
+
+	// render writes what was last fetched, counting down from now.
+	render func(w io.Writer, now time.Time) error
+}
+
+// run redraws until ctx is done. Failing to fetch at first is an error;
+// failing to refetch is shown above what was fetched last.
+func (w *watcher) run(ctx context.Context, format string) error {
+	if format != "table" {
+		return fmt.Errorf("watch only prints tables, not %s", format)
+	}
+	if w.refresh <= 0 {
+		return fmt.Errorf("--refresh must be positive")
+	}
+
+	offset, err := w.update(ctx)
+	if err != nil {
+		return err
+	}
+	fetched := w.app.now()
+
+	var fetchErr error
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	for {
+		if err := w.draw(w.app.now().Add(offset), fetchErr); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick.C:
+		}
+
+		if w.app.now().Sub(fetched) >= w.refresh {
+			fetched = w.app.now()
+			if o, err := w.update(ctx); err != nil {
+				fetchErr = err
+			} else {
+				offset, fetchErr = o, nil
+			}
+		}
+	}
+}
+
+// update fetches afresh, returning how far the service's clock is ahead of
+// the local one.
+func (w *watcher) update(ctx context.Context) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	now, err := w.fetch(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return now.Sub(w.app.now()), nil
+}
+
+// draw redraws the screen in one write, so it does not flicker.
+func (w *watcher) draw(now time.Time, fetchErr error) error {
+	var buf bytes.Buffer
+	buf.WriteString(clearScreen)
+	fmt.Fprintf(&buf, "%s at %s, refreshing every %s\n", w.title, now.In(w.app.loc).Format("15:04:05 MST"), w.refresh)
+	if fetchErr != nil {
+		fmt.Fprintf(&buf, "refresh failed: %s\n", fetchErr)
+	}
+	buf.WriteString("\n")
+
+	if err := w.render(&buf, now); err != nil {
+		return err
+	}
+	_, err := w.app.stdout.Write(buf.Bytes())
+
+	return err
+}
@@ -0,0 +1,131 @@
+package load
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+	"time"
+)
+
+// latencyNoise is the smallest latency change Compare flags: below it, run
+// to run jitter easily exceeds any threshold.
+const latencyNoise = time.Millisecond
+
+// Delta is how one measure of an op changed from a base run to a head run.
+type Delta struct {
+	Op Op
+
+	// Measure is "calls/s", "p50", "p90", "p99" or "errors", the error rate.
+	Measure    string
+	Base, Head float64
+
+	// Change is the relative change from Base to Head, e.g. 0.1 for 10%
+	// more, or +Inf if Base is zero and Head is not.
+	Change float64
+
+	// Regression reports whether the change is for the worse by more than
+	// the threshold.
+	Regression bool
+}
+
+// Compare compares head against base, for each op called in both and in
+// total. Lower throughput, higher latency and a higher error rate count as a
+// regression when they change by more than threshold, e.g. 0.1 for 10%;
+// latencies also need to change by more than a millisecond.
+func Compare(base, head *Report, threshold float64) []Delta {
+	ops := make([]Op, 0, len(Ops)+1)
+	ops = append(append(ops, Ops...), total)
+
+	var deltas []Delta
+	for _, op := range ops {
+		b, h := base.Op(op), head.Op(op)
+		if b == nil || h == nil {
+			continue
+		}
+
+		throughput := newDelta(op, "calls/s", b.Throughput, h.Throughput)
+		throughput.Regression = -throughput.Change > threshold
+		deltas = append(deltas, throughput)
+
+		for _, l := range []struct {
+			measure    string
+			base, head time.Duration
+		}{
+			{"p50", b.Latency.P50, h.Latency.P50},
+			{"p90", b.Latency.P90, h.Latency.P90},
+			{"p99", b.Latency.P99, h.Latency.P99},
+		} {
+			d := newDelta(op, l.measure, milliseconds(l.base), milliseconds(l.head))
+			d.Regression = d.Change > threshold && l.head-l.base > latencyNoise
+			deltas = append(deltas, d)
+		}
+
+		errors := newDelta(op, "errors", b.ErrorRate(), h.ErrorRate())
+		errors.Regression = errors.Change > threshold
+		deltas = append(deltas, errors)
+	}
+
+	return deltas
+}
+
+// Regressed reports whether any of deltas is a regression.
+func Regressed(deltas []Delta) bool {
+	for _, d := range deltas {
+		if d.Regression {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WriteComparison writes deltas as a table, marking regressions.
+func WriteComparison(w io.Writer, deltas []Delta) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "op\tmeasure\tbase\thead\tchange\t")
+	for _, d := range deltas {
+		mark := ""
+		if d.Regression {
+			mark = "REGRESSION"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", d.Op, d.Measure, formatMeasure(d.Measure, d.Base), formatMeasure(d.Measure, d.Head), formatChange(d.Change), mark)
+	}
+
+	return tw.Flush()
+}
+
+func newDelta(op Op, measure string, base, head float64) Delta {
+	d := Delta{Op: op, Measure: measure, Base: base, Head: head}
+	switch {
+	case base != 0:
+		d.Change = (head - base) / base
+	case head != 0:
+		d.Change = math.Inf(1)
+	}
+
+	return d
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func formatMeasure(measure string, v float64) string {
+	switch measure {
+	case "calls/s":
+		return fmt.Sprintf("%.1f", v)
+	case "errors":
+		return fmt.Sprintf("%.2f%%", v*100)
+	}
+
+	return fmt.Sprintf("%.2fms", v)
+}
+
+func formatChange(change float64) string {
+	if math.IsInf(change, 1) {
+		return "new"
+	}
+
+	return fmt.Sprintf("%+.1f%%", change*100)
+}
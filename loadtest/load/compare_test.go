@@ -0,0 +1,67 @@
+package load
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	opReport := func(op Op, calls, errors int, throughput float64, p50, p99 time.Duration) *OpReport {
+		r := &OpReport{Op: op, Calls: calls, Throughput: throughput, Latency: Latency{P50: p50, P90: p50, P99: p99, Max: p99}}
+		if errors > 0 {
+			r.Errors = map[string]int{"Unavailable": errors}
+		}
+		return r
+	}
+
+	base := &Report{
+		Ops:   []*OpReport{opReport(ListRaces, 1000, 0, 100, 2*time.Millisecond, 10*time.Millisecond), opReport(GetRace, 100, 0, 10, time.Millisecond, 2*time.Millisecond)},
+		Total: opReport(total, 1100, 0, 110, 2*time.Millisecond, 10*time.Millisecond),
+	}
+	head := &Report{
+		Ops: []*OpReport{
+			// slower at the tail, throughput within the threshold
+			opReport(ListRaces, 950, 0, 95, 2*time.Millisecond, 20*time.Millisecond),
+			// latency up by more than the threshold, but by less than the noise
+			opReport(GetRace, 100, 1, 10, 1500*time.Microsecond, 2500*time.Microsecond),
+		},
+		Total: opReport(total, 1050, 1, 105, 2*time.Millisecond, 20*time.Millisecond),
+	}
+
+	deltas := Compare(base, head, 0.1)
+	require.Len(t, deltas, 15)
+
+	regressions := make(map[string]bool)
+	for _, d := range deltas {
+		if d.Regression {
+			regressions[string(d.Op)+" "+d.Measure] = true
+		}
+	}
+	assert.Equal(t, map[string]bool{
+		"list-races p99":  true,
+		"get-race errors": true,
+		"total p99":       true,
+		"total errors":    true,
+	}, regressions)
+	assert.True(t, Regressed(deltas))
+
+	assert.Equal(t, -0.05, round(deltas[0].Change))
+	assert.True(t, math.IsInf(deltas[9].Change, 1), "errors where there were none")
+
+	var out bytes.Buffer
+	require.NoError(t, WriteComparison(&out, deltas))
+	assert.Contains(t, out.String(), "+100.0%")
+	assert.Contains(t, out.String(), "REGRESSION")
+	assert.Contains(t, out.String(), "new")
+
+	assert.False(t, Regressed(Compare(base, base, 0.1)))
+}
+
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
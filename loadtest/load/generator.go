@@ -0,0 +1,160 @@
+package load
+
+import (
+	"math/rand"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+// Call is a call to make.
+type Call struct {
+	Op Op
+
+	// Request is a *racing.ListRacesRequest, *racing.GetRaceRequest,
+	// *sports.ListEventsRequest or *sports.GetEventRequest, after Op.
+	Request proto.Message
+
+	// Pages is how many more pages of a list to fetch after the first,
+	// following the next page tokens, as a client scrolling would.
+	Pages int
+}
+
+// The values list filters are drawn from. Repeated values are drawn more
+// often.
+var (
+	raceOrders   = []string{"advertised_start_time", "advertised_start_time", "advertised_start_time", "advertised_start_time", "", "meeting_id, number", "name", "advertised_start_time desc"}
+	eventOrders  = []string{"advertised_start_time", "advertised_start_time", "advertised_start_time", "", "sports_type, advertised_start_time", "name"}
+	pageSizes    = []int32{10, 10, 20, 20, 20, 50, 0}
+	startsWithin = []time.Duration{time.Hour, 3 * time.Hour, 24 * time.Hour}
+	raceFilters  = []string{"number <= 4", `name != ""`, "meeting_id IN (1, 2, 3) AND number > 2"}
+	eventFilters = []string{`sports_type = "Tennis"`, `sports_type IN ("Football", "Soccer")`, "number <= 4 OR event_id = 1"}
+	sportTypes   = [][]string{{"Tennis"}, {"Football", "Soccer"}, {"DOTA", "Counter Strike 2"}}
+)
+
+// Races are seeded with meeting IDs, and events with event IDs, 1 to 10.
+const (
+	meetingIDRange = 10
+	eventIDRange   = 10
+)
+
+// Generator makes up calls of a mix, with filters distributed roughly the way
+// clients use them: most lists ask for the next visible, open races sorted
+// by start time, some narrow them down by meeting, sport type, time window or
+// filter expression, and some scroll through a few pages. Gets ask for IDs 1 to
+// maxID, the rows the services are seeded with.
+//
+// A Generator is not safe for concurrent use.
+type Generator struct {
+	mix   Mix
+	maxID int
+	rng   *rand.Rand
+}
+
+// NewGenerator returns a generator of calls of mix, making up the same calls
+// for the same seed.
+func NewGenerator(mix Mix, maxID int, seed int64) *Generator {
+	if maxID < 1 {
+		maxID = 1
+	}
+
+	return &Generator{mix: mix, maxID: maxID, rng: rand.New(rand.NewSource(seed))}
+}
+
+// Next makes up the next call.
+func (g *Generator) Next() Call {
+	call := Call{Op: g.mix.pick(g.rng)}
+
+	switch call.Op {
+	case ListRaces:
+		call.Request = g.listRaces()
+	case GetRace:
+		call.Request = &racing.GetRaceRequest{Id: g.id()}
+	case ListEvents:
+		call.Request = g.listEvents()
+	case GetEvent:
+		call.Request = &sports.GetEventRequest{Id: g.id()}
+	}
+
+	if (call.Op == ListRaces || call.Op == ListEvents) && g.chance(0.2) {
+		call.Pages = 1 + g.rng.Intn(3)
+	}
+
+	return call
+}
+
+func (g *Generator) listRaces() *racing.ListRacesRequest {
+	filter := &racing.ListRacesRequestFilter{}
+	if g.chance(0.7) {
+		filter.Visibility = racing.ListRacesRequestFilter_VISIBILE
+	}
+	if g.chance(0.4) {
+		filter.Status = racing.ListRacesRequestFilter_OPEN
+	}
+	if g.chance(0.3) {
+		filter.MeetingIds = g.ids(meetingIDRange)
+	}
+	if g.chance(0.2) {
+		filter.StartsWithin = durationpb.New(startsWithin[g.rng.Intn(len(startsWithin))])
+	}
+	if g.chance(0.1) {
+		filter.Expression = raceFilters[g.rng.Intn(len(raceFilters))]
+	}
+
+	return &racing.ListRacesRequest{
+		Filter:   filter,
+		OrderBy:  raceOrders[g.rng.Intn(len(raceOrders))],
+		PageSize: pageSizes[g.rng.Intn(len(pageSizes))],
+	}
+}
+
+func (g *Generator) listEvents() *sports.ListEventsRequest {
+	filter := &sports.ListEventsRequestFilter{}
+	if g.chance(0.4) {
+		filter.Status = sports.ListEventsRequestFilter_OPEN
+	}
+	if g.chance(0.3) {
+		filter.EventIds = g.ids(eventIDRange)
+	}
+	if g.chance(0.2) {
+		filter.StartsWithin = durationpb.New(startsWithin[g.rng.Intn(len(startsWithin))])
+	}
+	if g.chance(0.2) {
+		filter.Expression = eventFilters[g.rng.Intn(len(eventFilters))]
+	}
+	if g.chance(0.2) {
+		filter.SportTypes = sportTypes[g.rng.Intn(len(sportTypes))]
+	}
+
+	return &sports.ListEventsRequest{
+		Filter:   filter,
+		OrderBy:  eventOrders[g.rng.Intn(len(eventOrders))],
+		PageSize: pageSizes[g.rng.Intn(len(pageSizes))],
+	}
+}
+
+// id returns an ID between 1 and maxID.
+func (g *Generator) id() int32 {
+	return int32(1 + g.rng.Intn(g.maxID))
+}
+
+// ids returns one to three distinct IDs between 1 and n.
+func (g *Generator) ids(n int) []int64 {
+	perm := g.rng.Perm(n)[:1+g.rng.Intn(3)]
+
+	ids := make([]int64, len(perm))
+	for i, p := range perm {
+		ids[i] = int64(p + 1)
+	}
+
+	return ids
+}
+
+// chance reports true with probability p.
+func (g *Generator) chance(p float64) bool {
+	return g.rng.Float64() < p
+}
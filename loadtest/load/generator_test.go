@@ -0,0 +1,43 @@
+package load
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.neds.sh/matty/entain/common/validation"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+func TestGenerator(t *testing.T) {
+	mix, _ := ParseMix(DefaultMix)
+	gen := NewGenerator(mix, 100, 1)
+	same := NewGenerator(mix, 100, 1)
+
+	counts := make(map[Op]int)
+	pages := 0
+	for i := 0; i < 1000; i++ {
+		call := gen.Next()
+		assert.Equal(t, call, same.Next(), "the same seed makes up the same calls")
+		assert.Empty(t, validation.Validate(call.Request), "%s %v", call.Op, call.Request)
+
+		counts[call.Op]++
+		pages += call.Pages
+
+		switch req := call.Request.(type) {
+		case *racing.GetRaceRequest:
+			assert.True(t, req.Id >= 1 && req.Id <= 100, req.Id)
+		case *sports.GetEventRequest:
+			assert.True(t, req.Id >= 1 && req.Id <= 100, req.Id)
+		case *racing.ListRacesRequest:
+			assert.Equal(t, ListRaces, call.Op)
+		case *sports.ListEventsRequest:
+			assert.Equal(t, ListEvents, call.Op)
+		}
+	}
+
+	assert.Len(t, counts, len(Ops))
+	assert.Greater(t, counts[ListRaces], counts[GetRace])
+	assert.NotZero(t, pages, "some lists scroll")
+}
@@ -0,0 +1,119 @@
+// Package load drives a mix of calls at the racing and sports services, over
+// REST through the API gateway or over gRPC straight to the services, and
+// reports their throughput and latency.
+package load
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Op is a kind of call.
+type Op string
+
+// The calls load can be made of.
+const (
+	ListRaces  Op = "list-races"
+	GetRace    Op = "get-race"
+	ListEvents Op = "list-events"
+	GetEvent   Op = "get-event"
+)
+
+// Ops lists every Op.
+var Ops = []Op{ListRaces, GetRace, ListEvents, GetEvent}
+
+// DefaultMix is mostly race lists, as served to the racing pages.
+const DefaultMix = "list-races=6,get-race=2,list-events=1,get-event=1"
+
+// Mix weighs ops: each call is op with probability its weight over the total.
+type Mix map[Op]int
+
+// ParseMix parses comma separated op=weight pairs, e.g.
+// "list-races=3,get-race=1".
+func ParseMix(s string) (Mix, error) {
+	mix := make(Mix)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, weight, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("mix %q: want op=weight", pair)
+		}
+		op := Op(strings.TrimSpace(name))
+		if !knownOp(op) {
+			return nil, fmt.Errorf("mix %q: unknown op %q, want one of %s", pair, op, opNames())
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(weight))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("mix %q: weight must be a non-negative integer", pair)
+		}
+
+		mix[op] += n
+	}
+
+	if mix.total() == 0 {
+		return nil, fmt.Errorf("mix %q: no op has a weight", s)
+	}
+
+	return mix, nil
+}
+
+// String formats the mix as ParseMix takes it.
+func (m Mix) String() string {
+	var pairs []string
+	for _, op := range Ops {
+		if m[op] > 0 {
+			pairs = append(pairs, fmt.Sprintf("%s=%d", op, m[op]))
+		}
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func (m Mix) total() int {
+	total := 0
+	for _, op := range Ops {
+		total += m[op]
+	}
+
+	return total
+}
+
+// pick chooses an op by weight.
+func (m Mix) pick(rng *rand.Rand) Op {
+	n := rng.Intn(m.total())
+	for _, op := range Ops {
+		if n < m[op] {
+			return op
+		}
+		n -= m[op]
+	}
+
+	panic("load: empty mix")
+}
+
+func knownOp(op Op) bool {
+	for _, known := range Ops {
+		if op == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+func opNames() string {
+	names := make([]string, len(Ops))
+	for i, op := range Ops {
+		names[i] = string(op)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,35 @@
+package load
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMix(t *testing.T) {
+	mix, err := ParseMix(" list-races=3, get-race=1,list-races=1,get-event=0")
+	require.NoError(t, err)
+	assert.Equal(t, Mix{ListRaces: 4, GetRace: 1, GetEvent: 0}, mix)
+	assert.Equal(t, "list-races=4,get-race=1", mix.String())
+
+	for _, s := range []string{"", "list-races", "list-races=x", "list-races=-1", "search=1", "get-race=0"} {
+		_, err := ParseMix(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestMixPick(t *testing.T) {
+	mix := Mix{ListRaces: 3, GetEvent: 1}
+	rng := rand.New(rand.NewSource(1))
+
+	counts := make(map[Op]int)
+	for i := 0; i < 4000; i++ {
+		counts[mix.pick(rng)]++
+	}
+
+	assert.Len(t, counts, 2)
+	assert.InDelta(t, 3000, counts[ListRaces], 150)
+	assert.InDelta(t, 1000, counts[GetEvent], 150)
+}
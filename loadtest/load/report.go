@@ -0,0 +1,253 @@
+package load
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Report is the outcome of a run. It is saved as JSON to compare later runs
+// against; durations are in nanoseconds.
+type Report struct {
+	Target      string        `json:"target"`
+	Mix         string        `json:"mix"`
+	Concurrency int           `json:"concurrency"`
+	Rate        float64       `json:"rate,omitempty"`
+	Started     time.Time     `json:"started"`
+	Duration    time.Duration `json:"duration"`
+
+	// Missed counts the calls not started for want of a free worker.
+	Missed int `json:"missed,omitempty"`
+
+	// Ops has one entry per op called, in the order of Ops, and Total sums
+	// them up.
+	Ops   []*OpReport `json:"ops"`
+	Total *OpReport   `json:"total"`
+}
+
+// total is the op Report.Total reports on.
+const total Op = "total"
+
+// OpReport reports on the calls of one op.
+type OpReport struct {
+	Op    Op  `json:"op"`
+	Calls int `json:"calls"`
+
+	// Errors counts the failed calls by errorClass.
+	Errors map[string]int `json:"errors,omitempty"`
+
+	// Throughput is the number of successful calls a second.
+	Throughput float64 `json:"throughput"`
+
+	// Latency is that of the successful calls.
+	Latency Latency `json:"latency"`
+}
+
+// Latency are latency percentiles.
+type Latency struct {
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+// ErrorCount returns the number of failed calls.
+func (r *OpReport) ErrorCount() int {
+	n := 0
+	for _, count := range r.Errors {
+		n += count
+	}
+
+	return n
+}
+
+// ErrorRate returns the share of calls that failed.
+func (r *OpReport) ErrorRate() float64 {
+	if r.Calls == 0 {
+		return 0
+	}
+
+	return float64(r.ErrorCount()) / float64(r.Calls)
+}
+
+// Op returns the report of op, or nil if it was not called.
+func (r *Report) Op(op Op) *OpReport {
+	if op == total {
+		return r.Total
+	}
+	for _, o := range r.Ops {
+		if o.Op == op {
+			return o
+		}
+	}
+
+	return nil
+}
+
+// Write writes the report as a table.
+func (r *Report) Write(w io.Writer) error {
+	fmt.Fprintf(w, "%s, mix %s, concurrency %d", r.Target, r.Mix, r.Concurrency)
+	if r.Rate > 0 {
+		fmt.Fprintf(w, ", rate %g/s", r.Rate)
+	}
+	fmt.Fprintf(w, ", measured for %s\n\n", r.Duration.Round(time.Millisecond))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "op\tcalls\terrors\tcalls/s\tp50\tp90\tp99\tmax\t")
+	rows := make([]*OpReport, 0, len(r.Ops)+1)
+	rows = append(append(rows, r.Ops...), r.Total)
+	for _, op := range rows {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t\n", op.Op, op.Calls, op.ErrorCount(), op.Throughput,
+			formatLatency(op.Latency.P50), formatLatency(op.Latency.P90), formatLatency(op.Latency.P99), formatLatency(op.Latency.Max))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, op := range r.Ops {
+		if len(op.Errors) > 0 {
+			fmt.Fprintf(w, "\n%s errors: %s", op.Op, formatErrors(op.Errors))
+		}
+	}
+	if r.Missed > 0 {
+		fmt.Fprintf(w, "\n%d calls missed their start, every worker being busy: raise --concurrency or lower --rate", r.Missed)
+	}
+	_, err := fmt.Fprintln(w)
+
+	return err
+}
+
+// Save writes the report as JSON.
+func (r *Report) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(r)
+}
+
+// ReadReport reads a report written by Save.
+func ReadReport(r io.Reader) (*Report, error) {
+	var report Report
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, err
+	}
+	if report.Total == nil {
+		return nil, fmt.Errorf("not a load report: no total")
+	}
+
+	return &report, nil
+}
+
+func formatLatency(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+}
+
+func formatErrors(errors map[string]int) string {
+	classes := make([]string, 0, len(errors))
+	for class := range errors {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	for i, class := range classes {
+		classes[i] = fmt.Sprintf("%s=%d", class, errors[class])
+	}
+
+	return strings.Join(classes, ", ")
+}
+
+// recorder collects the outcome of the calls of one worker.
+type recorder struct {
+	latencies map[Op][]time.Duration
+	errors    map[Op]map[string]int
+}
+
+func newRecorder() *recorder {
+	return &recorder{latencies: make(map[Op][]time.Duration), errors: make(map[Op]map[string]int)}
+}
+
+func (r *recorder) record(op Op, latency time.Duration, err error) {
+	if err == nil {
+		r.latencies[op] = append(r.latencies[op], latency)
+		return
+	}
+
+	if r.errors[op] == nil {
+		r.errors[op] = make(map[string]int)
+	}
+	r.errors[op][errorClass(err)]++
+}
+
+func newReport(opts Options, started time.Time, measured time.Duration, missed int, recorders []*recorder) *Report {
+	report := &Report{
+		Target:      opts.TargetName,
+		Mix:         opts.Mix.String(),
+		Concurrency: opts.Concurrency,
+		Rate:        opts.Rate,
+		Started:     started,
+		Duration:    measured,
+		Missed:      missed,
+	}
+
+	var all []time.Duration
+	allErrors := make(map[string]int)
+	for _, op := range Ops {
+		var latencies []time.Duration
+		errors := make(map[string]int)
+		for _, rec := range recorders {
+			latencies = append(latencies, rec.latencies[op]...)
+			for class, n := range rec.errors[op] {
+				errors[class] += n
+				allErrors[class] += n
+			}
+		}
+		all = append(all, latencies...)
+
+		if opReport := newOpReport(op, latencies, errors, measured); opReport.Calls > 0 {
+			report.Ops = append(report.Ops, opReport)
+		}
+	}
+	report.Total = newOpReport(total, all, allErrors, measured)
+
+	return report
+}
+
+func newOpReport(op Op, latencies []time.Duration, errors map[string]int, measured time.Duration) *OpReport {
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	r := &OpReport{
+		Op:         op,
+		Throughput: float64(len(latencies)) / measured.Seconds(),
+		Latency: Latency{
+			P50: percentile(latencies, 50),
+			P90: percentile(latencies, 90),
+			P99: percentile(latencies, 99),
+			Max: percentile(latencies, 100),
+		},
+	}
+	if len(errors) > 0 {
+		r.Errors = errors
+	}
+	r.Calls = len(latencies) + r.ErrorCount()
+
+	return r
+}
+
+// percentile returns the p-th percentile of sorted by the nearest-rank
+// method, or zero if it is empty.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
@@ -0,0 +1,66 @@
+package load
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 200; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	assert.Equal(t, 100*time.Millisecond, percentile(latencies, 50))
+	assert.Equal(t, 180*time.Millisecond, percentile(latencies, 90))
+	assert.Equal(t, 198*time.Millisecond, percentile(latencies, 99))
+	assert.Equal(t, 200*time.Millisecond, percentile(latencies, 100))
+	assert.Equal(t, time.Millisecond, percentile(latencies[:1], 50))
+	assert.Zero(t, percentile(nil, 50))
+}
+
+func TestReport(t *testing.T) {
+	first, second := newRecorder(), newRecorder()
+	first.record(ListRaces, 2*time.Millisecond, nil)
+	first.record(ListRaces, 4*time.Millisecond, nil)
+	second.record(ListRaces, 3*time.Millisecond, nil)
+	second.record(ListRaces, time.Millisecond, &HTTPError{StatusCode: 503})
+	second.record(GetEvent, 5*time.Millisecond, errors.New("connection refused"))
+
+	report := newReport(Options{TargetName: "rest", Mix: Mix{ListRaces: 1, GetEvent: 1}, Concurrency: 2}, time.Now(), time.Second, 3, []*recorder{first, second})
+
+	list := report.Op(ListRaces)
+	assert.Equal(t, 4, list.Calls)
+	assert.Equal(t, map[string]int{"HTTP 503": 1}, list.Errors)
+	assert.Equal(t, 3.0, list.Throughput)
+	assert.Equal(t, Latency{P50: 3 * time.Millisecond, P90: 4 * time.Millisecond, P99: 4 * time.Millisecond, Max: 4 * time.Millisecond}, list.Latency)
+	assert.Equal(t, 0.25, list.ErrorRate())
+
+	assert.Equal(t, 5, report.Total.Calls)
+	assert.Equal(t, 2, report.Total.ErrorCount())
+	assert.Nil(t, report.Op(GetRace))
+
+	var out bytes.Buffer
+	require.NoError(t, report.Write(&out))
+	assert.Contains(t, out.String(), "list-races")
+	assert.Contains(t, out.String(), "3.00ms")
+	assert.Contains(t, out.String(), "list-races errors: HTTP 503=1")
+	assert.Contains(t, out.String(), "get-event errors: error=1")
+	assert.Contains(t, out.String(), "3 calls missed")
+
+	out.Reset()
+	require.NoError(t, report.Save(&out))
+	saved, err := ReadReport(&out)
+	require.NoError(t, err)
+	assert.Equal(t, report.Ops, saved.Ops)
+	assert.Equal(t, report.Total, saved.Total)
+
+	_, err = ReadReport(strings.NewReader(`{"target": "rest"}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,185 @@
+package load
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+// Options configure a run.
+type Options struct {
+	// Target is what the calls are made to, named TargetName in the report.
+	Target     Target
+	TargetName string
+
+	Mix Mix
+
+	// Concurrency is how many calls are in flight at most.
+	Concurrency int
+
+	// Rate is how many calls are started per second, evenly spaced. A call
+	// due while every worker is busy is skipped and counted as missed. Zero
+	// starts each call as soon as a worker is free.
+	Rate float64
+
+	// Duration is how long calls are measured for, after Warmup, for which
+	// they are made but not measured.
+	Duration time.Duration
+	Warmup   time.Duration
+
+	// Timeout bounds every call, if positive.
+	Timeout time.Duration
+
+	// MaxID and Seed are passed to NewGenerator; each worker generates its
+	// calls from its own seed derived from Seed.
+	MaxID int
+	Seed  int64
+}
+
+// Run makes calls to the target until the duration has passed or ctx is
+// done, whichever is first, and reports on those that were measured.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if opts.Concurrency < 1 {
+		return nil, errors.New("concurrency must be at least 1")
+	}
+	if opts.Duration <= 0 {
+		return nil, errors.New("duration must be positive")
+	}
+	if opts.Rate < 0 {
+		return nil, fmt.Errorf("rate must not be negative, got %g", opts.Rate)
+	}
+
+	started := time.Now()
+	measureFrom := started.Add(opts.Warmup)
+	ctx, cancel := context.WithDeadline(ctx, measureFrom.Add(opts.Duration))
+	defer cancel()
+
+	var starts <-chan struct{}
+	var missed int
+	var pacer sync.WaitGroup
+	if opts.Rate > 0 {
+		ch := make(chan struct{})
+		starts = ch
+
+		pacer.Add(1)
+		go func() {
+			defer pacer.Done()
+			missed = pace(ctx, ch, opts.Rate, measureFrom)
+		}()
+	}
+
+	recorders := make([]*recorder, opts.Concurrency)
+	var workers sync.WaitGroup
+	for i := range recorders {
+		recorders[i] = newRecorder()
+		gen := NewGenerator(opts.Mix, opts.MaxID, opts.Seed+int64(i))
+
+		workers.Add(1)
+		go func(rec *recorder) {
+			defer workers.Done()
+			work(ctx, opts, gen, starts, measureFrom, rec)
+		}(recorders[i])
+	}
+
+	workers.Wait()
+	pacer.Wait()
+
+	measured := time.Since(measureFrom)
+	if measured <= 0 {
+		return nil, ctx.Err()
+	}
+
+	return newReport(opts, started, measured, missed, recorders), nil
+}
+
+// work makes calls until ctx is done, waiting for each to be due on starts
+// unless it is nil, and records those started from measureFrom on.
+func work(ctx context.Context, opts Options, gen *Generator, starts <-chan struct{}, measureFrom time.Time, rec *recorder) {
+	for ctx.Err() == nil {
+		if starts != nil {
+			select {
+			case <-starts:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		call := gen.Next()
+		token, ok := do(ctx, opts, call.Op, call.Request, measureFrom, rec)
+		for page := 0; page < call.Pages && ok && token != ""; page++ {
+			token, ok = do(ctx, opts, call.Op, withPageToken(call.Request, token), measureFrom, rec)
+		}
+	}
+}
+
+// do makes one call and records it, unless the run ended while it was in
+// flight. It reports whether the call succeeded.
+func do(ctx context.Context, opts Options, op Op, req proto.Message, measureFrom time.Time, rec *recorder) (string, bool) {
+	callCtx := ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	token, err := opts.Target.Call(callCtx, op, req)
+	latency := time.Since(start)
+
+	if ctx.Err() != nil {
+		return "", false
+	}
+	if !start.Before(measureFrom) {
+		rec.record(op, latency, err)
+	}
+
+	return token, err == nil
+}
+
+// pace sends on starts rate times a second from now until ctx is done,
+// returning how many sends from measureFrom on found no worker ready.
+func pace(ctx context.Context, starts chan<- struct{}, rate float64, measureFrom time.Time) int {
+	interval := time.Duration(float64(time.Second) / rate)
+	if interval <= 0 {
+		interval = time.Nanosecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return missed
+		case now := <-ticker.C:
+			select {
+			case starts <- struct{}{}:
+			default:
+				if !now.Before(measureFrom) {
+					missed++
+				}
+			}
+		}
+	}
+}
+
+// withPageToken returns a copy of the list request req asking for the page
+// of token.
+func withPageToken(req proto.Message, token string) proto.Message {
+	req = proto.Clone(req)
+	switch req := req.(type) {
+	case *racing.ListRacesRequest:
+		req.PageToken = token
+	case *sports.ListEventsRequest:
+		req.PageToken = token
+	}
+
+	return req
+}
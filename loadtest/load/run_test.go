@@ -0,0 +1,117 @@
+package load
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// fakeTarget answers every call after a delay, failing GetRace calls with
+// NotFound and handing out a next page token to lists on their first page.
+type fakeTarget struct {
+	delay time.Duration
+
+	mu     sync.Mutex
+	calls  map[Op]int
+	tokens int
+}
+
+func (t *fakeTarget) Call(ctx context.Context, op Op, req proto.Message) (string, error) {
+	time.Sleep(t.delay)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls[op]++
+
+	switch op {
+	case GetRace:
+		return "", status.Error(codes.NotFound, "no race")
+	case ListRaces:
+		if req.(*racing.ListRacesRequest).PageToken != "" {
+			t.tokens++
+			return "", nil
+		}
+		return "next", nil
+	}
+
+	return "", nil
+}
+
+func TestRun(t *testing.T) {
+	target := &fakeTarget{delay: time.Millisecond, calls: make(map[Op]int)}
+
+	report, err := Run(context.Background(), Options{
+		Target:      target,
+		TargetName:  "fake",
+		Mix:         Mix{ListRaces: 1, GetRace: 1},
+		Concurrency: 4,
+		Duration:    200 * time.Millisecond,
+		Warmup:      50 * time.Millisecond,
+		MaxID:       10,
+		Seed:        1,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "fake", report.Target)
+	assert.Equal(t, "list-races=1,get-race=1", report.Mix)
+	require.Len(t, report.Ops, 2)
+
+	list, get := report.Op(ListRaces), report.Op(GetRace)
+	assert.Empty(t, list.Errors)
+	assert.GreaterOrEqual(t, list.Latency.P50, time.Millisecond)
+	assert.GreaterOrEqual(t, list.Latency.Max, list.Latency.P99)
+	assert.Greater(t, list.Throughput, 0.0)
+	assert.Equal(t, map[string]int{"NotFound": get.Calls}, get.Errors)
+	assert.Zero(t, get.Throughput)
+
+	assert.Equal(t, list.Calls+get.Calls, report.Total.Calls)
+	assert.Less(t, report.Total.Calls, target.calls[ListRaces]+target.calls[GetRace], "warmup calls are not measured")
+	assert.NotZero(t, target.tokens, "lists follow next page tokens")
+}
+
+func TestRunRate(t *testing.T) {
+	report, err := Run(context.Background(), Options{
+		Target:      &fakeTarget{calls: make(map[Op]int)},
+		Mix:         Mix{GetEvent: 1},
+		Concurrency: 2,
+		Rate:        100,
+		Duration:    500 * time.Millisecond,
+		Seed:        1,
+	})
+	require.NoError(t, err)
+
+	assert.InDelta(t, 50, report.Total.Calls, 15)
+	assert.Zero(t, report.Missed)
+
+	report, err = Run(context.Background(), Options{
+		Target:      &fakeTarget{delay: 50 * time.Millisecond, calls: make(map[Op]int)},
+		Mix:         Mix{GetEvent: 1},
+		Concurrency: 1,
+		Rate:        100,
+		Duration:    300 * time.Millisecond,
+		Seed:        1,
+	})
+	require.NoError(t, err)
+
+	assert.NotZero(t, report.Missed, "calls are missed while the only worker is busy")
+}
+
+func TestRunOptions(t *testing.T) {
+	for name, opts := range map[string]Options{
+		"Concurrency": {Mix: Mix{GetRace: 1}, Duration: time.Second},
+		"Duration":    {Mix: Mix{GetRace: 1}, Concurrency: 1},
+		"Rate":        {Mix: Mix{GetRace: 1}, Concurrency: 1, Duration: time.Second, Rate: -1},
+	} {
+		_, err := Run(context.Background(), opts)
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,180 @@
+package load
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"git.neds.sh/matty/entain/common/auth"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+// Target is what load is driven at.
+type Target interface {
+	// Call makes a call of op with req, one of the requests Call.Request lists,
+	// returning the next page token of lists.
+	Call(ctx context.Context, op Op, req proto.Message) (nextPageToken string, err error)
+}
+
+// GRPCTarget calls the racing and sports services over gRPC.
+type GRPCTarget struct {
+	racing racing.RacingClient
+	sports sports.SportsClient
+	md     metadata.MD
+}
+
+// NewGRPCTarget returns a target calling the services over racingConn and
+// sportsConn. A non-nil principal is forwarded the way the API gateway does,
+// for services enforcing auth.
+func NewGRPCTarget(racingConn, sportsConn grpc.ClientConnInterface, principal *auth.Principal) *GRPCTarget {
+	t := &GRPCTarget{racing: racing.NewRacingClient(racingConn), sports: sports.NewSportsClient(sportsConn)}
+	if principal != nil {
+		t.md = metadata.Pairs(
+			auth.SubjectKey, principal.Subject,
+			auth.ScopesKey, strings.Join(principal.Scopes, " "),
+			auth.MethodKey, principal.Method,
+		)
+	}
+
+	return t
+}
+
+// Call implements Target.
+func (t *GRPCTarget) Call(ctx context.Context, op Op, req proto.Message) (string, error) {
+	if t.md != nil {
+		ctx = metadata.NewOutgoingContext(ctx, t.md)
+	}
+
+	switch op {
+	case ListRaces:
+		resp, err := t.racing.ListRaces(ctx, req.(*racing.ListRacesRequest))
+		if err != nil {
+			return "", err
+		}
+		return resp.NextPageToken, nil
+	case GetRace:
+		_, err := t.racing.GetRace(ctx, req.(*racing.GetRaceRequest))
+		return "", err
+	case ListEvents:
+		resp, err := t.sports.ListEvents(ctx, req.(*sports.ListEventsRequest))
+		if err != nil {
+			return "", err
+		}
+		return resp.NextPageToken, nil
+	case GetEvent:
+		_, err := t.sports.GetEvent(ctx, req.(*sports.GetEventRequest))
+		return "", err
+	}
+
+	return "", fmt.Errorf("unknown op %q", op)
+}
+
+// HTTPError is a response of the API gateway other than 200 OK.
+type HTTPError struct {
+	StatusCode int
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// RESTTarget calls the API gateway.
+type RESTTarget struct {
+	baseURL string
+	client  *http.Client
+	header  http.Header
+}
+
+// NewRESTTarget returns a target calling the gateway at baseURL, e.g.
+// "http://localhost:8000", with client and adding header, e.g. the
+// credentials, to every request.
+func NewRESTTarget(baseURL string, client *http.Client, header http.Header) *RESTTarget {
+	return &RESTTarget{baseURL: strings.TrimSuffix(baseURL, "/"), client: client, header: header}
+}
+
+// Call implements Target.
+func (t *RESTTarget) Call(ctx context.Context, op Op, req proto.Message) (string, error) {
+	switch op {
+	case ListRaces:
+		resp := &racing.ListRacesResponse{}
+		err := t.post(ctx, "/v1/list-races", req, resp)
+		return resp.NextPageToken, err
+	case GetRace:
+		return "", t.post(ctx, fmt.Sprintf("/v1/race/%d", req.(*racing.GetRaceRequest).Id), nil, nil)
+	case ListEvents:
+		resp := &sports.ListEventsResponse{}
+		err := t.post(ctx, "/v1/list-events", req, resp)
+		return resp.NextPageToken, err
+	case GetEvent:
+		return "", t.post(ctx, fmt.Sprintf("/v1/event/%d", req.(*sports.GetEventRequest).Id), nil, nil)
+	}
+
+	return "", fmt.Errorf("unknown op %q", op)
+}
+
+// post posts body, if any, as JSON to path and decodes the response into
+// resp, if any, reading it to the end either way so the connection is reused.
+func (t *RESTTarget) post(ctx context.Context, path string, body, resp proto.Message) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := protojson.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	for name, values := range t.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return &HTTPError{StatusCode: res.StatusCode}
+	}
+	if resp == nil {
+		return nil
+	}
+
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(b, resp)
+}
+
+// errorClass names the kind of err in reports: its HTTP status, its gRPC
+// code, or "error" for anything else, e.g. a refused connection.
+func errorClass(err error) string {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return fmt.Sprintf("HTTP %d", httpErr.StatusCode)
+	}
+	if s, ok := status.FromError(err); ok {
+		return s.Code().String()
+	}
+
+	return "error"
+}
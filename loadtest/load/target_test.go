@@ -0,0 +1,108 @@
+package load
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"git.neds.sh/matty/entain/common/auth"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+func TestRESTTarget(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+		assert.Equal(t, "secret", r.Header.Get("X-API-Key"))
+
+		switch r.URL.Path {
+		case "/v1/list-races":
+			json.NewEncoder(w).Encode(map[string]interface{}{"races": []interface{}{map[string]string{"id": "1", "unknown": "ignored"}}, "nextPageToken": "bzE6MTA"})
+		case "/v1/event/3":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Write([]byte("{}"))
+		}
+	}))
+	defer srv.Close()
+
+	target := NewRESTTarget(srv.URL+"/", srv.Client(), http.Header{"X-Api-Key": {"secret"}})
+
+	token, err := target.Call(context.Background(), ListRaces, &racing.ListRacesRequest{Filter: &racing.ListRacesRequestFilter{MeetingIds: []int64{1}}, PageSize: 10})
+	require.NoError(t, err)
+	assert.Equal(t, "bzE6MTA", token)
+
+	_, err = target.Call(context.Background(), GetRace, &racing.GetRaceRequest{Id: 2})
+	require.NoError(t, err)
+
+	_, err = target.Call(context.Background(), GetEvent, &sports.GetEventRequest{Id: 3})
+	assert.Equal(t, &HTTPError{StatusCode: http.StatusNotFound}, err)
+	assert.Equal(t, "HTTP 404", errorClass(err))
+
+	require.Len(t, requests, 3)
+	assert.JSONEq(t, `{"filter": {"meetingIds": ["1"]}, "pageSize": 10}`, requests[0][len("POST /v1/list-races "):])
+	assert.Equal(t, "POST /v1/race/2 ", requests[1])
+	assert.Equal(t, "POST /v1/event/3 ", requests[2])
+}
+
+type fakeRacing struct {
+	racing.UnimplementedRacingServer
+	md metadata.MD
+}
+
+func (s *fakeRacing) ListRaces(ctx context.Context, in *racing.ListRacesRequest) (*racing.ListRacesResponse, error) {
+	s.md, _ = metadata.FromIncomingContext(ctx)
+	return &racing.ListRacesResponse{NextPageToken: "next"}, nil
+}
+
+type fakeSports struct {
+	sports.UnimplementedSportsServer
+}
+
+func (s *fakeSports) GetEvent(ctx context.Context, in *sports.GetEventRequest) (*sports.Event, error) {
+	return nil, status.Error(codes.NotFound, "no event")
+}
+
+func TestGRPCTarget(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	fr := &fakeRacing{}
+	racing.RegisterRacingServer(srv, fr)
+	sports.RegisterSportsServer(srv, &fakeSports{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	target := NewGRPCTarget(conn, conn, &auth.Principal{Subject: "loadtest", Scopes: []string{"races:read", "sports:read"}, Method: "api_key"})
+
+	token, err := target.Call(context.Background(), ListRaces, &racing.ListRacesRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "next", token)
+	assert.Equal(t, []string{"loadtest"}, fr.md.Get(auth.SubjectKey))
+	assert.Equal(t, []string{"races:read sports:read"}, fr.md.Get(auth.ScopesKey))
+
+	_, err = target.Call(context.Background(), GetEvent, &sports.GetEventRequest{Id: 1})
+	assert.Equal(t, "NotFound", errorClass(err))
+
+	_, err = target.Call(context.Background(), GetRace, &racing.GetRaceRequest{Id: 1})
+	assert.Equal(t, "Unimplemented", errorClass(err))
+}
@@ -0,0 +1,217 @@
+// Command loadtest drives a mix of list and get calls at the racing and
+// sports services, through the API gateway or straight over gRPC, reports
+// throughput and latency percentiles, and compares runs to catch
+// regressions:
+//
+//	loadtest run --target rest --duration 30s --out base.json
+//	loadtest run --target rest --duration 30s --out head.json
+//	loadtest compare base.json head.json
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"git.neds.sh/matty/entain/common/auth"
+	"git.neds.sh/matty/entain/loadtest/load"
+)
+
+// errRegression fails compare when head regressed.
+var errRegression = errors.New("performance regressed")
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("loadtest: %s\n", err)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return usage()
+	}
+
+	switch args[0] {
+	case "run":
+		return runLoad(args[1:], stdout)
+	case "compare":
+		return compare(args[1:], stdout)
+	case "-h", "-help", "--help", "help":
+		usage()
+		return flag.ErrHelp
+	}
+
+	return usage()
+}
+
+func usage() error {
+	fmt.Fprintln(os.Stderr, "usage: loadtest run [flags] | loadtest compare [flags] BASE.json HEAD.json")
+	return errors.New("unknown command")
+}
+
+func runLoad(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("loadtest run", flag.ContinueOnError)
+	target := fs.String("target", "rest", "Where to send calls: rest, to the API gateway, or grpc, straight to the services")
+	apiURL := fs.String("api-url", "http://localhost:8000", "Base URL of the API gateway, for --target rest")
+	apiKey := fs.String("api-key", "", "API key to authenticate to the gateway with, for --target rest")
+	bearerToken := fs.String("bearer-token", "", "JWT to authenticate to the gateway with, for --target rest")
+	racingEndpoint := fs.String("grpc-endpoint-racing", "localhost:9000", "Racing service endpoint, for --target grpc")
+	sportsEndpoint := fs.String("grpc-endpoint-sports", "localhost:9001", "Sports service endpoint, for --target grpc")
+	scopes := fs.String("auth-scopes", "", "Space separated scopes to forward as the gateway would, e.g. \"races:read sports:read\", for --target grpc against services with --auth-enabled")
+	mix := fs.String("mix", load.DefaultMix, "Weighted mix of calls, of "+strings.Join(opNames(), ", "))
+	concurrency := fs.Int("concurrency", 8, "How many calls are in flight at most")
+	rate := fs.Float64("rate", 0, "Calls started per second; 0 starts each as soon as a worker is free")
+	duration := fs.Duration("duration", 30*time.Second, "How long calls are measured for")
+	warmup := fs.Duration("warmup", 5*time.Second, "How long calls are made before being measured")
+	timeout := fs.Duration("timeout", 5*time.Second, "Deadline of every call")
+	maxID := fs.Int("max-id", 100, "Highest race and event ID to get, e.g. how many rows the services were seeded with")
+	seed := fs.Int64("seed", 1, "Seed of the generated calls; the same seed makes up the same calls")
+	out := fs.String("out", "", "File to save the report to as JSON, for compare")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	m, err := load.ParseMix(*mix)
+	if err != nil {
+		return err
+	}
+
+	opts := load.Options{
+		Mix:         m,
+		Concurrency: *concurrency,
+		Rate:        *rate,
+		Duration:    *duration,
+		Warmup:      *warmup,
+		Timeout:     *timeout,
+		MaxID:       *maxID,
+		Seed:        *seed,
+	}
+
+	switch *target {
+	case "rest":
+		header := make(http.Header)
+		if *apiKey != "" {
+			header.Set("X-API-Key", *apiKey)
+		}
+		if *bearerToken != "" {
+			header.Set("Authorization", "Bearer "+*bearerToken)
+		}
+		client := &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency}}
+		opts.Target = load.NewRESTTarget(*apiURL, client, header)
+		opts.TargetName = "rest " + *apiURL
+	case "grpc":
+		racingConn, err := grpc.Dial(*racingEndpoint, grpc.WithInsecure())
+		if err != nil {
+			return err
+		}
+		defer racingConn.Close()
+		sportsConn, err := grpc.Dial(*sportsEndpoint, grpc.WithInsecure())
+		if err != nil {
+			return err
+		}
+		defer sportsConn.Close()
+
+		var principal *auth.Principal
+		if *scopes != "" {
+			principal = &auth.Principal{Subject: "loadtest", Scopes: strings.Fields(*scopes), Method: "api_key"}
+		}
+		opts.Target = load.NewGRPCTarget(racingConn, sportsConn, principal)
+		opts.TargetName = fmt.Sprintf("grpc %s %s", *racingEndpoint, *sportsEndpoint)
+	default:
+		return fmt.Errorf("--target must be rest or grpc, got %q", *target)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	report, err := load.Run(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if err := report.Write(stdout); err != nil {
+		return err
+	}
+	if *out == "" {
+		return nil
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := report.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func compare(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("loadtest compare", flag.ContinueOnError)
+	threshold := fs.Float64("threshold", 0.1, "Relative change beyond which lower throughput or higher latency or error rate is a regression")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return errors.New("compare takes the base and head reports")
+	}
+
+	base, err := readReport(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	head, err := readReport(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	deltas := load.Compare(base, head, *threshold)
+	if err := load.WriteComparison(stdout, deltas); err != nil {
+		return err
+	}
+	if load.Regressed(deltas) {
+		return errRegression
+	}
+
+	return nil
+}
+
+func readReport(path string) (*load.Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	report, err := load.ReadReport(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	return report, nil
+}
+
+func opNames() []string {
+	names := make([]string, len(load.Ops))
+	for i, op := range load.Ops {
+		names[i] = string(op)
+	}
+
+	return names
+}
@@ -1,30 +1,94 @@
 package db
 
 import (
+	"context"
 	"time"
 
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"syreclabs.com/go/faker"
+
+	"git.neds.sh/matty/entain/common/outbox"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// seedCount is how many dummy races repositories are seeded with.
+const seedCount = 100
+
+// Topic and type of the outbox messages recording races being created.
+const (
+	racesTopic  = "racing.races"
+	raceCreated = "race.created"
 )
 
 func (r *racesRepo) seed() error {
-	statement, err := r.db.Prepare(`CREATE TABLE IF NOT EXISTS races (id INTEGER PRIMARY KEY, meeting_id INTEGER, name TEXT, number INTEGER, visible INTEGER, advertised_start_time DATETIME)`)
-	if err == nil {
-		_, err = statement.Exec()
+	ctx := context.Background()
+
+	if _, err := r.db.Exec(`CREATE TABLE IF NOT EXISTS races (id INTEGER PRIMARY KEY, meeting_id INTEGER, name TEXT, number INTEGER, visible INTEGER, advertised_start_time DATETIME)`); err != nil {
+		return err
+	}
+	if err := outbox.Init(ctx, r.db); err != nil {
+		return err
+	}
+
+	// Every race is recorded in the outbox in the same transaction as it is
+	// inserted, so the relay publishes exactly the races that were.
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statement, err := tx.Prepare(`INSERT OR IGNORE INTO races(id, meeting_id, name, number, visible, advertised_start_time) VALUES (?,?,?,?,?,?)`)
+	if err != nil {
+		return err
 	}
+	defer statement.Close()
 
-	for i := 1; i <= 100; i++ {
-		statement, err = r.db.Prepare(`INSERT OR IGNORE INTO races(id, meeting_id, name, number, visible, advertised_start_time) VALUES (?,?,?,?,?,?)`)
-		if err == nil {
-			_, err = statement.Exec(
-				i,
-				faker.Number().Between(1, 10),
-				faker.Team().Name(),
-				faker.Number().Between(1, 12),
-				faker.Number().Between(0, 1),
-				faker.Time().Between(time.Now().AddDate(0, 0, -1), time.Now().AddDate(0, 0, 2)).Format(time.RFC3339),
-			)
+	for i := 1; i <= seedCount; i++ {
+		now := r.clock.Now()
+		race := dummyRace(int64(i), now)
+
+		res, err := statement.Exec(
+			race.Id,
+			race.MeetingId,
+			race.Name,
+			race.Number,
+			race.Visible,
+			race.AdvertisedStartTime.AsTime().Format(time.RFC3339),
+		)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		// A race seeded by an earlier start is left as it is.
+		if n == 0 {
+			continue
+		}
+
+		msg, err := outbox.NewMessage(racesTopic, raceCreated, race.Id, race, now)
+		if err != nil {
+			return err
+		}
+		if err := outbox.Append(ctx, tx, msg); err != nil {
+			return err
 		}
 	}
 
-	return err
+	return tx.Commit()
+}
+
+// dummyRace makes up race id, advertised to start between a day before and
+// two days after now.
+func dummyRace(id int64, now time.Time) *racing.Race {
+	return &racing.Race{
+		Id:                  id,
+		MeetingId:           int64(faker.RandomInt(1, 10)),
+		Name:                faker.Team().Name(),
+		Number:              int64(faker.RandomInt(1, 12)),
+		Visible:             faker.RandomInt(0, 1) == 1,
+		AdvertisedStartTime: timestamppb.New(faker.Time().Between(now.AddDate(0, 0, -1), now.AddDate(0, 0, 2))),
+	}
 }
@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/sqlrepo"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// memoryRacesRepo keeps races in memory, listing and searching them the way
+// racesRepo does without a full-text index.
+type memoryRacesRepo struct {
+	mu    sync.RWMutex
+	races []*racing.Race // ordered by ID
+	init  sync.Once
+	clock clock.Clock
+}
+
+// NewMemoryRacesRepo creates a races repository holding races in memory,
+// nothing of which outlives it. Init adds the dummy races a new database is
+// seeded with, keeping those given here in place of any with the same ID.
+// Only the WithClock option applies.
+func NewMemoryRacesRepo(races []*racing.Race, opts ...Option) RacesRepo {
+	cfg := &racesRepo{clock: clock.Real{}}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	r := &memoryRacesRepo{clock: cfg.clock}
+	for _, race := range races {
+		r.add(race)
+	}
+
+	return r
+}
+
+// Init seeds the repository with dummy races.
+func (r *memoryRacesRepo) Init() error {
+	r.init.Do(func() {
+		now := r.clock.Now()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		for i := 1; i <= seedCount; i++ {
+			r.add(dummyRace(int64(i), now))
+		}
+	})
+
+	return nil
+}
+
+func (r *memoryRacesRepo) List(ctx context.Context, in *racing.ListRacesRequest) ([]*racing.Race, string, error) {
+	now := sqlrepo.Now(r.clock)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	q := sqlrepo.NewMemoryQuery(len(r.races), func(i int, field string) interface{} {
+		return raceField(r.races[i], field)
+	})
+	if err := applyRacesFilter(q, in.Filter, in.OrderBy, now); err != nil {
+		return nil, "", err
+	}
+	if err := q.Page(in.PageSize, in.PageToken); err != nil {
+		return nil, "", err
+	}
+
+	rows, nextPageToken := q.Rows()
+
+	var races []*racing.Race
+	for _, i := range rows {
+		races = append(races, withStatus(r.races[i], now))
+	}
+
+	return races, nextPageToken, nil
+}
+
+func (r *memoryRacesRepo) Get(ctx context.Context, filter *racing.GetRaceRequest) (*racing.Race, error) {
+	if filter == nil {
+		return nil, fmt.Errorf("error: no ID passed")
+	}
+
+	now := sqlrepo.Now(r.clock)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i, ok := r.find(int64(filter.Id))
+	if !ok {
+		return nil, fmt.Errorf("race %d: %w", filter.Id, sqlrepo.ErrNotFound)
+	}
+
+	return withStatus(r.races[i], now), nil
+}
+
+func (r *memoryRacesRepo) Search(ctx context.Context, query string, limit int) ([]*racing.RaceSearchResult, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	now := sqlrepo.Now(r.clock)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	races := make([]*racing.Race, 0, len(r.races))
+	for _, race := range r.races {
+		races = append(races, withStatus(race, now))
+	}
+
+	return rankRaces(races, terms, limit), nil
+}
+
+// add adds a copy of race unless there already is one with its ID.
+func (r *memoryRacesRepo) add(race *racing.Race) {
+	i, ok := r.find(race.Id)
+	if ok {
+		return
+	}
+
+	r.races = append(r.races, nil)
+	copy(r.races[i+1:], r.races[i:])
+	r.races[i] = proto.Clone(race).(*racing.Race)
+}
+
+// find returns the index of the race with the given ID, or where it would be
+// inserted, and whether there is one.
+func (r *memoryRacesRepo) find(id int64) (int, bool) {
+	i := sort.Search(len(r.races), func(i int) bool { return r.races[i].Id >= id })
+	return i, i < len(r.races) && r.races[i].Id == id
+}
+
+// raceField returns the value of a field of racesFilterSchema.
+func raceField(race *racing.Race, field string) interface{} {
+	switch field {
+	case "id":
+		return race.Id
+	case "meeting_id":
+		return race.MeetingId
+	case "name":
+		return race.Name
+	case "number":
+		return race.Number
+	case "visible":
+		return race.Visible
+	case "advertised_start_time":
+		return race.AdvertisedStartTime.AsTime()
+	}
+
+	panic("db: unknown race field " + field)
+}
+
+// withStatus returns a copy of race with the status derived at now.
+func withStatus(race *racing.Race, now time.Time) *racing.Race {
+	race = proto.Clone(race).(*racing.Race)
+	race.Status = sqlrepo.Status(race.AdvertisedStartTime.AsTime(), now)
+
+	return race
+}
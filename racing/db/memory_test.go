@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/sqlrepo"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+func TestMemoryRacesRepo(t *testing.T) {
+	clk := clock.NewFake(now)
+	repo := NewMemoryRacesRepo([]*racing.Race{
+		{Id: 7, MeetingId: 3, Name: "Rhode Island Red", Number: 4, Visible: true, AdvertisedStartTime: timestamppb.New(now.Add(time.Minute))},
+		{Id: 200, MeetingId: 3, Name: "Zanzibar Quokka Derby", Number: 5, AdvertisedStartTime: timestamppb.New(now.Add(-time.Minute))},
+	}, WithClock(clk))
+	require.NoError(t, repo.Init())
+
+	t.Run("SeedKeepsGivenRaces", func(t *testing.T) {
+		races, _, err := repo.List(context.Background(), &racing.ListRacesRequest{})
+		require.NoError(t, err)
+		assert.Len(t, races, seedCount+1)
+
+		race, err := repo.Get(context.Background(), &racing.GetRaceRequest{Id: 7})
+		require.NoError(t, err)
+		assert.Equal(t, "Rhode Island Red", race.Name)
+		assert.Equal(t, sqlrepo.Open, race.Status)
+	})
+
+	t.Run("StatusFollowsClock", func(t *testing.T) {
+		clk.Advance(time.Hour)
+		defer clk.Set(now)
+
+		race, err := repo.Get(context.Background(), &racing.GetRaceRequest{Id: 7})
+		require.NoError(t, err)
+		assert.Equal(t, sqlrepo.Closed, race.Status)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := repo.Get(context.Background(), &racing.GetRaceRequest{Id: 999})
+		assert.ErrorIs(t, err, sqlrepo.ErrNotFound)
+	})
+
+	t.Run("ReturnsCopies", func(t *testing.T) {
+		race, err := repo.Get(context.Background(), &racing.GetRaceRequest{Id: 7})
+		require.NoError(t, err)
+		race.Name = "changed"
+
+		race, err = repo.Get(context.Background(), &racing.GetRaceRequest{Id: 7})
+		require.NoError(t, err)
+		assert.Equal(t, "Rhode Island Red", race.Name)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		results, err := repo.Search(context.Background(), "quok zanz", 0)
+		require.NoError(t, err)
+		require.NotEmpty(t, results)
+
+		assert.Equal(t, int64(200), results[0].Race.Id)
+		assert.Equal(t, sqlrepo.Closed, results[0].Race.Status)
+		assert.Equal(t, map[string]string{"name": "<mark>Zanzibar</mark> <mark>Quokka</mark> Derby"}, results[0].Highlights)
+	})
+}
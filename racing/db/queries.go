@@ -1,7 +1,8 @@
 package db
 
 const (
-	racesList = "list"
+	racesList   = "list"
+	racesSearch = "search"
 )
 
 func getRaceQueries() map[string]string {
@@ -16,5 +17,21 @@ func getRaceQueries() map[string]string {
 				advertised_start_time 
 			FROM races
 		`,
+		racesSearch: `
+			SELECT 
+				races.id, 
+				races.meeting_id, 
+				races.name, 
+				races.number, 
+				races.visible, 
+				races.advertised_start_time, 
+				-bm25(races_fts), 
+				highlight(races_fts, 0, '<mark>', '</mark>') 
+			FROM races_fts 
+			JOIN races ON races.id = races_fts.rowid 
+			WHERE races_fts MATCH ? 
+			ORDER BY bm25(races_fts), races.id 
+			LIMIT ?
+		`,
 	}
 }
@@ -1,16 +1,18 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/golang/protobuf/ptypes"
 	_ "github.com/mattn/go-sqlite3"
 
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/filtering"
+	"git.neds.sh/matty/entain/common/sqlrepo"
 	"git.neds.sh/matty/entain/racing/proto/racing"
 )
 
@@ -19,21 +21,79 @@ type RacesRepo interface {
 	// Init will initialise our races repository.
 	Init() error
 
-	// List will return a list of races.
-	List(filter *racing.ListRacesRequestFilter) ([]*racing.Race, error)
+	// List will return a page of the races matching the request's filter,
+	// sorted by its AIP-132 order_by expression, and the token of the next
+	// page. An invalid order_by gives an *sqlrepo.OrderByError, an invalid
+	// filter expression a *filtering.Error and an invalid page token
+	// sqlrepo.ErrInvalidPageToken.
+	List(ctx context.Context, in *racing.ListRacesRequest) ([]*racing.Race, string, error)
 
-	// Get will return a single race based on the given ID.
-	Get(filter *racing.GetRaceRequest) (*racing.Race, error)
+	// Get will return a single race based on the given ID, or an error
+	// wrapping sqlrepo.ErrNotFound if there is none.
+	Get(ctx context.Context, filter *racing.GetRaceRequest) (*racing.Race, error)
+
+	// Search will return the races whose name matches query, best matches
+	// first, up to limit of them (20 if limit is not positive).
+	Search(ctx context.Context, query string, limit int) ([]*racing.RaceSearchResult, error)
+}
+
+// racesOrderColumns whitelists the fields races can be sorted by.
+// Start times are compared as datetime() so ones stored with different UTC
+// offsets sort chronologically.
+var racesOrderColumns = map[string]string{
+	"id":                    "id",
+	"meeting_id":            "meeting_id",
+	"name":                  "name",
+	"number":                "number",
+	"visible":               "visible",
+	"advertised_start_time": filtering.TimeColumn("advertised_start_time"),
+}
+
+// racesFilterSchema lists the fields filter expressions can refer to.
+var racesFilterSchema = filtering.Schema{
+	"id":                    {Column: "id", Type: filtering.Int},
+	"meeting_id":            {Column: "meeting_id", Type: filtering.Int},
+	"name":                  {Column: "name", Type: filtering.String},
+	"number":                {Column: "number", Type: filtering.Int},
+	"visible":               {Column: "visible", Type: filtering.Bool},
+	"advertised_start_time": {Column: "advertised_start_time", Type: filtering.Timestamp},
 }
 
 type racesRepo struct {
-	db   *sql.DB
-	init sync.Once
+	db                 *sql.DB
+	init               sync.Once
+	fts                bool
+	slowQueryThreshold time.Duration
+	clock              clock.Clock
+}
+
+// Option configures optional behaviour of the races repository.
+type Option func(*racesRepo)
+
+// WithSlowQueryThreshold makes the repository log a warning for every query
+// taking longer than d. A zero threshold disables the warning.
+func WithSlowQueryThreshold(d time.Duration) Option {
+	return func(r *racesRepo) {
+		r.slowQueryThreshold = d
+	}
+}
+
+// WithClock makes the repository take the time from c, e.g. when deriving
+// race statuses, instead of the system clock.
+func WithClock(c clock.Clock) Option {
+	return func(r *racesRepo) {
+		r.clock = c
+	}
 }
 
 // NewRacesRepo creates a new races repository.
-func NewRacesRepo(db *sql.DB) RacesRepo {
-	return &racesRepo{db: db}
+func NewRacesRepo(db *sql.DB, opts ...Option) RacesRepo {
+	r := &racesRepo{db: db, clock: clock.Real{}}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
 }
 
 // Init prepares the race repository dummy data.
@@ -41,109 +101,154 @@ func (r *racesRepo) Init() error {
 	var err error
 
 	r.init.Do(func() {
+		// The full-text index is rebuilt once seeded, so its triggers can go
+		// until then; left by a build with FTS5, they would fail the seeding.
+		if err = racesIndex.DropTriggers(r.db); err != nil {
+			return
+		}
+
 		// For test/example purposes, we seed the DB with some dummy races.
-		err = r.seed()
+		if err = r.seed(); err != nil {
+			return
+		}
+		r.fts, err = racesIndex.Init(r.db)
 	})
 
 	return err
 }
 
-func (r *racesRepo) List(filter *racing.ListRacesRequestFilter) ([]*racing.Race, error) {
-	var (
-		err   error
-		query string
-		args  []interface{}
-	)
-
-	query = getRaceQueries()[racesList]
+func (r *racesRepo) List(ctx context.Context, in *racing.ListRacesRequest) ([]*racing.Race, string, error) {
+	now := sqlrepo.Now(r.clock)
+	q, err := r.listQuery(in.Filter, in.OrderBy, now)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := q.Page(in.PageSize, in.PageToken); err != nil {
+		return nil, "", err
+	}
 
-	query, args = r.applyFilter(query, filter)
+	query, args := q.SQL()
+	rows, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
 
-	rows, err := r.db.Query(query, args...)
+	races, err := r.scanRaces(rows, now)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return r.scanRaces(rows)
+	n, nextPageToken := q.NextPageToken(len(races))
+	return races[:n], nextPageToken, nil
 }
 
-func (r *racesRepo) Get(filter *racing.GetRaceRequest) (*racing.Race, error) {
-	var (
-		err   error
-		query string
-	)
-
-	query = getRaceQueries()[racesList]
-
+func (r *racesRepo) Get(ctx context.Context, filter *racing.GetRaceRequest) (*racing.Race, error) {
 	if filter == nil {
 		return nil, fmt.Errorf("error: no ID passed")
 	}
-	if filter != nil {
-		// apply the ID filter
-		query += " WHERE id=" + strconv.Itoa(int(filter.Id))
-	}
 
-	rows, err := r.db.Query(query, nil)
+	q := sqlrepo.NewListQuery(getRaceQueries()[racesList])
+	q.Where("id = ?", filter.Id)
+
+	query, args := q.SQL()
+	rows, err := r.query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	races, err := r.scanRaces(rows)
+	races, err := r.scanRaces(rows, sqlrepo.Now(r.clock))
 	if err != nil {
 		return nil, err
 	}
+	if len(races) == 0 {
+		return nil, fmt.Errorf("race %d: %w", filter.Id, sqlrepo.ErrNotFound)
+	}
 
-	// since we are expecting only 1 row, so choose the first one
 	return races[0], nil
 }
 
-func (r *racesRepo) applyFilter(query string, filter *racing.ListRacesRequestFilter) (string, []interface{}) {
-	var (
-		clauses []string
-		args    []interface{}
-	)
+// query runs the given query, logging a warning with the request's log fields
+// if it took longer than the configured slow query threshold.
+func (r *racesRepo) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return sqlrepo.Query(ctx, r.db, r.slowQueryThreshold, query, args...)
+}
 
-	if filter == nil {
-		return query, args
+// listQuery builds the query listing the races matching filter in the
+// requested order. now is the time relative filters are evaluated at.
+func (r *racesRepo) listQuery(filter *racing.ListRacesRequestFilter, order string, now time.Time) (*sqlrepo.ListQuery, error) {
+	q := sqlrepo.NewListQuery(getRaceQueries()[racesList])
+	if err := applyRacesFilter(q, filter, order, now); err != nil {
+		return nil, err
 	}
 
-	if len(filter.MeetingIds) > 0 {
-		clauses = append(clauses, "meeting_id IN ("+strings.Repeat("?,", len(filter.MeetingIds)-1)+"?)")
+	return q, nil
+}
 
-		for _, meetingID := range filter.MeetingIds {
-			args = append(args, meetingID)
-		}
+// applyRacesFilter restricts q to the races matching filter and sorts them in
+// the requested order, the same way for every repository. now is the time
+// relative filters are evaluated at.
+func applyRacesFilter(q sqlrepo.ListBuilder, filter *racing.ListRacesRequestFilter, order string, now time.Time) error {
+	if filter == nil {
+		filter = &racing.ListRacesRequestFilter{}
 	}
 
+	q.In("meeting_id", filter.MeetingIds)
+
 	if filter.Visibility == racing.ListRacesRequestFilter_VISIBILE {
-		clauses = append(clauses, "visible = true")
+		q.Equal("visible", true)
 	} else if filter.Visibility == racing.ListRacesRequestFilter_HIDDEN {
-		clauses = append(clauses, "visible = false")
+		q.Equal("visible", false)
 	}
 
-	if len(clauses) != 0 {
-		query += " WHERE " + strings.Join(clauses, " AND ")
+	// time windows and statuses compare advertised_start_time in the query,
+	// so they are applied before any page limit
+	if filter.StartTimeFrom != nil {
+		q.From("advertised_start_time", filter.StartTimeFrom.AsTime())
+	}
+	if filter.StartTimeTo != nil {
+		q.Until("advertised_start_time", filter.StartTimeTo.AsTime())
+	}
+	if filter.StartsWithin != nil {
+		q.From("advertised_start_time", now)
+		q.Until("advertised_start_time", now.Add(filter.StartsWithin.AsDuration()))
+	}
+	if filter.Status != racing.ListRacesRequestFilter_RACE_STATUS_UNSPECIFIED {
+		q.Status("advertised_start_time", filter.Status.String(), now)
 	}
 
-	// add sort and order clause in the end
-	if filter.SortBy != "" {
-		// check for valid fields
-		if filter.SortBy == "advertised_start_time" || filter.SortBy == "number" ||
-			filter.SortBy == "meeting_id" || filter.SortBy == "name" {
-			sortCondition := " ORDER BY " + filter.SortBy
-			query += sortCondition
+	if err := q.Filter(filter.Expression, racesFilterSchema); err != nil {
+		return err
+	}
 
-			if filter.OrderBy == 1 {
-				query += " DESC"
-			} // note - we don't need to check for 0 since by default the sort order is ASC
-		}
+	// sort in the end, falling back to the deprecated sort_by/order_by filter
+	// fields
+	if order == "" {
+		order = legacyRacesOrder(filter)
+	}
+
+	return q.OrderBy(order, racesOrderColumns)
+}
+
+// legacyRacesOrder translates the deprecated sort_by/order_by filter fields
+// into an order_by expression. Unknown sort_by values are ignored, as they
+// always have been.
+func legacyRacesOrder(filter *racing.ListRacesRequestFilter) string {
+	switch filter.SortBy {
+	case "advertised_start_time", "number", "meeting_id", "name":
+	default:
+		return ""
 	}
 
-	return query, args
+	if filter.OrderBy == 1 {
+		return filter.SortBy + " desc"
+	}
+
+	return filter.SortBy
 }
 
 func (m *racesRepo) scanRaces(
 	rows *sql.Rows,
+	now time.Time,
 ) ([]*racing.Race, error) {
 	var races []*racing.Race
 
@@ -165,11 +270,7 @@ func (m *racesRepo) scanRaces(
 		}
 
 		race.AdvertisedStartTime = ts
-		if race.AdvertisedStartTime.AsTime().Before(time.Now()) {
-			race.Status = "CLOSED"
-		} else {
-			race.Status = "OPEN"
-		}
+		race.Status = sqlrepo.Status(advertisedStart, now)
 
 		races = append(races, &race)
 	}
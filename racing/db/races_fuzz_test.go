@@ -0,0 +1,269 @@
+package db
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/filtering"
+	"git.neds.sh/matty/entain/common/sqlrepo"
+	"git.neds.sh/matty/entain/common/sqlrepo/sqlrepotest"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// fuzzRace is a race of the fuzzed repository, with its start time in the
+// zone it is stored in.
+type fuzzRace struct {
+	id, meetingID, number int64
+	name                  string
+	visible               bool
+	start                 time.Time
+}
+
+func (r fuzzRace) field(name string) interface{} {
+	switch name {
+	case "id":
+		return r.id
+	case "meeting_id":
+		return r.meetingID
+	case "name":
+		return r.name
+	case "number":
+		return r.number
+	case "visible":
+		return r.visible
+	case "advertised_start_time":
+		return r.start
+	}
+
+	panic("unknown field " + name)
+}
+
+// fuzzRaces returns races with plenty of ties to sort, awkward names and
+// start times stored with different UTC offsets.
+func fuzzRaces() []fuzzRace {
+	rng := rand.New(rand.NewSource(1))
+	names := []string{"Flemington Cup", "flemington cup", "Randwick Plate", `The "Big" One`, `Back\slash`, "O'Brien Stakes", "Überraschung", ""}
+	zones := []*time.Location{time.UTC, time.FixedZone("AEST", 10*60*60), time.FixedZone("EST", -5*60*60)}
+
+	races := make([]fuzzRace, 40)
+	for i := range races {
+		races[i] = fuzzRace{
+			id:        int64(i + 1),
+			meetingID: int64(rng.Intn(5) + 1),
+			name:      names[rng.Intn(len(names))],
+			number:    int64(rng.Intn(4) + 1),
+			visible:   rng.Intn(2) == 1,
+			// every half hour for two days either side of now, stored to the
+			// second
+			start: now.Truncate(time.Second).Add(time.Duration(rng.Intn(193)-96) * 30 * time.Minute).In(zones[rng.Intn(len(zones))]),
+		}
+	}
+
+	return races
+}
+
+// openFuzzRaces returns a SQLite races repository holding races, with the
+// fuzz corpus seeded.
+func openFuzzRaces(f *testing.F, races []fuzzRace) RacesRepo {
+	db := sqlrepotest.OpenSQLite(f)
+	repo := NewRacesRepo(db, WithClock(clock.NewFake(now)))
+	require.NoError(f, repo.Init())
+	_, err := db.Exec(`DELETE FROM races`)
+	require.NoError(f, err)
+	for _, r := range races {
+		_, err := db.Exec(`INSERT INTO races(id, meeting_id, name, number, visible, advertised_start_time) VALUES (?,?,?,?,?,?)`,
+			r.id, r.meetingID, r.name, r.number, r.visible, r.start.Format(time.RFC3339))
+		require.NoError(f, err)
+	}
+
+	f.Add([]byte{}, uint8(0), uint8(0), int32(0), int32(0), uint32(0), "", "", uint8(0), false, uint8(0))
+	f.Add([]byte{1, 3}, uint8(1), uint8(1), int32(0), int32(0), uint32(0), "", "advertised_start_time desc", uint8(0), false, uint8(3))
+	f.Add([]byte{}, uint8(2), uint8(2), int32(-86400), int32(3600), uint32(0), `number > 2 OR name = "The \"Big\" One"`, "name, number desc", uint8(0), false, uint8(4))
+	f.Add([]byte{2}, uint8(0), uint8(0), int32(0), int32(0), uint32(7200), `NOT visible = true AND advertised_start_time >= "2026-10-18T20:00:00+10:00"`, "", uint8(1), true, uint8(1))
+	f.Add([]byte{4, 5}, uint8(0), uint8(0), int32(0), int32(0), uint32(0), `name IN ("Back\\slash", "O'Brien Stakes") meeting_id != 4`, "", uint8(3), false, uint8(2))
+	f.Add([]byte{}, uint8(0), uint8(0), int32(0), int32(0), uint32(0), `advertised_start_time < "9999-12-31T23:00:00-02:00"`, "", uint8(5), false, uint8(0))
+	f.Add([]byte{}, uint8(0), uint8(0), int32(0), int32(0), uint32(0), `venue = 1`, "venue", uint8(0), false, uint8(0))
+	f.Add([]byte{}, uint8(0), uint8(0), int32(0), int32(0), uint32(0), "", "number asc, number", uint8(0), false, uint8(0))
+
+	return repo
+}
+
+var legacySortBy = []string{"advertised_start_time", "number", "meeting_id", "name", "visible", ""}
+
+// fuzzRacesRequest builds the list request the fuzzed arguments describe.
+func fuzzRacesRequest(meetingIDs []byte, visibility, status uint8, from, to int32, within uint32, expression, order string, sortBy uint8, desc bool, pageSize uint8) *racing.ListRacesRequest {
+	filter := &racing.ListRacesRequestFilter{
+		Visibility: racing.ListRacesRequestFilter_STATUS(visibility % 3),
+		SortBy:     legacySortBy[int(sortBy)%len(legacySortBy)],
+		Expression: expression,
+		Status:     racing.ListRacesRequestFilter_RaceStatus(status % 3),
+	}
+	for _, id := range meetingIDs {
+		filter.MeetingIds = append(filter.MeetingIds, int64(id%6))
+	}
+	if desc {
+		filter.OrderBy = 1
+	}
+	if from != 0 {
+		filter.StartTimeFrom = timestamppb.New(now.Add(time.Duration(from) * time.Second))
+	}
+	if to != 0 {
+		filter.StartTimeTo = timestamppb.New(now.Add(time.Duration(to) * time.Second))
+	}
+	if within != 0 {
+		filter.StartsWithin = durationpb.New(time.Duration(within) * time.Second)
+	}
+
+	return &racing.ListRacesRequest{Filter: filter, OrderBy: order, PageSize: int32(pageSize % 8)}
+}
+
+// FuzzList checks the races listed from SQLite against a reference
+// implementation filtering and sorting fuzzRaces in memory.
+func FuzzList(f *testing.F) {
+	races := fuzzRaces()
+	repo := openFuzzRaces(f, races)
+
+	f.Fuzz(func(t *testing.T, meetingIDs []byte, visibility, status uint8, from, to int32, within uint32, expression, order string, sortBy uint8, desc bool, pageSize uint8) {
+		req := fuzzRacesRequest(meetingIDs, visibility, status, from, to, within, expression, order, sortBy, desc, pageSize)
+		want, wantErr := referenceList(races, req.Filter, order)
+
+		var got []int64
+		for page := 0; page <= len(races); page++ {
+			list, token, err := repo.List(context.Background(), req)
+			if wantErr != nil {
+				require.IsType(t, wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+
+			for _, race := range list {
+				r := races[race.Id-1]
+				assert.Equal(t, r.meetingID, race.MeetingId)
+				assert.Equal(t, r.name, race.Name)
+				assert.True(t, r.start.Equal(race.AdvertisedStartTime.AsTime()))
+				assert.Equal(t, sqlrepo.Status(r.start, now.Truncate(time.Second)), race.Status)
+				got = append(got, race.Id)
+			}
+
+			if token == "" {
+				break
+			}
+			req.PageToken = token
+		}
+
+		assert.Equal(t, want, got)
+	})
+}
+
+// FuzzListMemory checks the in-memory repository lists the same races, page
+// for page, as SQLite does from the same fuzzRaces.
+func FuzzListMemory(f *testing.F) {
+	races := fuzzRaces()
+	repo := openFuzzRaces(f, races)
+
+	var protos []*racing.Race
+	for _, r := range races {
+		protos = append(protos, &racing.Race{Id: r.id, MeetingId: r.meetingID, Name: r.name, Number: r.number, Visible: r.visible, AdvertisedStartTime: timestamppb.New(r.start)})
+	}
+	memory := NewMemoryRacesRepo(protos, WithClock(clock.NewFake(now)))
+
+	f.Fuzz(func(t *testing.T, meetingIDs []byte, visibility, status uint8, from, to int32, within uint32, expression, order string, sortBy uint8, desc bool, pageSize uint8) {
+		req := fuzzRacesRequest(meetingIDs, visibility, status, from, to, within, expression, order, sortBy, desc, pageSize)
+
+		want, wantErr := listAll(repo, req)
+		got, err := listAll(memory, req)
+		if wantErr != nil {
+			require.IsType(t, wantErr, err)
+			return
+		}
+		require.NoError(t, err)
+
+		require.Equal(t, raceIDs(want), raceIDs(got))
+		for i := range want {
+			assert.True(t, proto.Equal(want[i], got[i]), "want %v, got %v", want[i], got[i])
+		}
+	})
+}
+
+// referenceList returns the IDs of the races matching filter in the
+// requested order, or the error List should give.
+func referenceList(races []fuzzRace, filter *racing.ListRacesRequestFilter, order string) ([]int64, error) {
+	expr, err := filtering.Parse(filter.Expression, racesFilterSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	meetings := make(map[int64]bool)
+	for _, id := range filter.MeetingIds {
+		meetings[id] = true
+	}
+
+	var rows []sqlrepotest.Row
+	for _, r := range races {
+		start := r.start.Unix()
+		switch {
+		case len(meetings) != 0 && !meetings[r.meetingID]:
+		case filter.Visibility == racing.ListRacesRequestFilter_VISIBILE && !r.visible:
+		case filter.Visibility == racing.ListRacesRequestFilter_HIDDEN && r.visible:
+		case filter.StartTimeFrom != nil && start < filter.StartTimeFrom.AsTime().Unix():
+		case filter.StartTimeTo != nil && start >= filter.StartTimeTo.AsTime().Unix():
+		case filter.StartsWithin != nil && (start < now.Unix() || start >= now.Add(filter.StartsWithin.AsDuration()).Unix()):
+		case filter.Status == racing.ListRacesRequestFilter_OPEN && start < now.Unix():
+		case filter.Status == racing.ListRacesRequestFilter_CLOSED && start >= now.Unix():
+		case !sqlrepotest.Match(expr, r.field):
+		default:
+			rows = append(rows, r.field)
+		}
+	}
+
+	if order == "" {
+		order = legacyRacesOrder(filter)
+	}
+	if !sqlrepotest.Sort(rows, order, racesOrderColumns) {
+		return nil, &sqlrepo.OrderByError{}
+	}
+
+	var ids []int64
+	for _, row := range rows {
+		ids = append(ids, row("id").(int64))
+	}
+
+	return ids, nil
+}
+
+// listAll lists the races of every page of req.
+func listAll(repo RacesRepo, req *racing.ListRacesRequest) ([]*racing.Race, error) {
+	req = proto.Clone(req).(*racing.ListRacesRequest)
+
+	var all []*racing.Race
+	for {
+		races, token, err := repo.List(context.Background(), req)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, races...)
+
+		if token == "" {
+			return all, nil
+		}
+		req.PageToken = token
+	}
+}
+
+func raceIDs(races []*racing.Race) []int64 {
+	var ids []int64
+	for _, race := range races {
+		ids = append(ids, race.Id)
+	}
+
+	return ids
+}
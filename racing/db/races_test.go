@@ -1,26 +1,36 @@
 package db
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/stretchr/testify/assert"
-
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/filtering"
+	"git.neds.sh/matty/entain/common/outbox"
+	"git.neds.sh/matty/entain/common/sqlrepo"
+	"git.neds.sh/matty/entain/common/sqlrepo/sqlrepotest"
 	"git.neds.sh/matty/entain/racing/proto/racing"
 )
 
+// now is when the tests run, as far as the repository can tell.
+var now = time.Date(2026, time.October, 18, 12, 0, 0, 500_000_000, time.UTC)
+
 func TestGetRaceClosed(t *testing.T) {
 	// Set up the mock database and get a mock database connection
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("Error creating mock database: %v", err)
-	}
-	defer db.Close()
+	db, mock := sqlrepotest.NewMock(t)
 
-	repo := &racesRepo{db: db}
-	expectedTime := time.Date(2022, time.January, 1, 12, 0, 0, 0, time.UTC)
+	repo := &racesRepo{db: db, clock: clock.NewFake(now)}
+	expectedTime := now.Add(-time.Minute)
 
 	expectedPTime, _ := ptypes.TimestampProto(expectedTime)
 	t.Run("GetRaceByID", func(t *testing.T) {
@@ -44,13 +54,13 @@ func TestGetRaceClosed(t *testing.T) {
 				expectedTime,
 			)
 
-		mock.ExpectQuery("SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races WHERE id=1").WillReturnRows(rows)
+		mock.ExpectQuery("SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races WHERE id = \\?").WithArgs(1).WillReturnRows(rows)
 
 		filter := &racing.GetRaceRequest{
 			Id: int32(expectedRace.Id),
 		}
 
-		resultRace, err := repo.Get(filter)
+		resultRace, err := repo.Get(context.Background(), filter)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, resultRace)
@@ -58,21 +68,14 @@ func TestGetRaceClosed(t *testing.T) {
 	})
 
 	// Add more test cases as needed
-
-	// Assert that the expected queries were executed
-	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestGetRaceOpen(t *testing.T) {
 	// Set up the mock database and get a mock database connection
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("Error creating mock database: %v", err)
-	}
-	defer db.Close()
+	db, mock := sqlrepotest.NewMock(t)
 
-	repo := &racesRepo{db: db}
-	expectedTime := time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+	repo := &racesRepo{db: db, clock: clock.NewFake(now)}
+	expectedTime := now.Add(time.Minute)
 
 	expectedPTime, _ := ptypes.TimestampProto(expectedTime)
 	t.Run("GetRaceByID", func(t *testing.T) {
@@ -96,13 +99,13 @@ func TestGetRaceOpen(t *testing.T) {
 				expectedTime,
 			)
 
-		mock.ExpectQuery("SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races WHERE id=1").WillReturnRows(rows)
+		mock.ExpectQuery("SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races WHERE id = \\?").WithArgs(1).WillReturnRows(rows)
 
 		filter := &racing.GetRaceRequest{
 			Id: int32(expectedRace.Id),
 		}
 
-		resultRace, err := repo.Get(filter)
+		resultRace, err := repo.Get(context.Background(), filter)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, resultRace)
@@ -110,22 +113,20 @@ func TestGetRaceOpen(t *testing.T) {
 	})
 
 	// Add more test cases as needed
-
-	// Assert that the expected queries were executed
-	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestApplyFilter(t *testing.T) {
+func TestListQuery(t *testing.T) {
 	tests := []struct {
-		name   string
-		filter *racing.ListRacesRequestFilter
-		query  string
-		args   []interface{}
+		name    string
+		filter  *racing.ListRacesRequestFilter
+		orderBy string
+		query   string
+		args    []interface{}
 	}{
 		{
 			name:   "NoFilter",
 			filter: nil,
-			query:  "SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races",
+			query:  "SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races ORDER BY id",
 			args:   nil,
 		},
 		{
@@ -133,7 +134,7 @@ func TestApplyFilter(t *testing.T) {
 			filter: &racing.ListRacesRequestFilter{
 				MeetingIds: []int64{1, 2, 3},
 			},
-			query: "SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races WHERE meeting_id IN (?,?,?)",
+			query: "SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races WHERE meeting_id IN (?,?,?) ORDER BY id",
 			args:  []interface{}{int64(1), int64(2), int64(3)},
 		},
 		{
@@ -142,7 +143,7 @@ func TestApplyFilter(t *testing.T) {
 				SortBy:  "number",
 				OrderBy: 1,
 			},
-			query: "SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races ORDER BY number DESC",
+			query: "SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races ORDER BY number DESC, id",
 			args:  nil,
 		},
 		{
@@ -150,7 +151,7 @@ func TestApplyFilter(t *testing.T) {
 			filter: &racing.ListRacesRequestFilter{
 				SortBy: "invalid_field",
 			},
-			query: "SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races",
+			query: "SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races ORDER BY id",
 			args:  nil,
 		},
 		{
@@ -158,15 +159,70 @@ func TestApplyFilter(t *testing.T) {
 			filter: &racing.ListRacesRequestFilter{
 				SortBy: "name",
 			},
-			query: "SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races ORDER BY name",
+			query: "SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races ORDER BY name, id",
 			args:  nil,
 		},
+		{
+			name: "FilterExpression",
+			filter: &racing.ListRacesRequestFilter{
+				Visibility: racing.ListRacesRequestFilter_VISIBILE,
+				Expression: `advertised_start_time > "2026-10-18T00:00:00Z" AND (meeting_id IN (1, 2) OR name = "Flemington")`,
+			},
+			query: "SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races WHERE visible = ? AND (datetime(advertised_start_time) > ? AND (meeting_id IN (?,?) OR name = ?)) ORDER BY id",
+			args:  []interface{}{true, "2026-10-18 00:00:00", int64(1), int64(2), "Flemington"},
+		},
+		{
+			name: "StartTimeWindow",
+			filter: &racing.ListRacesRequestFilter{
+				StartTimeFrom: timestamppb.New(time.Date(2026, time.October, 18, 10, 0, 0, 0, time.FixedZone("AEST", 10*60*60))),
+				StartTimeTo:   timestamppb.New(time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)),
+			},
+			query: "SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races WHERE datetime(advertised_start_time) >= ? AND datetime(advertised_start_time) < ? ORDER BY id",
+			args:  []interface{}{"2026-10-18 00:00:00", "2026-10-19 00:00:00"},
+		},
+		{
+			name: "StartsWithinOpen",
+			filter: &racing.ListRacesRequestFilter{
+				StartsWithin: durationpb.New(time.Hour),
+				Status:       racing.ListRacesRequestFilter_OPEN,
+			},
+			query: "SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races WHERE datetime(advertised_start_time) >= ? AND datetime(advertised_start_time) < ? AND datetime(advertised_start_time) >= ? ORDER BY id",
+			args:  []interface{}{"2026-10-18 12:00:00", "2026-10-18 13:00:00", "2026-10-18 12:00:00"},
+		},
+		{
+			name: "StatusClosed",
+			filter: &racing.ListRacesRequestFilter{
+				Status: racing.ListRacesRequestFilter_CLOSED,
+			},
+			query: "SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races WHERE datetime(advertised_start_time) < ? ORDER BY id",
+			args:  []interface{}{"2026-10-18 12:00:00"},
+		},
+		{
+			name:    "OrderByMultipleFields",
+			orderBy: "advertised_start_time desc, number",
+			query:   "SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races ORDER BY datetime(advertised_start_time) DESC, number, id",
+			args:    nil,
+		},
+		{
+			name: "OrderByOverridesSortBy",
+			filter: &racing.ListRacesRequestFilter{
+				MeetingIds: []int64{1},
+				SortBy:     "name",
+			},
+			orderBy: "id desc",
+			query:   "SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races WHERE meeting_id IN (?) ORDER BY id DESC",
+			args:    []interface{}{int64(1)},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := &racesRepo{}
-			resultQuery, resultArgs := repo.applyFilter("SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races", tt.filter)
+			q, err := repo.listQuery(tt.filter, tt.orderBy, now)
+			require.NoError(t, err)
+
+			resultQuery, resultArgs := q.SQL()
+			resultQuery = strings.Join(strings.Fields(resultQuery), " ")
 
 			if resultQuery != tt.query {
 				t.Errorf("Query mismatch. Expected: %s, Got: %s", tt.query, resultQuery)
@@ -179,6 +235,91 @@ func TestApplyFilter(t *testing.T) {
 	}
 }
 
+func TestListUsesClock(t *testing.T) {
+	db, mock := sqlrepotest.NewMock(t)
+
+	// a simulated race day, an hour before the first race
+	clk := clock.NewFake(time.Date(2021, time.March, 3, 9, 0, 0, 0, time.UTC))
+	repo := NewRacesRepo(db, WithClock(clk))
+
+	columns := []string{"id", "meeting_id", "name", "number", "visible", "advertised_start_time"}
+	mock.ExpectQuery("WHERE datetime\\(advertised_start_time\\) >= \\?").
+		WithArgs("2021-03-03 09:00:00").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 5, "F1 Race", 10, true, clk.Now().Add(time.Hour)))
+
+	races, _, err := repo.List(context.Background(), &racing.ListRacesRequest{Filter: &racing.ListRacesRequestFilter{Status: racing.ListRacesRequestFilter_OPEN}})
+	assert.NoError(t, err)
+	if assert.Len(t, races, 1) {
+		assert.Equal(t, "OPEN", races[0].Status)
+	}
+
+	// once the race has started it is CLOSED
+	clk.Advance(2 * time.Hour)
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 5, "F1 Race", 10, true, clk.Now().Add(-time.Hour)))
+
+	races, _, err = repo.List(context.Background(), &racing.ListRacesRequest{})
+	assert.NoError(t, err)
+	if assert.Len(t, races, 1) {
+		assert.Equal(t, "CLOSED", races[0].Status)
+	}
+
+}
+
+func TestListQueryInvalidOrderBy(t *testing.T) {
+	repo := &racesRepo{}
+	_, err := repo.listQuery(nil, "sports_type", now)
+
+	var orderErr *sqlrepo.OrderByError
+	assert.ErrorAs(t, err, &orderErr)
+}
+
+func TestListQueryInvalidExpression(t *testing.T) {
+	repo := &racesRepo{}
+	_, err := repo.listQuery(&racing.ListRacesRequestFilter{Expression: `sports_type = "Tennis"`}, "", now)
+
+	var filterErr *filtering.Error
+	assert.ErrorAs(t, err, &filterErr)
+}
+
+func TestGetRaceNotFound(t *testing.T) {
+	db, mock := sqlrepotest.NewMock(t)
+	repo := &racesRepo{db: db, clock: clock.NewFake(now)}
+
+	mock.ExpectQuery("FROM races WHERE id = \\?").WithArgs(404).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "meeting_id", "name", "number", "visible", "advertised_start_time"}))
+
+	_, err := repo.Get(context.Background(), &racing.GetRaceRequest{Id: 404})
+	assert.ErrorIs(t, err, sqlrepo.ErrNotFound)
+}
+
+func TestListPages(t *testing.T) {
+	db, mock := sqlrepotest.NewMock(t)
+	repo := NewRacesRepo(db, WithClock(clock.NewFake(now)))
+
+	columns := []string{"id", "meeting_id", "name", "number", "visible", "advertised_start_time"}
+	mock.ExpectQuery("ORDER BY number, id LIMIT \\? OFFSET \\?").WithArgs(3, 0).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 5, "F1 Race", 1, true, now).
+			AddRow(2, 5, "F2 Race", 2, true, now).
+			AddRow(3, 5, "F3 Race", 3, true, now))
+
+	races, token, err := repo.List(context.Background(), &racing.ListRacesRequest{OrderBy: "number", PageSize: 2})
+	require.NoError(t, err)
+	assert.Len(t, races, 2)
+	require.NotEmpty(t, token)
+
+	mock.ExpectQuery("ORDER BY number, id LIMIT \\? OFFSET \\?").WithArgs(3, 2).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(3, 5, "F3 Race", 3, true, now))
+
+	races, token, err = repo.List(context.Background(), &racing.ListRacesRequest{OrderBy: "number", PageSize: 2, PageToken: token})
+	require.NoError(t, err)
+	assert.Len(t, races, 1)
+	assert.Empty(t, token, "last page")
+
+	_, _, err = repo.List(context.Background(), &racing.ListRacesRequest{PageSize: 2, PageToken: "bogus"})
+	assert.ErrorIs(t, err, sqlrepo.ErrInvalidPageToken)
+}
+
 func equal(a, b []interface{}) bool {
 	if len(a) != len(b) {
 		return false
@@ -193,22 +334,18 @@ func equal(a, b []interface{}) bool {
 
 func TestScanRaceOpen(t *testing.T) {
 	// Set up the mock database and get a mock database connection
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("Error creating mock database: %v", err)
-	}
-	defer db.Close()
+	db, mock := sqlrepotest.NewMock(t)
 
 	// Define columns and rows for the mock
 	columns := []string{"id", "meeting_id", "name", "number", "visible", "advertised_start_time"}
-	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 5, "F1 Race", 10, true, time.Now().Add(time.Hour)))
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 5, "F1 Race", 10, true, now.Add(time.Hour)))
 
-	repo := &racesRepo{}
+	repo := &racesRepo{clock: clock.NewFake(now)}
 	rows, err := db.Query("SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races")
 	assert.NoError(t, err)
 
 	t.Run("ScanValidRace", func(t *testing.T) {
-		races, err := repo.scanRaces(rows)
+		races, err := repo.scanRaces(rows, now)
 
 		assert.NoError(t, err)
 		assert.Len(t, races, 1)
@@ -216,29 +353,22 @@ func TestScanRaceOpen(t *testing.T) {
 		// Add assertions for the event properties
 		assert.Equal(t, "OPEN", races[0].Status)
 	})
-
-	// Assert that the expected queries were executed
-	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestScanRaceClosed(t *testing.T) {
 	// Set up the mock database and get a mock database connection
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("Error creating mock database: %v", err)
-	}
-	defer db.Close()
+	db, mock := sqlrepotest.NewMock(t)
 
 	// Define columns and rows for the mock
 	columns := []string{"id", "meeting_id", "name", "number", "visible", "advertised_start_time"}
-	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 5, "F1 Race", 10, true, time.Now().Add(-time.Hour)))
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 5, "F1 Race", 10, true, now.Add(-time.Hour)))
 
-	repo := &racesRepo{}
+	repo := &racesRepo{clock: clock.NewFake(now)}
 	rows, err := db.Query("SELECT id, meeting_id, name, number, visible, advertised_start_time FROM races")
 	assert.NoError(t, err)
 
 	t.Run("ScanValidRace", func(t *testing.T) {
-		races, err := repo.scanRaces(rows)
+		races, err := repo.scanRaces(rows, now)
 
 		assert.NoError(t, err)
 		assert.Len(t, races, 1)
@@ -246,7 +376,88 @@ func TestScanRaceClosed(t *testing.T) {
 		// Add assertions for the event properties
 		assert.Equal(t, "CLOSED", races[0].Status)
 	})
+}
+
+func TestSeedOutbox(t *testing.T) {
+	ctx := context.Background()
+	db := sqlrepotest.OpenSQLite(t)
+	require.NoError(t, NewRacesRepo(db, WithClock(clock.NewFake(now))).Init())
+
+	var published []outbox.Message
+	bus := outbox.NewBus()
+	bus.Subscribe(func(ctx context.Context, msg outbox.Message) error {
+		published = append(published, msg)
+		return nil
+	})
+	relay := outbox.NewRelay(db, "test", bus, 1000)
+	n, err := relay.Flush(ctx)
+	require.NoError(t, err)
+	require.Equal(t, seedCount, n)
+
+	// Every seeded race is recorded as created, as it was stored.
+	msg := published[0]
+	assert.Equal(t, racesTopic, msg.Topic)
+	assert.Equal(t, raceCreated, msg.Type)
+	assert.Equal(t, "1", msg.Key)
+	assert.Equal(t, now, msg.CreatedAt)
+	var race racing.Race
+	require.NoError(t, protojson.Unmarshal(msg.Payload, &race))
+	stored, err := NewRacesRepo(db, WithClock(clock.NewFake(now))).Get(ctx, &racing.GetRaceRequest{Id: 1})
+	require.NoError(t, err)
+	assert.Equal(t, stored.Name, race.Name)
+	assert.Equal(t, stored.AdvertisedStartTime.AsTime(), race.AdvertisedStartTime.AsTime())
+
+	// Seeding again leaves the races as they are, recording nothing.
+	require.NoError(t, NewRacesRepo(db, WithClock(clock.NewFake(now))).Init())
+	n, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
 
-	// Assert that the expected queries were executed
-	assert.NoError(t, mock.ExpectationsWereMet())
+func TestStatusAtSubSecondNow(t *testing.T) {
+	// Start times are stored to the second, so the status of a race that
+	// started at race 12:00:00 must agree with the status filter throughout
+	// the second, whatever the fraction of now.
+	start := now.Truncate(time.Second)
+	require.NotEqual(t, start, now, "now must have a fraction of a second")
+	races := []*racing.Race{
+		{Id: 1, MeetingId: 1, Name: "Started", Number: 1, AdvertisedStartTime: timestamppb.New(start.Add(-time.Second))},
+		{Id: 2, MeetingId: 1, Name: "Starting", Number: 2, AdvertisedStartTime: timestamppb.New(start)},
+		{Id: 3, MeetingId: 1, Name: "Next", Number: 3, AdvertisedStartTime: timestamppb.New(start.Add(time.Second))},
+	}
+
+	db := sqlrepotest.OpenSQLite(t)
+	sqlRepo := NewRacesRepo(db, WithClock(clock.NewFake(now)))
+	require.NoError(t, sqlRepo.Init())
+	_, err := db.Exec(`DELETE FROM races`)
+	require.NoError(t, err)
+	for _, race := range races {
+		_, err := db.Exec(`INSERT INTO races(id, meeting_id, name, number, visible, advertised_start_time) VALUES (?,?,?,?,?,?)`,
+			race.Id, race.MeetingId, race.Name, race.Number, race.Visible, race.AdvertisedStartTime.AsTime().Format(time.RFC3339))
+		require.NoError(t, err)
+	}
+
+	repos := map[string]RacesRepo{
+		"SQLite": sqlRepo,
+		"Memory": NewMemoryRacesRepo(races, WithClock(clock.NewFake(now))),
+	}
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			for status, want := range map[racing.ListRacesRequestFilter_RaceStatus][]int64{
+				racing.ListRacesRequestFilter_OPEN:   {2, 3},
+				racing.ListRacesRequestFilter_CLOSED: {1},
+			} {
+				listed, _, err := repo.List(context.Background(), &racing.ListRacesRequest{Filter: &racing.ListRacesRequestFilter{Status: status}, OrderBy: "id"})
+				require.NoError(t, err)
+				require.Equal(t, want, raceIDs(listed), status.String())
+				for _, race := range listed {
+					assert.Equal(t, status.String(), race.Status, "race %d", race.Id)
+				}
+			}
+
+			race, err := repo.Get(context.Background(), &racing.GetRaceRequest{Id: 2})
+			require.NoError(t, err)
+			assert.Equal(t, sqlrepo.Open, race.Status)
+		})
+	}
 }
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"git.neds.sh/matty/entain/common/sqlrepo"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// defaultSearchLimit bounds search results when no limit is asked for.
+const defaultSearchLimit = 20
+
+// racesIndex is the full-text index over race names.
+var racesIndex = sqlrepo.TextIndex{Table: "races", Columns: []string{"name"}}
+
+func (r *racesRepo) Search(ctx context.Context, query string, limit int) ([]*racing.RaceSearchResult, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	if !r.fts {
+		return r.searchLike(ctx, terms, limit)
+	}
+
+	rows, err := r.query(ctx, getRaceQueries()[racesSearch], sqlrepo.FTSQuery(terms), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := sqlrepo.Now(r.clock)
+	var results []*racing.RaceSearchResult
+	for rows.Next() {
+		var (
+			race            racing.Race
+			advertisedStart time.Time
+			result          = racing.RaceSearchResult{Race: &race}
+			highlight       string
+		)
+
+		if err := rows.Scan(&race.Id, &race.MeetingId, &race.Name, &race.Number, &race.Visible, &advertisedStart, &result.Score, &highlight); err != nil {
+			return nil, err
+		}
+		if err := setAdvertisedStart(&race, advertisedStart, now); err != nil {
+			return nil, err
+		}
+
+		result.Highlights = map[string]string{"name": highlight}
+		results = append(results, &result)
+	}
+
+	return results, rows.Err()
+}
+
+// searchLike searches without the full-text index: every term must prefix a
+// word of the name. Results are scored by the share of the name's words
+// matched.
+func (r *racesRepo) searchLike(ctx context.Context, terms []string, limit int) ([]*racing.RaceSearchResult, error) {
+	q := sqlrepo.NewListQuery(getRaceQueries()[racesList])
+	racesIndex.Like(q, terms)
+
+	query, args := q.SQL()
+	rows, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	races, err := r.scanRaces(rows, sqlrepo.Now(r.clock))
+	if err != nil {
+		return nil, err
+	}
+
+	return rankRaces(races, terms, limit), nil
+}
+
+// rankRaces returns up to limit of the races with a word prefixed by every
+// term, scored by the share of their name's words matched, best first.
+func rankRaces(races []*racing.Race, terms []string, limit int) []*racing.RaceSearchResult {
+	var results []*racing.RaceSearchResult
+	for _, race := range races {
+		highlights, score, ok := sqlrepo.MatchFields(map[string]string{"name": race.Name}, terms)
+		if !ok {
+			continue
+		}
+
+		results = append(results, &racing.RaceSearchResult{
+			Race:       race,
+			Score:      score,
+			Highlights: highlights,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
+
+// setAdvertisedStart sets the race's advertised start time and the status
+// derived from it at now.
+func setAdvertisedStart(race *racing.Race, advertisedStart, now time.Time) error {
+	ts, err := ptypes.TimestampProto(advertisedStart)
+	if err != nil {
+		return err
+	}
+
+	race.AdvertisedStartTime = ts
+	race.Status = sqlrepo.Status(advertisedStart, now)
+	return nil
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/sqlrepo/sqlrepotest"
+)
+
+var searchColumns = []string{"id", "meeting_id", "name", "number", "visible", "advertised_start_time"}
+
+func TestSearchFTS(t *testing.T) {
+	db, mock := sqlrepotest.NewMock(t)
+
+	repo := &racesRepo{db: db, fts: true, clock: clock.NewFake(now)}
+	start := time.Date(2021, time.March, 3, 11, 30, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows(append(searchColumns, "score", "highlight")).
+		AddRow(7, 3, "Rhode Island Red", 4, true, start, 2.5, "<mark>Rhode</mark> Island Red")
+	mock.ExpectQuery("FROM races_fts .* WHERE races_fts MATCH \\?").
+		WithArgs(`"rho"* "isl""and"*`, 20).
+		WillReturnRows(rows)
+
+	results, err := repo.Search(context.Background(), ` rho  isl"and `, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	assert.Equal(t, int64(7), results[0].Race.Id)
+	assert.Equal(t, "CLOSED", results[0].Race.Status)
+	assert.Equal(t, 2.5, results[0].Score)
+	assert.Equal(t, map[string]string{"name": "<mark>Rhode</mark> Island Red"}, results[0].Highlights)
+}
+
+func TestSearchLike(t *testing.T) {
+	db, mock := sqlrepotest.NewMock(t)
+
+	repo := &racesRepo{db: db, clock: clock.NewFake(now)}
+	start := time.Date(2021, time.March, 3, 11, 30, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows(searchColumns).
+		AddRow(1, 3, "North Dakota Kings", 4, true, start).
+		AddRow(2, 3, "Kings", 5, true, start).
+		// "kin" is inside a word here, not at its start
+		AddRow(3, 3, "Pumpkin Pie", 6, true, start)
+	mock.ExpectQuery("WHERE \\(name LIKE \\? ESCAPE").
+		WithArgs(`%kin%`).
+		WillReturnRows(rows)
+
+	results, err := repo.Search(context.Background(), "kin", 5)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, int64(2), results[0].Race.Id)
+	assert.Equal(t, 1.0, results[0].Score)
+	assert.Equal(t, map[string]string{"name": "<mark>Kings</mark>"}, results[0].Highlights)
+
+	assert.Equal(t, int64(1), results[1].Race.Id)
+	assert.InDelta(t, 1.0/3, results[1].Score, 1e-9)
+	assert.Equal(t, map[string]string{"name": "North Dakota <mark>Kings</mark>"}, results[1].Highlights)
+}
+
+func TestSearchEmptyQuery(t *testing.T) {
+	db, _ := sqlrepotest.NewMock(t)
+
+	results, err := (&racesRepo{db: db}).Search(context.Background(), "   ", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
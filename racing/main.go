@@ -1,59 +1,101 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"log"
-	"net"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"google.golang.org/grpc"
+
+	"git.neds.sh/matty/entain/common/auth"
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/logging"
+	"git.neds.sh/matty/entain/common/server"
+	"git.neds.sh/matty/entain/racing/config"
 	"git.neds.sh/matty/entain/racing/db"
 	"git.neds.sh/matty/entain/racing/proto/racing"
 	"git.neds.sh/matty/entain/racing/service"
-	"google.golang.org/grpc"
 )
 
-var (
-	grpcEndpoint = flag.String("grpc-endpoint", "localhost:9000", "gRPC server endpoint")
-)
+// methodScopes lists the scope each RPC requires when auth is enabled. Any
+// RPC missing here is denied.
+var methodScopes = map[string]string{
+	"/racing.Racing/ListRaces":     "races:read",
+	"/racing.Racing/GetRace":       "races:read",
+	"/racing.Racing/SearchRaces":   "races:read",
+	"/racing.Racing/GetClock":      "races:read",
+	"/racing.Racing/SetClock":      "races:admin",
+	"/grpc.health.v1.Health/Check": auth.Public,
+	"/grpc.health.v1.Health/Watch": auth.Public,
+}
 
 func main() {
-	flag.Parse()
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("failed loading config: %s\n", err)
+	}
+
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("failed printing config: %s\n", err)
+		}
+		return
+	}
 
-	if err := run(); err != nil {
+	if err := run(cfg); err != nil {
 		log.Fatalf("failed running grpc server: %s\n", err)
 	}
 }
 
-func run() error {
-	conn, err := net.Listen("tcp", ":9000")
+func run(cfg *config.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger, err := logging.New(cfg.LogLevel, cfg.LogFormat)
 	if err != nil {
 		return err
 	}
 
-	racingDB, err := sql.Open("sqlite3", "./db/racing.db")
+	clk := server.NewClock(&cfg.Config, logger)
+	racesRepo, racingDB, err := newRacesRepo(cfg, clk)
 	if err != nil {
 		return err
 	}
-
-	racesRepo := db.NewRacesRepo(racingDB)
-	if err := racesRepo.Init(); err != nil {
-		return err
+	if racingDB != nil {
+		defer racingDB.Close()
 	}
 
-	grpcServer := grpc.NewServer()
-
-	racing.RegisterRacingServer(
-		grpcServer,
-		service.NewRacingService(
-			racesRepo,
-		),
-	)
+	return server.Run(ctx, &cfg.Config, logger, server.Service{
+		Name:   "racing.Racing",
+		Scopes: methodScopes,
+		DB:     racingDB,
+		Register: func(s *grpc.Server) {
+			racing.RegisterRacingServer(s, service.NewRacingService(racesRepo, clk))
+		},
+		Init:        racesRepo.Init,
+		PrintConfig: cfg.Print,
+	})
+}
 
-	log.Printf("gRPC server listening on: %s\n", *grpcEndpoint)
+// newRacesRepo returns the races repository of the configured storage, and
+// the database it keeps the races in, if any.
+func newRacesRepo(cfg *config.Config, clk clock.Clock) (db.RacesRepo, *sql.DB, error) {
+	if cfg.Storage == server.StorageMemory {
+		return db.NewMemoryRacesRepo(nil, db.WithClock(clk)), nil, nil
+	}
 
-	if err := grpcServer.Serve(conn); err != nil {
-		return err
+	racingDB, err := sql.Open("sqlite3", cfg.DBPath)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return nil
+	return db.NewRacesRepo(racingDB, db.WithSlowQueryThreshold(cfg.SlowQueryThreshold), db.WithClock(clk)), racingDB, nil
 }
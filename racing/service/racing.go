@@ -3,6 +3,12 @@ package service
 import (
 	"context"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/server"
 	"git.neds.sh/matty/entain/racing/db"
 	"git.neds.sh/matty/entain/racing/proto/racing"
 )
@@ -13,32 +19,82 @@ type Racing interface {
 
 	// GetRace will return a single race based on the given ID.
 	GetRace(ctx context.Context, in *racing.GetRaceRequest) (*racing.Race, error)
+
+	// SearchRaces will return the races best matching a text query.
+	SearchRaces(ctx context.Context, in *racing.SearchRacesRequest) (*racing.SearchRacesResponse, error)
+
+	// GetClock will return the time the service takes as now.
+	GetClock(ctx context.Context, in *racing.GetClockRequest) (*racing.Clock, error)
+
+	// SetClock will make the service take a simulated time as now.
+	SetClock(ctx context.Context, in *racing.SetClockRequest) (*racing.Clock, error)
 }
 
 // racingService implements the Racing interface.
 type racingService struct {
 	racesRepo db.RacesRepo
+	clock     clock.Clock
 }
 
-// NewRacingService instantiates and returns a new racingService.
-func NewRacingService(racesRepo db.RacesRepo) Racing {
-	return &racingService{racesRepo}
+// NewRacingService instantiates and returns a new racingService, telling the
+// time by clk. SetClock is only available if clk is a *clock.Simulated, which
+// should be shared with the repository.
+func NewRacingService(racesRepo db.RacesRepo, clk clock.Clock) Racing {
+	return &racingService{racesRepo, clk}
 }
 
 func (s *racingService) ListRaces(ctx context.Context, in *racing.ListRacesRequest) (*racing.ListRacesResponse, error) {
-	races, err := s.racesRepo.List(in.Filter)
+	races, nextPageToken, err := s.racesRepo.List(ctx, in)
 	if err != nil {
-		return nil, err
+		return nil, server.StatusError(err)
 	}
 
-	return &racing.ListRacesResponse{Races: races}, nil
+	return &racing.ListRacesResponse{Races: races, NextPageToken: nextPageToken}, nil
 }
 
 func (s *racingService) GetRace(ctx context.Context, in *racing.GetRaceRequest) (*racing.Race, error) {
-	race, err := s.racesRepo.Get(in)
+	race, err := s.racesRepo.Get(ctx, in)
 	if err != nil {
-		return nil, err
+		return nil, server.StatusError(err)
 	}
 
 	return race, nil
 }
+
+func (s *racingService) SearchRaces(ctx context.Context, in *racing.SearchRacesRequest) (*racing.SearchRacesResponse, error) {
+	results, err := s.racesRepo.Search(ctx, in.Query, int(in.Limit))
+	if err != nil {
+		return nil, err
+	}
+
+	return &racing.SearchRacesResponse{Results: results}, nil
+}
+
+func (s *racingService) GetClock(ctx context.Context, in *racing.GetClockRequest) (*racing.Clock, error) {
+	return s.clockState(), nil
+}
+
+func (s *racingService) SetClock(ctx context.Context, in *racing.SetClockRequest) (*racing.Clock, error) {
+	simulated, ok := s.clock.(*clock.Simulated)
+	if !ok {
+		return nil, status.Error(codes.FailedPrecondition, "simulated clock is disabled, see --simulated-clock")
+	}
+
+	if in.Now == nil {
+		simulated.Reset()
+	} else {
+		simulated.Set(in.Now.AsTime())
+	}
+
+	return s.clockState(), nil
+}
+
+// clockState describes the service's clock.
+func (s *racingService) clockState() *racing.Clock {
+	simulated, ok := s.clock.(*clock.Simulated)
+
+	return &racing.Clock{
+		Now:       timestamppb.New(s.clock.Now()),
+		Simulated: ok && simulated.Active(),
+	}
+}
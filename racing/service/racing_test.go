@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+var now = time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+
+func newTestService(t *testing.T) Racing {
+	t.Helper()
+
+	clk := clock.NewSimulated(clock.NewFake(now))
+	repo := db.NewMemoryRacesRepo([]*racing.Race{
+		{Id: 1, MeetingId: 1, Name: "Flemington Cup", Number: 3, Visible: true, AdvertisedStartTime: timestamppb.New(now.Add(-time.Hour))},
+		{Id: 2, MeetingId: 1, Name: "Flemington Sprint", Number: 1, Visible: true, AdvertisedStartTime: timestamppb.New(now.Add(time.Hour))},
+		{Id: 3, MeetingId: 2, Name: "Randwick Plate", Number: 2, AdvertisedStartTime: timestamppb.New(now.Add(2 * time.Hour))},
+	}, db.WithClock(clk))
+
+	return NewRacingService(repo, clk)
+}
+
+func TestListRaces(t *testing.T) {
+	svc := newTestService(t)
+
+	resp, err := svc.ListRaces(context.Background(), &racing.ListRacesRequest{
+		Filter:   &racing.ListRacesRequestFilter{Visibility: racing.ListRacesRequestFilter_VISIBILE},
+		OrderBy:  "number",
+		PageSize: 1,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Races, 1)
+	assert.Equal(t, int64(2), resp.Races[0].Id)
+	assert.Equal(t, "OPEN", resp.Races[0].Status)
+
+	resp, err = svc.ListRaces(context.Background(), &racing.ListRacesRequest{
+		Filter:    &racing.ListRacesRequestFilter{Visibility: racing.ListRacesRequestFilter_VISIBILE},
+		OrderBy:   "number",
+		PageSize:  1,
+		PageToken: resp.NextPageToken,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Races, 1)
+	assert.Equal(t, int64(1), resp.Races[0].Id)
+	assert.Empty(t, resp.NextPageToken)
+
+	_, err = svc.ListRaces(context.Background(), &racing.ListRacesRequest{OrderBy: "venue"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGetRace(t *testing.T) {
+	svc := newTestService(t)
+
+	race, err := svc.GetRace(context.Background(), &racing.GetRaceRequest{Id: 3})
+	require.NoError(t, err)
+	assert.Equal(t, "Randwick Plate", race.Name)
+
+	_, err = svc.GetRace(context.Background(), &racing.GetRaceRequest{Id: 99})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestSetClock(t *testing.T) {
+	svc := newTestService(t)
+
+	_, err := svc.SetClock(context.Background(), &racing.SetClockRequest{Now: timestamppb.New(now.Add(90 * time.Minute))})
+	require.NoError(t, err)
+
+	race, err := svc.GetRace(context.Background(), &racing.GetRaceRequest{Id: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "CLOSED", race.Status, "races follow the simulated clock")
+}
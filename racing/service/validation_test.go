@@ -0,0 +1,102 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"git.neds.sh/matty/entain/common/validation"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+func fields(violations []*errdetails.BadRequest_FieldViolation) map[string]string {
+	m := make(map[string]string, len(violations))
+	for _, v := range violations {
+		m[v.Field] = v.Description
+	}
+
+	return m
+}
+
+// TestRequestRules checks the validation rules declared on the service's
+// requests.
+func TestRequestRules(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		assert.Empty(t, validation.Validate(&racing.GetRaceRequest{Id: 1}))
+		assert.Empty(t, validation.Validate(&racing.ListRacesRequest{}))
+		assert.Empty(t, validation.Validate(&racing.ListRacesRequest{Filter: &racing.ListRacesRequestFilter{
+			MeetingIds: []int64{1, 2},
+			Visibility: racing.ListRacesRequestFilter_HIDDEN,
+			SortBy:     "advertised_start_time",
+			OrderBy:    1,
+		}}))
+	})
+
+	t.Run("GetRaceID", func(t *testing.T) {
+		assert.Equal(t, map[string]string{"id": "must be greater than 0"}, fields(validation.Validate(&racing.GetRaceRequest{})))
+		assert.Equal(t, map[string]string{"id": "must be greater than 0"}, fields(validation.Validate(&racing.GetRaceRequest{Id: -3})))
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		violations := validation.Validate(&racing.ListRacesRequest{Filter: &racing.ListRacesRequestFilter{
+			MeetingIds: []int64{1, 1, -2},
+			Visibility: 7,
+			SortBy:     "advertised_start",
+			OrderBy:    2,
+		}})
+
+		assert.Equal(t, map[string]string{
+			"filter.meeting_ids":    "must not contain duplicates",
+			"filter.meeting_ids[2]": "must be greater than 0",
+			"filter.visibility":     "must be a defined STATUS value",
+			"filter.sort_by":        "must be one of advertised_start_time, number, meeting_id, name",
+			"filter.order_by":       "must be less than or equal to 1",
+		}, fields(violations))
+	})
+
+	t.Run("Page", func(t *testing.T) {
+		assert.Empty(t, validation.Validate(&racing.ListRacesRequest{PageSize: 1000, PageToken: "bzE6MTA"}))
+		assert.Equal(t, map[string]string{
+			"page_size":  "must be less than or equal to 1000",
+			"page_token": "must be at most 100 characters",
+		}, fields(validation.Validate(&racing.ListRacesRequest{PageSize: 1001, PageToken: strings.Repeat("x", 101)})))
+	})
+
+	t.Run("StartsWithin", func(t *testing.T) {
+		for d, want := range map[time.Duration]string{
+			time.Hour:           "",
+			0:                   "must be longer than 0s",
+			-time.Minute:        "must be longer than 0s",
+			31 * 24 * time.Hour: "must be at most 720h0m0s",
+		} {
+			violations := validation.Validate(&racing.ListRacesRequest{Filter: &racing.ListRacesRequestFilter{StartsWithin: durationpb.New(d)}})
+			if want == "" {
+				assert.Empty(t, violations, d)
+				continue
+			}
+			assert.Equal(t, map[string]string{"filter.starts_within": want}, fields(violations), d)
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		assert.Empty(t, validation.Validate(&racing.SearchRacesRequest{Query: "kings", Limit: 10}))
+		assert.Equal(t, map[string]string{
+			"query": "must be at least 1 characters",
+			"limit": "must be less than or equal to 100",
+		}, fields(validation.Validate(&racing.SearchRacesRequest{Query: "   ", Limit: 101})))
+	})
+
+	t.Run("MaxItems", func(t *testing.T) {
+		ids := make([]int64, 101)
+		for i := range ids {
+			ids[i] = int64(i + 1)
+		}
+
+		violations := validation.Validate(&racing.ListRacesRequest{Filter: &racing.ListRacesRequestFilter{MeetingIds: ids}})
+		assert.Equal(t, map[string]string{"filter.meeting_ids": "must have at most 100 items"}, fields(violations))
+	})
+}
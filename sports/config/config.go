@@ -0,0 +1,69 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"io"
+
+	"git.neds.sh/matty/entain/common/configload"
+	"git.neds.sh/matty/entain/common/server"
+)
+
+// EnvPrefix prefixes the environment variable of every setting, e.g.
+// SPORTS_GRPC_ENDPOINT for --grpc-endpoint.
+const EnvPrefix = "SPORTS"
+
+// Config is the effective configuration of the sports service.
+type Config struct {
+	server.Config `yaml:",inline"`
+
+	// PrintConfig asks for the effective configuration to be printed instead
+	// of starting the service. It can only be set as a flag.
+	PrintConfig bool `yaml:"-" toml:"-"`
+
+	flags *flag.FlagSet
+}
+
+// Default returns the configuration used when nothing else is specified.
+func Default() *Config {
+	return &Config{Config: server.DefaultConfig("localhost:9001", "./db/sports.db")}
+}
+
+// Load builds the configuration from, in increasing order of precedence: the
+// defaults, the config file given by --config (or SPORTS_CONFIG), SPORTS_*
+// environment variables and command line flags. The result is validated.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	c := Default()
+
+	fs := flag.NewFlagSet("sports", flag.ContinueOnError)
+	if err := configload.Load(c, fs, EnvPrefix, args, lookupEnv, c.bind); err != nil {
+		return nil, err
+	}
+	c.flags = fs
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// bind registers a flag for every setting, defaulting to its current value.
+func (c *Config) bind(fs *flag.FlagSet) {
+	c.Config.Bind(fs)
+	fs.BoolVar(&c.PrintConfig, "print-config", false, "Print the effective configuration and exit")
+}
+
+// Validate checks the configuration is usable.
+func (c *Config) Validate() error {
+	if errs := c.Config.Validate(); len(errs) > 0 {
+		return errors.New(configload.FormatErrors(errs))
+	}
+
+	return nil
+}
+
+// Print writes the effective configuration to w as YAML, secrets redacted.
+func (c *Config) Print(w io.Writer) error {
+	return configload.Print(w, c.flags, server.Secrets)
+}
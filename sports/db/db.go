@@ -1,10 +1,15 @@
 package db
 
 import (
+	"context"
 	"math/rand"
 	"time"
 
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"syreclabs.com/go/faker"
+
+	"git.neds.sh/matty/entain/common/outbox"
+	"git.neds.sh/matty/entain/sports/proto/sports"
 )
 
 // Sport represents a type of sport
@@ -22,33 +27,137 @@ const (
 	Boxing     Sport = "Boxing"
 )
 
+// seedCount is how many dummy events repositories are seeded with.
+const seedCount = 100
+
+// sportsSchema creates the tables of the repository. Events refer to their
+// sport type by name; triggers rather than a foreign key keep them to known
+// ones, as SQLite only enforces foreign keys on connections enabling them and
+// cannot add one to the existing sports table.
+var sportsSchema = []string{
+	`CREATE TABLE IF NOT EXISTS sport_types (id INTEGER PRIMARY KEY, name TEXT NOT NULL UNIQUE, category TEXT NOT NULL, icon TEXT NOT NULL, active BOOLEAN NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS sports (id INTEGER PRIMARY KEY, event_id INTEGER, sports_type TEXT, name TEXT, number INTEGER, advertised_start_time DATETIME)`,
+	`CREATE INDEX IF NOT EXISTS sports_sports_type ON sports (sports_type)`,
+	`CREATE TRIGGER IF NOT EXISTS sports_sport_type_insert BEFORE INSERT ON sports
+		WHEN NOT EXISTS (SELECT 1 FROM sport_types WHERE name = NEW.sports_type)
+		BEGIN SELECT RAISE(ABORT, 'unknown sport type'); END`,
+	`CREATE TRIGGER IF NOT EXISTS sports_sport_type_update BEFORE UPDATE OF sports_type ON sports
+		WHEN NOT EXISTS (SELECT 1 FROM sport_types WHERE name = NEW.sports_type)
+		BEGIN SELECT RAISE(ABORT, 'unknown sport type'); END`,
+	`CREATE TRIGGER IF NOT EXISTS sport_types_delete BEFORE DELETE ON sport_types
+		WHEN EXISTS (SELECT 1 FROM sports WHERE sports_type = OLD.name)
+		BEGIN SELECT RAISE(ABORT, 'sport type in use'); END`,
+	`CREATE TRIGGER IF NOT EXISTS sport_types_rename BEFORE UPDATE OF name ON sport_types
+		WHEN NEW.name != OLD.name AND EXISTS (SELECT 1 FROM sports WHERE sports_type = OLD.name)
+		BEGIN SELECT RAISE(ABORT, 'sport type in use'); END`,
+}
+
+// Topic and type of the outbox messages recording events being created.
+const (
+	eventsTopic  = "sports.events"
+	eventCreated = "event.created"
+)
+
 func (r *sportsRepo) seed() error {
-	statement, err := r.db.Prepare(`CREATE TABLE IF NOT EXISTS sports (id INTEGER PRIMARY KEY, event_id INTEGER, sports_type TEXT, name TEXT, number INTEGER, advertised_start_time DATETIME)`)
-	if err == nil {
-		_, err = statement.Exec()
+	ctx := context.Background()
+
+	for _, statement := range sportsSchema {
+		if _, err := r.db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	if err := outbox.Init(ctx, r.db); err != nil {
+		return err
+	}
+
+	// The sport types are reference data, brought in line with sportTypes on
+	// every start.
+	for _, sportType := range sportTypes {
+		_, err := r.db.Exec(`INSERT INTO sport_types(id, name, category, icon, active) VALUES (?,?,?,?,?)
+			ON CONFLICT(id) DO UPDATE SET name = excluded.name, category = excluded.category, icon = excluded.icon, active = excluded.active`,
+			sportType.Id,
+			sportType.Name,
+			sportType.Category.String(),
+			sportType.Icon,
+			sportType.Active,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Every event is recorded in the outbox in the same transaction as it is
+	// inserted, so the relay publishes exactly the events that were.
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statement, err := tx.Prepare(`INSERT OR IGNORE INTO sports(id, event_id, sports_type, name, number, advertised_start_time) VALUES (?,?,?,?,?,?)`)
+	if err != nil {
+		return err
 	}
+	defer statement.Close()
+
+	for i := 1; i <= seedCount; i++ {
+		now := r.clock.Now()
+		event := dummyEvent(int64(i), now)
+
+		res, err := statement.Exec(
+			event.Id,
+			event.EventId,
+			event.SportsType,
+			event.Name,
+			event.Number,
+			event.AdvertisedStartTime.AsTime().Format(time.RFC3339),
+		)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		// An event seeded by an earlier start is left as it is.
+		if n == 0 {
+			continue
+		}
 
-	for i := 1; i <= 100; i++ {
-		statement, err = r.db.Prepare(`INSERT OR IGNORE INTO sports(id, event_id, sports_type, name, number, advertised_start_time) VALUES (?,?,?,?,?,?)`)
-		if err == nil {
-			_, err = statement.Exec(
-				i,
-				faker.Number().Between(1, 10),
-				generateSportType(),
-				faker.Team().Name(),
-				faker.Number().Between(1, 10),
-				faker.Time().Between(time.Now().AddDate(0, 0, -1), time.Now().AddDate(0, 0, 2)).Format(time.RFC3339),
-			)
+		msg, err := outbox.NewMessage(eventsTopic, eventCreated, event.Id, event, now)
+		if err != nil {
+			return err
+		}
+		if err := outbox.Append(ctx, tx, msg); err != nil {
+			return err
 		}
 	}
 
-	return err
+	return tx.Commit()
 }
 
-// generateSportType chooses a random type of sport based on the given list of options
+// dummyEvent makes up event id, advertised to start between a day before and
+// two days after now.
+func dummyEvent(id int64, now time.Time) *sports.Event {
+	return &sports.Event{
+		Id:                  id,
+		EventId:             int64(faker.RandomInt(1, 10)),
+		SportsType:          string(generateSportType()),
+		Name:                faker.Team().Name(),
+		Number:              int64(faker.RandomInt(1, 10)),
+		AdvertisedStartTime: timestamppb.New(faker.Time().Between(now.AddDate(0, 0, -1), now.AddDate(0, 0, 2))),
+	}
+}
+
+// generateSportType chooses a random type of sport among the active ones
 func generateSportType() Sport {
 	source := rand.NewSource(time.Now().UnixNano())
 	rng := rand.New(source)
-	sports := []Sport{Football, Basketball, Tennis, Soccer, Cricket, DOTA, CS, Boxing}
+	var sports []Sport
+	for _, sportType := range sportTypes {
+		if sportType.Active {
+			sports = append(sports, Sport(sportType.Name))
+		}
+	}
 	return sports[rng.Intn(len(sports))]
 }
@@ -0,0 +1,186 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/sqlrepo"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+// memorySportsRepo keeps events in memory, listing and searching them the way
+// sportsRepo does without a full-text index.
+type memorySportsRepo struct {
+	mu     sync.RWMutex
+	events []*sports.Event // ordered by ID
+	init   sync.Once
+	clock  clock.Clock
+}
+
+// NewMemorySportsRepo creates a sports repository holding events in memory,
+// nothing of which outlives it. Init adds the dummy events a new database is
+// seeded with, keeping those given here in place of any with the same ID, and
+// fails if one of them is of an unknown sport type, as inserting it into the
+// database would. Only the WithClock option applies.
+func NewMemorySportsRepo(events []*sports.Event, opts ...Option) SportsRepo {
+	cfg := &sportsRepo{clock: clock.Real{}}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	r := &memorySportsRepo{clock: cfg.clock}
+	for _, event := range events {
+		r.add(event)
+	}
+
+	return r
+}
+
+// Init seeds the repository with dummy events.
+func (r *memorySportsRepo) Init() error {
+	var err error
+
+	r.init.Do(func() {
+		now := r.clock.Now()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		for _, event := range r.events {
+			if !knownSportType(event.SportsType) {
+				err = fmt.Errorf("event %d: unknown sport type %q", event.Id, event.SportsType)
+				return
+			}
+		}
+
+		for i := 1; i <= seedCount; i++ {
+			r.add(dummyEvent(int64(i), now))
+		}
+	})
+
+	return err
+}
+
+func (r *memorySportsRepo) List(ctx context.Context, in *sports.ListEventsRequest) ([]*sports.Event, string, error) {
+	now := sqlrepo.Now(r.clock)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	q := sqlrepo.NewMemoryQuery(len(r.events), func(i int, field string) interface{} {
+		return eventField(r.events[i], field)
+	})
+	if err := applySportsFilter(q, in.Filter, in.OrderBy, now); err != nil {
+		return nil, "", err
+	}
+	if err := q.Page(in.PageSize, in.PageToken); err != nil {
+		return nil, "", err
+	}
+
+	rows, nextPageToken := q.Rows()
+
+	var events []*sports.Event
+	for _, i := range rows {
+		events = append(events, withStatus(r.events[i], now))
+	}
+
+	return events, nextPageToken, nil
+}
+
+func (r *memorySportsRepo) Get(ctx context.Context, filter *sports.GetEventRequest) (*sports.Event, error) {
+	if filter == nil {
+		return nil, fmt.Errorf("error: no ID passed")
+	}
+
+	now := sqlrepo.Now(r.clock)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i, ok := r.find(int64(filter.Id))
+	if !ok {
+		return nil, fmt.Errorf("event %d: %w", filter.Id, sqlrepo.ErrNotFound)
+	}
+
+	return withStatus(r.events[i], now), nil
+}
+
+func (r *memorySportsRepo) Search(ctx context.Context, query string, limit int) ([]*sports.EventSearchResult, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	now := sqlrepo.Now(r.clock)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := make([]*sports.Event, 0, len(r.events))
+	for _, event := range r.events {
+		events = append(events, withStatus(event, now))
+	}
+
+	return rankEvents(events, terms, limit), nil
+}
+
+func (r *memorySportsRepo) ListSportTypes(ctx context.Context, includeInactive bool) ([]*sports.SportType, error) {
+	return listSportTypes(includeInactive), nil
+}
+
+// add adds a copy of event unless there already is one with its ID.
+func (r *memorySportsRepo) add(event *sports.Event) {
+	i, ok := r.find(event.Id)
+	if ok {
+		return
+	}
+
+	r.events = append(r.events, nil)
+	copy(r.events[i+1:], r.events[i:])
+	r.events[i] = proto.Clone(event).(*sports.Event)
+}
+
+// find returns the index of the event with the given ID, or where it would be
+// inserted, and whether there is one.
+func (r *memorySportsRepo) find(id int64) (int, bool) {
+	i := sort.Search(len(r.events), func(i int) bool { return r.events[i].Id >= id })
+	return i, i < len(r.events) && r.events[i].Id == id
+}
+
+// eventField returns the value of a field of sportsFilterSchema.
+func eventField(event *sports.Event, field string) interface{} {
+	switch field {
+	case "id":
+		return event.Id
+	case "event_id":
+		return event.EventId
+	case "sports_type":
+		return event.SportsType
+	case "name":
+		return event.Name
+	case "number":
+		return event.Number
+	case "advertised_start_time":
+		return event.AdvertisedStartTime.AsTime()
+	}
+
+	panic("db: unknown event field " + field)
+}
+
+// withStatus returns a copy of event with the status derived at now.
+func withStatus(event *sports.Event, now time.Time) *sports.Event {
+	event = proto.Clone(event).(*sports.Event)
+	event.Status = sqlrepo.Status(event.AdvertisedStartTime.AsTime(), now)
+
+	return event
+}
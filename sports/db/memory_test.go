@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/sqlrepo"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+func TestMemorySportsRepo(t *testing.T) {
+	clk := clock.NewFake(now)
+	repo := NewMemorySportsRepo([]*sports.Event{
+		{Id: 7, EventId: 3, SportsType: string(Tennis), Name: "Wimbledon Final", Number: 4, AdvertisedStartTime: timestamppb.New(now.Add(time.Minute))},
+		{Id: 200, EventId: 3, SportsType: string(Boxing), Name: "Zanzibar Quokka Shield", Number: 5, AdvertisedStartTime: timestamppb.New(now.Add(-time.Minute))},
+	}, WithClock(clk))
+	require.NoError(t, repo.Init())
+
+	t.Run("SeedKeepsGivenEvents", func(t *testing.T) {
+		events, _, err := repo.List(context.Background(), &sports.ListEventsRequest{})
+		require.NoError(t, err)
+		assert.Len(t, events, seedCount+1)
+
+		event, err := repo.Get(context.Background(), &sports.GetEventRequest{Id: 7})
+		require.NoError(t, err)
+		assert.Equal(t, "Wimbledon Final", event.Name)
+		assert.Equal(t, sqlrepo.Open, event.Status)
+	})
+
+	t.Run("StatusFollowsClock", func(t *testing.T) {
+		clk.Advance(time.Hour)
+		defer clk.Set(now)
+
+		event, err := repo.Get(context.Background(), &sports.GetEventRequest{Id: 7})
+		require.NoError(t, err)
+		assert.Equal(t, sqlrepo.Closed, event.Status)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := repo.Get(context.Background(), &sports.GetEventRequest{Id: 999})
+		assert.ErrorIs(t, err, sqlrepo.ErrNotFound)
+	})
+
+	t.Run("ReturnsCopies", func(t *testing.T) {
+		event, err := repo.Get(context.Background(), &sports.GetEventRequest{Id: 7})
+		require.NoError(t, err)
+		event.Name = "changed"
+
+		event, err = repo.Get(context.Background(), &sports.GetEventRequest{Id: 7})
+		require.NoError(t, err)
+		assert.Equal(t, "Wimbledon Final", event.Name)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		results, err := repo.Search(context.Background(), "quok zanz", 0)
+		require.NoError(t, err)
+		require.NotEmpty(t, results)
+
+		assert.Equal(t, int64(200), results[0].Event.Id)
+		assert.Equal(t, sqlrepo.Closed, results[0].Event.Status)
+		assert.Equal(t, map[string]string{"name": "<mark>Zanzibar</mark> <mark>Quokka</mark> Shield"}, results[0].Highlights)
+	})
+}
+
+func TestMemorySportsRepoSportTypes(t *testing.T) {
+	retired := withRetiredSportType(t)
+
+	repo := NewMemorySportsRepo(nil)
+	require.NoError(t, repo.Init())
+
+	active, err := repo.ListSportTypes(context.Background(), false)
+	require.NoError(t, err)
+	assert.NotContains(t, names(active), retired)
+	assert.True(t, sort.StringsAreSorted(names(active)))
+
+	all, err := repo.ListSportTypes(context.Background(), true)
+	require.NoError(t, err)
+	assert.Contains(t, names(all), retired)
+
+	events, _, err := repo.List(context.Background(), &sports.ListEventsRequest{Filter: &sports.ListEventsRequestFilter{SportTypes: []string{string(Tennis)}}})
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	for _, event := range events {
+		assert.Equal(t, string(Tennis), event.SportsType)
+	}
+
+	repo = NewMemorySportsRepo([]*sports.Event{{Id: 1, SportsType: "Curling", AdvertisedStartTime: timestamppb.New(now)}})
+	assert.EqualError(t, repo.Init(), `event 1: unknown sport type "Curling"`)
+}
+
+func names(sportTypes []*sports.SportType) []string {
+	var names []string
+	for _, sportType := range sportTypes {
+		names = append(names, sportType.Name)
+	}
+
+	return names
+}
@@ -1,7 +1,9 @@
 package db
 
 const (
-	sportsList = "list"
+	sportsList     = "list"
+	sportsSearch   = "search"
+	sportTypesList = "sport_types"
 )
 
 func getSportsQueries() map[string]string {
@@ -16,5 +18,31 @@ func getSportsQueries() map[string]string {
 				advertised_start_time 
 			FROM sports
 		`,
+		sportsSearch: `
+			SELECT 
+				sports.id, 
+				sports.event_id, 
+				sports.sports_type, 
+				sports.name, 
+				sports.number, 
+				sports.advertised_start_time, 
+				-bm25(sports_fts), 
+				highlight(sports_fts, 0, '<mark>', '</mark>'), 
+				highlight(sports_fts, 1, '<mark>', '</mark>') 
+			FROM sports_fts 
+			JOIN sports ON sports.id = sports_fts.rowid 
+			WHERE sports_fts MATCH ? 
+			ORDER BY bm25(sports_fts), sports.id 
+			LIMIT ?
+		`,
+		sportTypesList: `
+			SELECT 
+				id, 
+				name, 
+				category, 
+				icon, 
+				active 
+			FROM sport_types
+		`,
 	}
 }
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"git.neds.sh/matty/entain/common/sqlrepo"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+// defaultSearchLimit bounds search results when no limit is asked for.
+const defaultSearchLimit = 20
+
+// sportsIndex is the full-text index over event names and sports types.
+var sportsIndex = sqlrepo.TextIndex{Table: "sports", Columns: []string{"name", "sports_type"}}
+
+func (r *sportsRepo) Search(ctx context.Context, query string, limit int) ([]*sports.EventSearchResult, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	if !r.fts {
+		return r.searchLike(ctx, terms, limit)
+	}
+
+	rows, err := r.query(ctx, getSportsQueries()[sportsSearch], sqlrepo.FTSQuery(terms), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := sqlrepo.Now(r.clock)
+	var results []*sports.EventSearchResult
+	for rows.Next() {
+		var (
+			event                        sports.Event
+			advertisedStart              time.Time
+			result                       = sports.EventSearchResult{Event: &event}
+			nameMarked, sportsTypeMarked string
+		)
+
+		if err := rows.Scan(&event.Id, &event.EventId, &event.SportsType, &event.Name, &event.Number, &advertisedStart, &result.Score, &nameMarked, &sportsTypeMarked); err != nil {
+			return nil, err
+		}
+		if err := setAdvertisedStart(&event, advertisedStart, now); err != nil {
+			return nil, err
+		}
+
+		// only the fields a term matched are highlighted
+		result.Highlights = make(map[string]string)
+		for field, marked := range map[string]string{"name": nameMarked, "sports_type": sportsTypeMarked} {
+			if strings.Contains(marked, "<mark>") {
+				result.Highlights[field] = marked
+			}
+		}
+		results = append(results, &result)
+	}
+
+	return results, rows.Err()
+}
+
+// searchLike searches without the full-text index: every term must prefix a
+// word of the name or sports type. Results are scored by the share of their
+// words matched.
+func (r *sportsRepo) searchLike(ctx context.Context, terms []string, limit int) ([]*sports.EventSearchResult, error) {
+	q := sqlrepo.NewListQuery(getSportsQueries()[sportsList])
+	sportsIndex.Like(q, terms)
+
+	query, args := q.SQL()
+	rows, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := r.scanEvents(rows, sqlrepo.Now(r.clock))
+	if err != nil {
+		return nil, err
+	}
+
+	return rankEvents(events, terms, limit), nil
+}
+
+// rankEvents returns up to limit of the events with a word prefixed by every
+// term, scored by the share of their name's and sports type's words matched,
+// best first.
+func rankEvents(events []*sports.Event, terms []string, limit int) []*sports.EventSearchResult {
+	var results []*sports.EventSearchResult
+	for _, event := range events {
+		highlights, score, ok := sqlrepo.MatchFields(map[string]string{"name": event.Name, "sports_type": event.SportsType}, terms)
+		if !ok {
+			continue
+		}
+
+		results = append(results, &sports.EventSearchResult{
+			Event:      event,
+			Score:      score,
+			Highlights: highlights,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
+
+// setAdvertisedStart sets the event's advertised start time and the status
+// derived from it at now.
+func setAdvertisedStart(event *sports.Event, advertisedStart, now time.Time) error {
+	ts, err := ptypes.TimestampProto(advertisedStart)
+	if err != nil {
+		return err
+	}
+
+	event.AdvertisedStartTime = ts
+	event.Status = sqlrepo.Status(advertisedStart, now)
+	return nil
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/sqlrepo/sqlrepotest"
+)
+
+var searchColumns = []string{"id", "event_id", "sports_type", "name", "number", "advertised_start_time"}
+
+func TestSearchFTS(t *testing.T) {
+	db, mock := sqlrepotest.NewMock(t)
+
+	repo := &sportsRepo{db: db, fts: true, clock: clock.NewFake(now)}
+	start := time.Date(2021, time.March, 3, 11, 30, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows(append(searchColumns, "score", "name_highlight", "sports_type_highlight")).
+		AddRow(7, 3, "Tennis", "Rhode Island Open", 4, start, 2.5, "<mark>Rhode</mark> Island Open", "Tennis")
+	mock.ExpectQuery("FROM sports_fts .* WHERE sports_fts MATCH \\?").
+		WithArgs(`"rho"* "isl""and"*`, 20).
+		WillReturnRows(rows)
+
+	results, err := repo.Search(context.Background(), ` rho  isl"and `, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	assert.Equal(t, int64(7), results[0].Event.Id)
+	assert.Equal(t, "CLOSED", results[0].Event.Status)
+	assert.Equal(t, 2.5, results[0].Score)
+	assert.Equal(t, map[string]string{"name": "<mark>Rhode</mark> Island Open"}, results[0].Highlights)
+}
+
+func TestSearchLike(t *testing.T) {
+	db, mock := sqlrepotest.NewMock(t)
+
+	repo := &sportsRepo{db: db, clock: clock.NewFake(now)}
+	start := time.Date(2021, time.March, 3, 11, 30, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows(searchColumns).
+		AddRow(1, 3, "Tennis", "Kings Cup", 4, start).
+		AddRow(2, 3, "Tennis Doubles", "Open", 5, start).
+		// "ten" is inside a word here, not at its start
+		AddRow(3, 3, "Golf", "Fourteenth Masters", 6, start)
+	mock.ExpectQuery("WHERE \\(name LIKE \\? ESCAPE .* OR sports_type LIKE \\? ESCAPE .*\\)").
+		WithArgs(`%ten%`, `%ten%`).
+		WillReturnRows(rows)
+
+	results, err := repo.Search(context.Background(), "ten", 5)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, int64(1), results[0].Event.Id)
+	assert.InDelta(t, 1.0/3, results[0].Score, 1e-9)
+	assert.Equal(t, map[string]string{"sports_type": "<mark>Tennis</mark>"}, results[0].Highlights)
+
+	assert.Equal(t, int64(2), results[1].Event.Id)
+	assert.InDelta(t, 1.0/3, results[1].Score, 1e-9)
+	assert.Equal(t, map[string]string{"sports_type": "<mark>Tennis</mark> Doubles"}, results[1].Highlights)
+}
+
+func TestSearchEmptyQuery(t *testing.T) {
+	db, _ := sqlrepotest.NewMock(t)
+
+	results, err := (&sportsRepo{db: db}).Search(context.Background(), "   ", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
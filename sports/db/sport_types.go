@@ -0,0 +1,48 @@
+package db
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+// sportTypes is the reference data of the sport_types table, which events'
+// sports_type refers to by name. Sport types are only ever made inactive,
+// never removed, so past events keep referring to a known one.
+var sportTypes = []*sports.SportType{
+	{Id: 1, Name: string(Football), Category: sports.SportType_TRADITIONAL, Icon: "football", Active: true},
+	{Id: 2, Name: string(Basketball), Category: sports.SportType_TRADITIONAL, Icon: "basketball", Active: true},
+	{Id: 3, Name: string(Tennis), Category: sports.SportType_TRADITIONAL, Icon: "tennis", Active: true},
+	{Id: 4, Name: string(Soccer), Category: sports.SportType_TRADITIONAL, Icon: "soccer", Active: true},
+	{Id: 5, Name: string(Cricket), Category: sports.SportType_TRADITIONAL, Icon: "cricket", Active: true},
+	{Id: 6, Name: string(DOTA), Category: sports.SportType_ESPORTS, Icon: "dota", Active: true},
+	{Id: 7, Name: string(CS), Category: sports.SportType_ESPORTS, Icon: "counter-strike", Active: true},
+	{Id: 8, Name: string(Boxing), Category: sports.SportType_TRADITIONAL, Icon: "boxing", Active: true},
+}
+
+// listSportTypes returns copies of the sport types sorted by name, leaving out
+// inactive ones unless includeInactive is set.
+func listSportTypes(includeInactive bool) []*sports.SportType {
+	var list []*sports.SportType
+	for _, sportType := range sportTypes {
+		if sportType.Active || includeInactive {
+			list = append(list, proto.Clone(sportType).(*sports.SportType))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return list
+}
+
+// knownSportType reports whether name is the name of a sport type.
+func knownSportType(name string) bool {
+	for _, sportType := range sportTypes {
+		if sportType.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,13 +1,15 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/filtering"
+	"git.neds.sh/matty/entain/common/sqlrepo"
 	"git.neds.sh/matty/entain/sports/proto/sports"
 	"github.com/golang/protobuf/ptypes"
 	_ "github.com/mattn/go-sqlite3"
@@ -18,21 +20,89 @@ type SportsRepo interface {
 	// Init will initialise our sports repository.
 	Init() error
 
-	// List will return a list of events.
-	List(filter *sports.ListEventsRequestFilter) ([]*sports.Event, error)
+	// List will return a page of the events matching the request's filter,
+	// sorted by its AIP-132 order_by expression, and the token of the next
+	// page. An invalid order_by gives an *sqlrepo.OrderByError, an invalid
+	// filter expression a *filtering.Error and an invalid page token
+	// sqlrepo.ErrInvalidPageToken.
+	List(ctx context.Context, in *sports.ListEventsRequest) ([]*sports.Event, string, error)
 
-	// Get will return a single event based on the given ID.
-	Get(filter *sports.GetEventRequest) (*sports.Event, error)
+	// Get will return a single event based on the given ID, or an error
+	// wrapping sqlrepo.ErrNotFound if there is none.
+	Get(ctx context.Context, filter *sports.GetEventRequest) (*sports.Event, error)
+
+	// Search will return the events whose name or sports type matches query,
+	// best matches first, up to limit of them (20 if limit is not positive).
+	Search(ctx context.Context, query string, limit int) ([]*sports.EventSearchResult, error)
+
+	// ListSportTypes will return the sport types sorted by name, leaving out
+	// inactive ones unless includeInactive is set.
+	ListSportTypes(ctx context.Context, includeInactive bool) ([]*sports.SportType, error)
+}
+
+// sportsOrderColumns whitelists the fields events can be sorted by.
+// Start times are compared as datetime() so ones stored with different UTC
+// offsets sort chronologically.
+var sportsOrderColumns = map[string]string{
+	"id":                    "id",
+	"event_id":              "event_id",
+	"sports_type":           "sports_type",
+	"name":                  "name",
+	"number":                "number",
+	"advertised_start_time": filtering.TimeColumn("advertised_start_time"),
+}
+
+// sportTypesOrderColumns whitelists the fields sport types can be sorted by.
+var sportTypesOrderColumns = map[string]string{
+	"id":   "id",
+	"name": "name",
+}
+
+// sportsFilterSchema lists the fields filter expressions can refer to.
+var sportsFilterSchema = filtering.Schema{
+	"id":                    {Column: "id", Type: filtering.Int},
+	"event_id":              {Column: "event_id", Type: filtering.Int},
+	"sports_type":           {Column: "sports_type", Type: filtering.String},
+	"name":                  {Column: "name", Type: filtering.String},
+	"number":                {Column: "number", Type: filtering.Int},
+	"advertised_start_time": {Column: "advertised_start_time", Type: filtering.Timestamp},
 }
 
 type sportsRepo struct {
-	db   *sql.DB
-	init sync.Once
+	db                 *sql.DB
+	init               sync.Once
+	fts                bool
+	slowQueryThreshold time.Duration
+	clock              clock.Clock
+}
+
+// Option configures optional behaviour of the sports repository.
+type Option func(*sportsRepo)
+
+// WithSlowQueryThreshold makes the repository log a warning for every query
+// taking longer than d. A zero threshold disables the warning.
+func WithSlowQueryThreshold(d time.Duration) Option {
+	return func(r *sportsRepo) {
+		r.slowQueryThreshold = d
+	}
+}
+
+// WithClock makes the repository take the time from c, e.g. when deriving
+// event statuses, instead of the system clock.
+func WithClock(c clock.Clock) Option {
+	return func(r *sportsRepo) {
+		r.clock = c
+	}
 }
 
 // NewSportsRepo creates a new sports repository.
-func NewSportsRepo(db *sql.DB) SportsRepo {
-	return &sportsRepo{db: db}
+func NewSportsRepo(db *sql.DB, opts ...Option) SportsRepo {
+	r := &sportsRepo{db: db, clock: clock.Real{}}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
 }
 
 // Init prepares the sports repository dummy data.
@@ -40,103 +110,181 @@ func (r *sportsRepo) Init() error {
 	var err error
 
 	r.init.Do(func() {
+		// The full-text index is rebuilt once seeded, so its triggers can go
+		// until then; left by a build with FTS5, they would fail the seeding.
+		if err = sportsIndex.DropTriggers(r.db); err != nil {
+			return
+		}
+
 		// For test/example purposes, we seed the DB with some dummy events.
-		err = r.seed()
+		if err = r.seed(); err != nil {
+			return
+		}
+		r.fts, err = sportsIndex.Init(r.db)
 	})
 
 	return err
 }
 
-func (r *sportsRepo) List(filter *sports.ListEventsRequestFilter) ([]*sports.Event, error) {
-	var (
-		err   error
-		query string
-		args  []interface{}
-	)
-
-	query = getSportsQueries()[sportsList]
+func (r *sportsRepo) List(ctx context.Context, in *sports.ListEventsRequest) ([]*sports.Event, string, error) {
+	now := sqlrepo.Now(r.clock)
+	q, err := r.listQuery(in.Filter, in.OrderBy, now)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := q.Page(in.PageSize, in.PageToken); err != nil {
+		return nil, "", err
+	}
 
-	query, args = r.applyFilter(query, filter)
+	query, args := q.SQL()
+	rows, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
 
-	rows, err := r.db.Query(query, args...)
+	events, err := r.scanEvents(rows, now)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return r.scanEvents(rows)
+	n, nextPageToken := q.NextPageToken(len(events))
+	return events[:n], nextPageToken, nil
 }
 
-func (r *sportsRepo) Get(filter *sports.GetEventRequest) (*sports.Event, error) {
-	var (
-		err   error
-		query string
-	)
-
-	query = getSportsQueries()[sportsList]
-
+func (r *sportsRepo) Get(ctx context.Context, filter *sports.GetEventRequest) (*sports.Event, error) {
 	if filter == nil {
 		return nil, fmt.Errorf("error: no ID passed")
 	}
-	if filter != nil {
-		// apply the ID filter
-		query += " WHERE id=" + strconv.Itoa(int(filter.Id))
-	}
 
-	rows, err := r.db.Query(query, nil)
+	q := sqlrepo.NewListQuery(getSportsQueries()[sportsList])
+	q.Where("id = ?", filter.Id)
+
+	query, args := q.SQL()
+	rows, err := r.query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	events, err := r.scanEvents(rows)
+	events, err := r.scanEvents(rows, sqlrepo.Now(r.clock))
 	if err != nil {
 		return nil, err
 	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("event %d: %w", filter.Id, sqlrepo.ErrNotFound)
+	}
 
-	// since we are expecting only 1 row, so choose the first one
 	return events[0], nil
 }
 
-func (r *sportsRepo) applyFilter(query string, filter *sports.ListEventsRequestFilter) (string, []interface{}) {
-	var (
-		clauses []string
-		args    []interface{}
-	)
+func (r *sportsRepo) ListSportTypes(ctx context.Context, includeInactive bool) ([]*sports.SportType, error) {
+	q := sqlrepo.NewListQuery(getSportsQueries()[sportTypesList])
+	if !includeInactive {
+		q.Where("active")
+	}
+	if err := q.OrderBy("name", sportTypesOrderColumns); err != nil {
+		return nil, err
+	}
+
+	query, args := q.SQL()
+	rows, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*sports.SportType
+	for rows.Next() {
+		var sportType sports.SportType
+		var category string
+
+		if err := rows.Scan(&sportType.Id, &sportType.Name, &category, &sportType.Icon, &sportType.Active); err != nil {
+			return nil, err
+		}
+		sportType.Category = sports.SportType_Category(sports.SportType_Category_value[category])
+
+		list = append(list, &sportType)
+	}
+
+	return list, rows.Err()
+}
+
+// query runs the given query, logging a warning with the request's log fields
+// if it took longer than the configured slow query threshold.
+func (r *sportsRepo) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return sqlrepo.Query(ctx, r.db, r.slowQueryThreshold, query, args...)
+}
 
+// listQuery builds the query listing the events matching filter in the
+// requested order. now is the time relative filters are evaluated at.
+func (r *sportsRepo) listQuery(filter *sports.ListEventsRequestFilter, order string, now time.Time) (*sqlrepo.ListQuery, error) {
+	q := sqlrepo.NewListQuery(getSportsQueries()[sportsList])
+	if err := applySportsFilter(q, filter, order, now); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// applySportsFilter restricts q to the events matching filter and sorts them
+// in the requested order, the same way for every repository. now is the time
+// relative filters are evaluated at.
+func applySportsFilter(q sqlrepo.ListBuilder, filter *sports.ListEventsRequestFilter, order string, now time.Time) error {
 	if filter == nil {
-		return query, args
+		filter = &sports.ListEventsRequestFilter{}
 	}
 
-	if len(filter.EventIds) > 0 {
-		clauses = append(clauses, "event_id IN ("+strings.Repeat("?,", len(filter.EventIds)-1)+"?)")
+	q.In("event_id", filter.EventIds)
+	q.InStrings("sports_type", filter.SportTypes)
 
-		for _, eventID := range filter.EventIds {
-			args = append(args, eventID)
-		}
+	// time windows and statuses compare advertised_start_time in the query,
+	// so they are applied before any page limit
+	if filter.StartTimeFrom != nil {
+		q.From("advertised_start_time", filter.StartTimeFrom.AsTime())
+	}
+	if filter.StartTimeTo != nil {
+		q.Until("advertised_start_time", filter.StartTimeTo.AsTime())
+	}
+	if filter.StartsWithin != nil {
+		q.From("advertised_start_time", now)
+		q.Until("advertised_start_time", now.Add(filter.StartsWithin.AsDuration()))
+	}
+	if filter.Status != sports.ListEventsRequestFilter_EVENT_STATUS_UNSPECIFIED {
+		q.Status("advertised_start_time", filter.Status.String(), now)
 	}
 
-	if len(clauses) != 0 {
-		query += " WHERE " + strings.Join(clauses, " AND ")
+	if err := q.Filter(filter.Expression, sportsFilterSchema); err != nil {
+		return err
 	}
 
-	// add sort and order clause in the end
-	if filter.SortBy != "" {
-		// check for valid fields
-		if filter.SortBy == "advertised_start_time" || filter.SortBy == "number" ||
-			filter.SortBy == "event_id" || filter.SortBy == "name" || filter.SortBy == "sports_type" {
-			sortCondition := " ORDER BY " + filter.SortBy
-			query += sortCondition
+	// sort in the end, falling back to the deprecated sort_by/order_by filter
+	// fields
+	if order == "" {
+		order = legacySportsOrder(filter)
+	}
 
-			if filter.OrderBy == 1 {
-				query += " DESC"
-			} // note - we don't need to check for 0 since by default the sort order is ASC
-		}
+	return q.OrderBy(order, sportsOrderColumns)
+}
+
+// legacySportsOrder translates the deprecated sort_by/order_by filter fields
+// into an order_by expression. Unknown sort_by values are ignored, as they
+// always have been.
+func legacySportsOrder(filter *sports.ListEventsRequestFilter) string {
+	switch filter.SortBy {
+	case "advertised_start_time", "number", "event_id", "name", "sports_type":
+	default:
+		return ""
+	}
+
+	if filter.OrderBy == 1 {
+		return filter.SortBy + " desc"
 	}
 
-	return query, args
+	return filter.SortBy
 }
 
 func (m *sportsRepo) scanEvents(
 	rows *sql.Rows,
+	now time.Time,
 ) ([]*sports.Event, error) {
 	var events []*sports.Event
 
@@ -159,11 +307,7 @@ func (m *sportsRepo) scanEvents(
 
 		// calculate whether an event is OPEN or CLOSED based on it's existence in the past or the future
 		event.AdvertisedStartTime = ts
-		if event.AdvertisedStartTime.AsTime().Before(time.Now()) {
-			event.Status = "CLOSED"
-		} else {
-			event.Status = "OPEN"
-		}
+		event.Status = sqlrepo.Status(advertisedStart, now)
 
 		events = append(events, &event)
 	}
@@ -0,0 +1,282 @@
+package db
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/filtering"
+	"git.neds.sh/matty/entain/common/sqlrepo"
+	"git.neds.sh/matty/entain/common/sqlrepo/sqlrepotest"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+// fuzzEvent is an event of the fuzzed repository, with its start time in the
+// zone it is stored in.
+type fuzzEvent struct {
+	id, eventID, number int64
+	sportsType, name    string
+	start               time.Time
+}
+
+func (e fuzzEvent) field(name string) interface{} {
+	switch name {
+	case "id":
+		return e.id
+	case "event_id":
+		return e.eventID
+	case "sports_type":
+		return e.sportsType
+	case "name":
+		return e.name
+	case "number":
+		return e.number
+	case "advertised_start_time":
+		return e.start
+	}
+
+	panic("unknown field " + name)
+}
+
+// fuzzEvents returns events with plenty of ties to sort, awkward names and
+// start times stored with different UTC offsets.
+func fuzzEvents() []fuzzEvent {
+	rng := rand.New(rand.NewSource(1))
+	sportsTypes := []string{string(Tennis), string(Football), string(CS), string(Boxing)}
+	names := []string{"Grand Final", "grand final", "Wimbledon Semi Final", `The "Big" One`, `Back\slash`, "O'Brien Cup", "Überraschung", ""}
+	zones := []*time.Location{time.UTC, time.FixedZone("AEST", 10*60*60), time.FixedZone("EST", -5*60*60)}
+
+	events := make([]fuzzEvent, 40)
+	for i := range events {
+		events[i] = fuzzEvent{
+			id:         int64(i + 1),
+			eventID:    int64(rng.Intn(5) + 1),
+			sportsType: sportsTypes[rng.Intn(len(sportsTypes))],
+			name:       names[rng.Intn(len(names))],
+			number:     int64(rng.Intn(4) + 1),
+			// every half hour for two days either side of now, stored to the
+			// second
+			start: now.Truncate(time.Second).Add(time.Duration(rng.Intn(193)-96) * 30 * time.Minute).In(zones[rng.Intn(len(zones))]),
+		}
+	}
+
+	return events
+}
+
+// openFuzzEvents returns a SQLite sports repository holding events, with the
+// fuzz corpus seeded.
+func openFuzzEvents(f *testing.F, events []fuzzEvent) SportsRepo {
+	db := sqlrepotest.OpenSQLite(f)
+	repo := NewSportsRepo(db, WithClock(clock.NewFake(now)))
+	require.NoError(f, repo.Init())
+	_, err := db.Exec(`DELETE FROM sports`)
+	require.NoError(f, err)
+	for _, e := range events {
+		_, err := db.Exec(`INSERT INTO sports(id, event_id, sports_type, name, number, advertised_start_time) VALUES (?,?,?,?,?,?)`,
+			e.id, e.eventID, e.sportsType, e.name, e.number, e.start.Format(time.RFC3339))
+		require.NoError(f, err)
+	}
+
+	f.Add([]byte{}, uint8(0), int32(0), int32(0), uint32(0), "", "", uint8(0), false, uint8(0), uint8(0))
+	f.Add([]byte{1, 3}, uint8(1), int32(0), int32(0), uint32(0), "", "advertised_start_time desc", uint8(0), false, uint8(3), uint8(0))
+	f.Add([]byte{}, uint8(2), int32(-86400), int32(3600), uint32(0), `number > 2 OR name = "The \"Big\" One"`, "sports_type, name desc", uint8(0), false, uint8(4), uint8(0))
+	f.Add([]byte{2}, uint8(0), int32(0), int32(0), uint32(7200), `NOT sports_type = "Tennis" AND advertised_start_time >= "2026-10-18T20:00:00+10:00"`, "", uint8(1), true, uint8(1), uint8(0))
+	f.Add([]byte{4, 5}, uint8(0), int32(0), int32(0), uint32(0), `name IN ("Back\\slash", "O'Brien Cup") event_id != 4`, "", uint8(4), false, uint8(2), uint8(0))
+	f.Add([]byte{}, uint8(0), int32(0), int32(0), uint32(0), " ", "", uint8(5), false, uint8(0), uint8(0))
+	f.Add([]byte{}, uint8(0), int32(0), int32(0), uint32(0), `venue = 1`, "venue", uint8(0), false, uint8(0), uint8(0))
+	f.Add([]byte{}, uint8(0), int32(0), int32(0), uint32(0), "", "number asc, number", uint8(0), false, uint8(0), uint8(0))
+	f.Add([]byte{}, uint8(0), int32(0), int32(0), uint32(0), "", "sports_type", uint8(0), false, uint8(3), uint8(0b1001))
+	f.Add([]byte{1}, uint8(1), int32(0), int32(0), uint32(0), `sports_type != "Tennis"`, "", uint8(0), false, uint8(0), uint8(0b10011))
+
+	return repo
+}
+
+var legacySortBy = []string{"advertised_start_time", "number", "event_id", "name", "sports_type", ""}
+
+// filterSportTypes are picked by the bits of the fuzzed sportTypes argument.
+var filterSportTypes = []string{string(Tennis), string(Football), string(CS), string(Boxing), string(DOTA)}
+
+// fuzzEventsRequest builds the list request the fuzzed arguments describe.
+func fuzzEventsRequest(eventIDs []byte, status uint8, from, to int32, within uint32, expression, order string, sortBy uint8, desc bool, pageSize, sportTypes uint8) *sports.ListEventsRequest {
+	filter := &sports.ListEventsRequestFilter{
+		SortBy:     legacySortBy[int(sortBy)%len(legacySortBy)],
+		Expression: expression,
+		Status:     sports.ListEventsRequestFilter_EventStatus(status % 3),
+	}
+	for _, id := range eventIDs {
+		filter.EventIds = append(filter.EventIds, int64(id%6))
+	}
+	for i, sportType := range filterSportTypes {
+		if sportTypes&(1<<i) != 0 {
+			filter.SportTypes = append(filter.SportTypes, sportType)
+		}
+	}
+	if desc {
+		filter.OrderBy = 1
+	}
+	if from != 0 {
+		filter.StartTimeFrom = timestamppb.New(now.Add(time.Duration(from) * time.Second))
+	}
+	if to != 0 {
+		filter.StartTimeTo = timestamppb.New(now.Add(time.Duration(to) * time.Second))
+	}
+	if within != 0 {
+		filter.StartsWithin = durationpb.New(time.Duration(within) * time.Second)
+	}
+
+	return &sports.ListEventsRequest{Filter: filter, OrderBy: order, PageSize: int32(pageSize % 8)}
+}
+
+// FuzzList checks the events listed from SQLite against a reference
+// implementation filtering and sorting fuzzEvents in memory.
+func FuzzList(f *testing.F) {
+	events := fuzzEvents()
+	repo := openFuzzEvents(f, events)
+
+	f.Fuzz(func(t *testing.T, eventIDs []byte, status uint8, from, to int32, within uint32, expression, order string, sortBy uint8, desc bool, pageSize, sportTypes uint8) {
+		req := fuzzEventsRequest(eventIDs, status, from, to, within, expression, order, sortBy, desc, pageSize, sportTypes)
+		want, wantErr := referenceList(events, req.Filter, order)
+
+		var got []int64
+		for page := 0; page <= len(events); page++ {
+			list, token, err := repo.List(context.Background(), req)
+			if wantErr != nil {
+				require.IsType(t, wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+
+			for _, event := range list {
+				e := events[event.Id-1]
+				assert.Equal(t, e.eventID, event.EventId)
+				assert.Equal(t, e.sportsType, event.SportsType)
+				assert.Equal(t, e.name, event.Name)
+				assert.True(t, e.start.Equal(event.AdvertisedStartTime.AsTime()))
+				assert.Equal(t, sqlrepo.Status(e.start, now.Truncate(time.Second)), event.Status)
+				got = append(got, event.Id)
+			}
+
+			if token == "" {
+				break
+			}
+			req.PageToken = token
+		}
+
+		assert.Equal(t, want, got)
+	})
+}
+
+// FuzzListMemory checks the in-memory repository lists the same events, page
+// for page, as SQLite does from the same fuzzEvents.
+func FuzzListMemory(f *testing.F) {
+	events := fuzzEvents()
+	repo := openFuzzEvents(f, events)
+
+	var protos []*sports.Event
+	for _, e := range events {
+		protos = append(protos, &sports.Event{Id: e.id, EventId: e.eventID, SportsType: e.sportsType, Name: e.name, Number: e.number, AdvertisedStartTime: timestamppb.New(e.start)})
+	}
+	memory := NewMemorySportsRepo(protos, WithClock(clock.NewFake(now)))
+
+	f.Fuzz(func(t *testing.T, eventIDs []byte, status uint8, from, to int32, within uint32, expression, order string, sortBy uint8, desc bool, pageSize, sportTypes uint8) {
+		req := fuzzEventsRequest(eventIDs, status, from, to, within, expression, order, sortBy, desc, pageSize, sportTypes)
+
+		want, wantErr := listAll(repo, req)
+		got, err := listAll(memory, req)
+		if wantErr != nil {
+			require.IsType(t, wantErr, err)
+			return
+		}
+		require.NoError(t, err)
+
+		require.Equal(t, ids(want), ids(got))
+		for i := range want {
+			assert.True(t, proto.Equal(want[i], got[i]), "want %v, got %v", want[i], got[i])
+		}
+	})
+}
+
+// referenceList returns the IDs of the events matching filter in the
+// requested order, or the error List should give.
+func referenceList(events []fuzzEvent, filter *sports.ListEventsRequestFilter, order string) ([]int64, error) {
+	expr, err := filtering.Parse(filter.Expression, sportsFilterSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	eventIDs := make(map[int64]bool)
+	for _, id := range filter.EventIds {
+		eventIDs[id] = true
+	}
+	sportTypes := make(map[string]bool)
+	for _, sportType := range filter.SportTypes {
+		sportTypes[sportType] = true
+	}
+
+	var rows []sqlrepotest.Row
+	for _, e := range events {
+		start := e.start.Unix()
+		switch {
+		case len(eventIDs) != 0 && !eventIDs[e.eventID]:
+		case len(sportTypes) != 0 && !sportTypes[e.sportsType]:
+		case filter.StartTimeFrom != nil && start < filter.StartTimeFrom.AsTime().Unix():
+		case filter.StartTimeTo != nil && start >= filter.StartTimeTo.AsTime().Unix():
+		case filter.StartsWithin != nil && (start < now.Unix() || start >= now.Add(filter.StartsWithin.AsDuration()).Unix()):
+		case filter.Status == sports.ListEventsRequestFilter_OPEN && start < now.Unix():
+		case filter.Status == sports.ListEventsRequestFilter_CLOSED && start >= now.Unix():
+		case !sqlrepotest.Match(expr, e.field):
+		default:
+			rows = append(rows, e.field)
+		}
+	}
+
+	if order == "" {
+		order = legacySportsOrder(filter)
+	}
+	if !sqlrepotest.Sort(rows, order, sportsOrderColumns) {
+		return nil, &sqlrepo.OrderByError{}
+	}
+
+	var ids []int64
+	for _, row := range rows {
+		ids = append(ids, row("id").(int64))
+	}
+
+	return ids, nil
+}
+
+// listAll lists the events of every page of req.
+func listAll(repo SportsRepo, req *sports.ListEventsRequest) ([]*sports.Event, error) {
+	req = proto.Clone(req).(*sports.ListEventsRequest)
+
+	var all []*sports.Event
+	for {
+		events, token, err := repo.List(context.Background(), req)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, events...)
+
+		if token == "" {
+			return all, nil
+		}
+		req.PageToken = token
+	}
+}
+
+func ids(events []*sports.Event) []int64 {
+	var ids []int64
+	for _, event := range events {
+		ids = append(ids, event.Id)
+	}
+
+	return ids
+}
@@ -1,26 +1,36 @@
 package db
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/stretchr/testify/assert"
-
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/filtering"
+	"git.neds.sh/matty/entain/common/outbox"
+	"git.neds.sh/matty/entain/common/sqlrepo"
+	"git.neds.sh/matty/entain/common/sqlrepo/sqlrepotest"
 	"git.neds.sh/matty/entain/sports/proto/sports"
 )
 
+// now is when the tests run, as far as the repository can tell.
+var now = time.Date(2026, time.October, 18, 12, 0, 0, 500_000_000, time.UTC)
+
 func TestGetRaceClosed(t *testing.T) {
 	// Set up the mock database and get a mock database connection
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("Error creating mock database: %v", err)
-	}
-	defer db.Close()
+	db, mock := sqlrepotest.NewMock(t)
 
-	repo := &sportsRepo{db: db}
-	expectedTime := time.Date(2022, time.January, 1, 12, 0, 0, 0, time.UTC)
+	repo := &sportsRepo{db: db, clock: clock.NewFake(now)}
+	expectedTime := now.Add(-time.Minute)
 
 	expectedPTime, _ := ptypes.TimestampProto(expectedTime)
 	t.Run("GetEventByID", func(t *testing.T) {
@@ -44,13 +54,13 @@ func TestGetRaceClosed(t *testing.T) {
 				expectedTime,
 			)
 
-		mock.ExpectQuery("SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports WHERE id=1").WillReturnRows(rows)
+		mock.ExpectQuery("SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports WHERE id = \\?").WithArgs(1).WillReturnRows(rows)
 
 		filter := &sports.GetEventRequest{
 			Id: int32(expectedEvent.Id),
 		}
 
-		resultEvent, err := repo.Get(filter)
+		resultEvent, err := repo.Get(context.Background(), filter)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, resultEvent)
@@ -58,21 +68,14 @@ func TestGetRaceClosed(t *testing.T) {
 	})
 
 	// Add more test cases as needed
-
-	// Assert that the expected queries were executed
-	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestGetRaceOpen(t *testing.T) {
 	// Set up the mock database and get a mock database connection
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("Error creating mock database: %v", err)
-	}
-	defer db.Close()
+	db, mock := sqlrepotest.NewMock(t)
 
-	repo := &sportsRepo{db: db}
-	expectedTime := time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+	repo := &sportsRepo{db: db, clock: clock.NewFake(now)}
+	expectedTime := now.Add(time.Minute)
 
 	expectedPTime, _ := ptypes.TimestampProto(expectedTime)
 	t.Run("GetEventByID", func(t *testing.T) {
@@ -96,13 +99,13 @@ func TestGetRaceOpen(t *testing.T) {
 				expectedTime,
 			)
 
-		mock.ExpectQuery("SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports WHERE id=1").WillReturnRows(rows)
+		mock.ExpectQuery("SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports WHERE id = \\?").WithArgs(1).WillReturnRows(rows)
 
 		filter := &sports.GetEventRequest{
 			Id: int32(expectedEvent.Id),
 		}
 
-		resultEvent, err := repo.Get(filter)
+		resultEvent, err := repo.Get(context.Background(), filter)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, resultEvent)
@@ -110,22 +113,20 @@ func TestGetRaceOpen(t *testing.T) {
 	})
 
 	// Add more test cases as needed
-
-	// Assert that the expected queries were executed
-	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestApplyFilter(t *testing.T) {
+func TestListQuery(t *testing.T) {
 	tests := []struct {
-		name   string
-		filter *sports.ListEventsRequestFilter
-		query  string
-		args   []interface{}
+		name    string
+		filter  *sports.ListEventsRequestFilter
+		orderBy string
+		query   string
+		args    []interface{}
 	}{
 		{
 			name:   "NoFilter",
 			filter: nil,
-			query:  "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports",
+			query:  "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports ORDER BY id",
 			args:   nil,
 		},
 		{
@@ -133,7 +134,7 @@ func TestApplyFilter(t *testing.T) {
 			filter: &sports.ListEventsRequestFilter{
 				EventIds: []int64{1, 2, 3},
 			},
-			query: "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports WHERE event_id IN (?,?,?)",
+			query: "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports WHERE event_id IN (?,?,?) ORDER BY id",
 			args:  []interface{}{int64(1), int64(2), int64(3)},
 		},
 		{
@@ -142,7 +143,7 @@ func TestApplyFilter(t *testing.T) {
 				SortBy:  "number",
 				OrderBy: 1,
 			},
-			query: "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports ORDER BY number DESC",
+			query: "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports ORDER BY number DESC, id",
 			args:  nil,
 		},
 		{
@@ -150,7 +151,7 @@ func TestApplyFilter(t *testing.T) {
 			filter: &sports.ListEventsRequestFilter{
 				SortBy: "invalid_field",
 			},
-			query: "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports",
+			query: "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports ORDER BY id",
 			args:  nil,
 		},
 		{
@@ -158,15 +159,79 @@ func TestApplyFilter(t *testing.T) {
 			filter: &sports.ListEventsRequestFilter{
 				SortBy: "name",
 			},
-			query: "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports ORDER BY name",
+			query: "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports ORDER BY name, id",
 			args:  nil,
 		},
+		{
+			name: "FilterExpression",
+			filter: &sports.ListEventsRequestFilter{
+				EventIds:   []int64{7},
+				Expression: `sports_type = "Tennis" NOT number >= 3`,
+			},
+			query: "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports WHERE event_id IN (?) AND (sports_type = ? AND NOT (number >= ?)) ORDER BY id",
+			args:  []interface{}{int64(7), "Tennis", int64(3)},
+		},
+		{
+			name: "FilterWithSportTypes",
+			filter: &sports.ListEventsRequestFilter{
+				EventIds:   []int64{7},
+				SportTypes: []string{"Tennis", "Boxing"},
+			},
+			query: "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports WHERE event_id IN (?) AND sports_type IN (?,?) ORDER BY id",
+			args:  []interface{}{int64(7), "Tennis", "Boxing"},
+		},
+		{
+			name: "StartTimeWindow",
+			filter: &sports.ListEventsRequestFilter{
+				StartTimeFrom: timestamppb.New(time.Date(2026, time.October, 18, 10, 0, 0, 0, time.FixedZone("AEST", 10*60*60))),
+				StartTimeTo:   timestamppb.New(time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)),
+			},
+			query: "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports WHERE datetime(advertised_start_time) >= ? AND datetime(advertised_start_time) < ? ORDER BY id",
+			args:  []interface{}{"2026-10-18 00:00:00", "2026-10-19 00:00:00"},
+		},
+		{
+			name: "StartsWithinOpen",
+			filter: &sports.ListEventsRequestFilter{
+				StartsWithin: durationpb.New(time.Hour),
+				Status:       sports.ListEventsRequestFilter_OPEN,
+			},
+			query: "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports WHERE datetime(advertised_start_time) >= ? AND datetime(advertised_start_time) < ? AND datetime(advertised_start_time) >= ? ORDER BY id",
+			args:  []interface{}{"2026-10-18 12:00:00", "2026-10-18 13:00:00", "2026-10-18 12:00:00"},
+		},
+		{
+			name: "StatusClosed",
+			filter: &sports.ListEventsRequestFilter{
+				Status: sports.ListEventsRequestFilter_CLOSED,
+			},
+			query: "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports WHERE datetime(advertised_start_time) < ? ORDER BY id",
+			args:  []interface{}{"2026-10-18 12:00:00"},
+		},
+		{
+			name:    "OrderByMultipleFields",
+			orderBy: "sports_type, advertised_start_time desc",
+			query:   "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports ORDER BY sports_type, datetime(advertised_start_time) DESC, id",
+			args:    nil,
+		},
+		{
+			name: "OrderByOverridesSortBy",
+			filter: &sports.ListEventsRequestFilter{
+				EventIds: []int64{1},
+				SortBy:   "name",
+			},
+			orderBy: "id desc",
+			query:   "SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports WHERE event_id IN (?) ORDER BY id DESC",
+			args:    []interface{}{int64(1)},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := &sportsRepo{}
-			resultQuery, resultArgs := repo.applyFilter("SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports", tt.filter)
+			q, err := repo.listQuery(tt.filter, tt.orderBy, now)
+			require.NoError(t, err)
+
+			resultQuery, resultArgs := q.SQL()
+			resultQuery = strings.Join(strings.Fields(resultQuery), " ")
 
 			if resultQuery != tt.query {
 				t.Errorf("Query mismatch. Expected: %s, Got: %s", tt.query, resultQuery)
@@ -179,6 +244,91 @@ func TestApplyFilter(t *testing.T) {
 	}
 }
 
+func TestListUsesClock(t *testing.T) {
+	db, mock := sqlrepotest.NewMock(t)
+
+	// a simulated day of events, an hour before the first one
+	clk := clock.NewFake(time.Date(2021, time.March, 3, 9, 0, 0, 0, time.UTC))
+	repo := NewSportsRepo(db, WithClock(clk))
+
+	columns := []string{"id", "event_id", "sports_type", "name", "number", "advertised_start_time"}
+	mock.ExpectQuery("WHERE datetime\\(advertised_start_time\\) >= \\?").
+		WithArgs("2021-03-03 09:00:00").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 5, "Football", "Match 1", 10, clk.Now().Add(time.Hour)))
+
+	events, _, err := repo.List(context.Background(), &sports.ListEventsRequest{Filter: &sports.ListEventsRequestFilter{Status: sports.ListEventsRequestFilter_OPEN}})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "OPEN", events[0].Status)
+	}
+
+	// once the event has started it is CLOSED
+	clk.Advance(2 * time.Hour)
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 5, "Football", "Match 1", 10, clk.Now().Add(-time.Hour)))
+
+	events, _, err = repo.List(context.Background(), &sports.ListEventsRequest{})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "CLOSED", events[0].Status)
+	}
+
+}
+
+func TestListQueryInvalidOrderBy(t *testing.T) {
+	repo := &sportsRepo{}
+	_, err := repo.listQuery(nil, "meeting_id", now)
+
+	var orderErr *sqlrepo.OrderByError
+	assert.ErrorAs(t, err, &orderErr)
+}
+
+func TestListQueryInvalidExpression(t *testing.T) {
+	repo := &sportsRepo{}
+	_, err := repo.listQuery(&sports.ListEventsRequestFilter{Expression: `visible = true`}, "", now)
+
+	var filterErr *filtering.Error
+	assert.ErrorAs(t, err, &filterErr)
+}
+
+func TestGetEventNotFound(t *testing.T) {
+	db, mock := sqlrepotest.NewMock(t)
+	repo := &sportsRepo{db: db, clock: clock.NewFake(now)}
+
+	mock.ExpectQuery("FROM sports WHERE id = \\?").WithArgs(404).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_id", "sports_type", "name", "number", "advertised_start_time"}))
+
+	_, err := repo.Get(context.Background(), &sports.GetEventRequest{Id: 404})
+	assert.ErrorIs(t, err, sqlrepo.ErrNotFound)
+}
+
+func TestListPages(t *testing.T) {
+	db, mock := sqlrepotest.NewMock(t)
+	repo := NewSportsRepo(db, WithClock(clock.NewFake(now)))
+
+	columns := []string{"id", "event_id", "sports_type", "name", "number", "advertised_start_time"}
+	mock.ExpectQuery("ORDER BY number, id LIMIT \\? OFFSET \\?").WithArgs(3, 0).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 5, "Tennis", "Final", 1, now).
+			AddRow(2, 5, "Tennis", "Semi Final", 2, now).
+			AddRow(3, 5, "Tennis", "Quarter Final", 3, now))
+
+	events, token, err := repo.List(context.Background(), &sports.ListEventsRequest{OrderBy: "number", PageSize: 2})
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+	require.NotEmpty(t, token)
+
+	mock.ExpectQuery("ORDER BY number, id LIMIT \\? OFFSET \\?").WithArgs(3, 2).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(3, 5, "Tennis", "Quarter Final", 3, now))
+
+	events, token, err = repo.List(context.Background(), &sports.ListEventsRequest{OrderBy: "number", PageSize: 2, PageToken: token})
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Empty(t, token, "last page")
+
+	_, _, err = repo.List(context.Background(), &sports.ListEventsRequest{PageSize: 2, PageToken: "bogus"})
+	assert.ErrorIs(t, err, sqlrepo.ErrInvalidPageToken)
+}
+
 func equal(a, b []interface{}) bool {
 	if len(a) != len(b) {
 		return false
@@ -193,22 +343,18 @@ func equal(a, b []interface{}) bool {
 
 func TestScanEventsOpen(t *testing.T) {
 	// Set up the mock database and get a mock database connection
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("Error creating mock database: %v", err)
-	}
-	defer db.Close()
+	db, mock := sqlrepotest.NewMock(t)
 
 	// Define columns and rows for the mock
 	columns := []string{"id", "event_id", "sports_type", "name", "number", "advertised_start_time"}
-	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 5, "Football", "Match 1", 10, time.Now().Add(time.Hour)))
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 5, "Football", "Match 1", 10, now.Add(time.Hour)))
 
-	repo := &sportsRepo{}
+	repo := &sportsRepo{clock: clock.NewFake(now)}
 	rows, err := db.Query("SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports")
 	assert.NoError(t, err)
 
 	t.Run("ScanValidEvent", func(t *testing.T) {
-		events, err := repo.scanEvents(rows)
+		events, err := repo.scanEvents(rows, now)
 
 		assert.NoError(t, err)
 		assert.Len(t, events, 1)
@@ -216,29 +362,22 @@ func TestScanEventsOpen(t *testing.T) {
 		// Add assertions for the event properties
 		assert.Equal(t, "OPEN", events[0].Status)
 	})
-
-	// Assert that the expected queries were executed
-	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestScanEventsClosed(t *testing.T) {
 	// Set up the mock database and get a mock database connection
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("Error creating mock database: %v", err)
-	}
-	defer db.Close()
+	db, mock := sqlrepotest.NewMock(t)
 
 	// Define columns and rows for the mock
 	columns := []string{"id", "event_id", "sports_type", "name", "number", "advertised_start_time"}
-	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 5, "Football", "Match 1", 10, time.Now().Add(-time.Hour)))
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 5, "Football", "Match 1", 10, now.Add(-time.Hour)))
 
-	repo := &sportsRepo{}
+	repo := &sportsRepo{clock: clock.NewFake(now)}
 	rows, err := db.Query("SELECT id, event_id, sports_type, name, number, advertised_start_time FROM sports")
 	assert.NoError(t, err)
 
 	t.Run("ScanValidEvent", func(t *testing.T) {
-		events, err := repo.scanEvents(rows)
+		events, err := repo.scanEvents(rows, now)
 
 		assert.NoError(t, err)
 		assert.Len(t, events, 1)
@@ -246,7 +385,148 @@ func TestScanEventsClosed(t *testing.T) {
 		// Add assertions for the event properties
 		assert.Equal(t, "CLOSED", events[0].Status)
 	})
+}
+
+// withRetiredSportType adds an inactive sport type to sportTypes until the
+// test ends, as every sport type offered is active, and returns its name.
+func withRetiredSportType(t *testing.T) string {
+	t.Helper()
+
+	original := sportTypes
+	t.Cleanup(func() { sportTypes = original })
+
+	retired := &sports.SportType{Id: 100, Name: "Polo", Category: sports.SportType_TRADITIONAL, Icon: "polo"}
+	sportTypes = append(append([]*sports.SportType(nil), original...), retired)
+
+	return retired.Name
+}
+
+func TestListSportTypes(t *testing.T) {
+	retired := withRetiredSportType(t)
+
+	db := sqlrepotest.OpenSQLite(t)
+	repo := NewSportsRepo(db, WithClock(clock.NewFake(now)))
+	require.NoError(t, repo.Init())
+
+	active, err := repo.ListSportTypes(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, listSportTypes(false), active)
+	assert.NotContains(t, names(active), retired)
+	for _, sportType := range active {
+		assert.True(t, sportType.Active, sportType.Name)
+	}
+
+	all, err := repo.ListSportTypes(context.Background(), true)
+	require.NoError(t, err)
+	assert.Equal(t, listSportTypes(true), all)
+	assert.Len(t, all, len(sportTypes))
+	assert.Contains(t, names(all), retired)
+}
+
+func TestSportTypeIntegrity(t *testing.T) {
+	retired := withRetiredSportType(t)
+
+	db := sqlrepotest.OpenSQLite(t)
+	repo := NewSportsRepo(db, WithClock(clock.NewFake(now)))
+	require.NoError(t, repo.Init())
+	// seeding again keeps the sport types in line rather than failing
+	require.NoError(t, NewSportsRepo(db, WithClock(clock.NewFake(now))).Init())
+
+	insert := `INSERT INTO sports(id, event_id, sports_type, name, number, advertised_start_time) VALUES (1000, 1, ?, 'Final', 1, '2026-10-18T12:00:00Z')`
+	_, err := db.Exec(insert, "Curling")
+	assert.EqualError(t, err, "unknown sport type")
+	_, err = db.Exec(insert, retired)
+	assert.NoError(t, err, "inactive sport types are still known")
+
+	_, err = db.Exec(`UPDATE sports SET sports_type = 'Curling' WHERE id = 1000`)
+	assert.EqualError(t, err, "unknown sport type")
+
+	_, err = db.Exec(`DELETE FROM sport_types WHERE name = ?`, retired)
+	assert.EqualError(t, err, "sport type in use")
+	_, err = db.Exec(`UPDATE sport_types SET name = 'Water Polo' WHERE name = ?`, retired)
+	assert.EqualError(t, err, "sport type in use")
+}
+
+func TestSeedOutbox(t *testing.T) {
+	ctx := context.Background()
+	db := sqlrepotest.OpenSQLite(t)
+	require.NoError(t, NewSportsRepo(db, WithClock(clock.NewFake(now))).Init())
+
+	var published []outbox.Message
+	bus := outbox.NewBus()
+	bus.Subscribe(func(ctx context.Context, msg outbox.Message) error {
+		published = append(published, msg)
+		return nil
+	})
+	relay := outbox.NewRelay(db, "test", bus, 1000)
+	n, err := relay.Flush(ctx)
+	require.NoError(t, err)
+	require.Equal(t, seedCount, n)
+
+	// Every seeded event is recorded as created, as it was stored.
+	msg := published[0]
+	assert.Equal(t, eventsTopic, msg.Topic)
+	assert.Equal(t, eventCreated, msg.Type)
+	assert.Equal(t, "1", msg.Key)
+	assert.Equal(t, now, msg.CreatedAt)
+	var event sports.Event
+	require.NoError(t, protojson.Unmarshal(msg.Payload, &event))
+	stored, err := NewSportsRepo(db, WithClock(clock.NewFake(now))).Get(ctx, &sports.GetEventRequest{Id: 1})
+	require.NoError(t, err)
+	assert.Equal(t, stored.Name, event.Name)
+	assert.Equal(t, stored.AdvertisedStartTime.AsTime(), event.AdvertisedStartTime.AsTime())
+
+	// Seeding again leaves the events as they are, recording nothing.
+	require.NoError(t, NewSportsRepo(db, WithClock(clock.NewFake(now))).Init())
+	n, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestStatusAtSubSecondNow(t *testing.T) {
+	// Start times are stored to the second, so the status of a event that
+	// started at event 12:00:00 must agree with the status filter throughout
+	// the second, whatever the fraction of now.
+	start := now.Truncate(time.Second)
+	require.NotEqual(t, start, now, "now must have a fraction of a second")
+	events := []*sports.Event{
+		{Id: 1, EventId: 1, SportsType: string(Tennis), Name: "Started", Number: 1, AdvertisedStartTime: timestamppb.New(start.Add(-time.Second))},
+		{Id: 2, EventId: 1, SportsType: string(Tennis), Name: "Starting", Number: 2, AdvertisedStartTime: timestamppb.New(start)},
+		{Id: 3, EventId: 1, SportsType: string(Tennis), Name: "Next", Number: 3, AdvertisedStartTime: timestamppb.New(start.Add(time.Second))},
+	}
+
+	db := sqlrepotest.OpenSQLite(t)
+	sqlRepo := NewSportsRepo(db, WithClock(clock.NewFake(now)))
+	require.NoError(t, sqlRepo.Init())
+	_, err := db.Exec(`DELETE FROM sports`)
+	require.NoError(t, err)
+	for _, event := range events {
+		_, err := db.Exec(`INSERT INTO sports(id, event_id, sports_type, name, number, advertised_start_time) VALUES (?,?,?,?,?,?)`,
+			event.Id, event.EventId, event.SportsType, event.Name, event.Number, event.AdvertisedStartTime.AsTime().Format(time.RFC3339))
+		require.NoError(t, err)
+	}
+
+	repos := map[string]SportsRepo{
+		"SQLite": sqlRepo,
+		"Memory": NewMemorySportsRepo(events, WithClock(clock.NewFake(now))),
+	}
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			for status, want := range map[sports.ListEventsRequestFilter_EventStatus][]int64{
+				sports.ListEventsRequestFilter_OPEN:   {2, 3},
+				sports.ListEventsRequestFilter_CLOSED: {1},
+			} {
+				listed, _, err := repo.List(context.Background(), &sports.ListEventsRequest{Filter: &sports.ListEventsRequestFilter{Status: status}, OrderBy: "id"})
+				require.NoError(t, err)
+				require.Equal(t, want, ids(listed), status.String())
+				for _, event := range listed {
+					assert.Equal(t, status.String(), event.Status, "event %d", event.Id)
+				}
+			}
 
-	// Assert that the expected queries were executed
-	assert.NoError(t, mock.ExpectationsWereMet())
+			event, err := repo.Get(context.Background(), &sports.GetEventRequest{Id: 2})
+			require.NoError(t, err)
+			assert.Equal(t, sqlrepo.Open, event.Status)
+		})
+	}
 }
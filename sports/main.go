@@ -1,60 +1,102 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"log"
-	"net"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"git.neds.sh/matty/entain/sports/proto/sports"
+	"google.golang.org/grpc"
 
+	"git.neds.sh/matty/entain/common/auth"
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/logging"
+	"git.neds.sh/matty/entain/common/server"
+	"git.neds.sh/matty/entain/sports/config"
 	"git.neds.sh/matty/entain/sports/db"
+	"git.neds.sh/matty/entain/sports/proto/sports"
 	"git.neds.sh/matty/entain/sports/service"
-	"google.golang.org/grpc"
 )
 
-var (
-	grpcEndpoint = flag.String("grpc-endpoint", "localhost:9001", "gRPC server endpoint")
-)
+// methodScopes lists the scope each RPC requires when auth is enabled. Any
+// RPC missing here is denied.
+var methodScopes = map[string]string{
+	"/sports.Sports/ListEvents":     "sports:read",
+	"/sports.Sports/GetEvent":       "sports:read",
+	"/sports.Sports/SearchEvents":   "sports:read",
+	"/sports.Sports/ListSportTypes": "sports:read",
+	"/sports.Sports/GetClock":       "sports:read",
+	"/sports.Sports/SetClock":       "sports:admin",
+	"/grpc.health.v1.Health/Check":  auth.Public,
+	"/grpc.health.v1.Health/Watch":  auth.Public,
+}
 
 func main() {
-	flag.Parse()
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("failed loading config: %s\n", err)
+	}
 
-	if err := run(); err != nil {
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("failed printing config: %s\n", err)
+		}
+		return
+	}
+
+	if err := run(cfg); err != nil {
 		log.Fatalf("failed running grpc server: %s\n", err)
 	}
 }
 
-func run() error {
-	conn, err := net.Listen("tcp", ":9001")
+func run(cfg *config.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger, err := logging.New(cfg.LogLevel, cfg.LogFormat)
 	if err != nil {
 		return err
 	}
 
-	sportsDB, err := sql.Open("sqlite3", "./db/sports.db")
+	clk := server.NewClock(&cfg.Config, logger)
+	sportsRepo, sportsDB, err := newSportsRepo(cfg, clk)
 	if err != nil {
 		return err
 	}
-
-	sportsRepo := db.NewSportsRepo(sportsDB)
-	if err := sportsRepo.Init(); err != nil {
-		return err
+	if sportsDB != nil {
+		defer sportsDB.Close()
 	}
 
-	grpcServer := grpc.NewServer()
-
-	sports.RegisterSportsServer(
-		grpcServer,
-		service.NewSportsService(
-			sportsRepo,
-		),
-	)
+	return server.Run(ctx, &cfg.Config, logger, server.Service{
+		Name:   "sports.Sports",
+		Scopes: methodScopes,
+		DB:     sportsDB,
+		Register: func(s *grpc.Server) {
+			sports.RegisterSportsServer(s, service.NewSportsService(sportsRepo, clk))
+		},
+		Init:        sportsRepo.Init,
+		PrintConfig: cfg.Print,
+	})
+}
 
-	log.Printf("gRPC server listening on: %s\n", *grpcEndpoint)
+// newSportsRepo returns the sports repository of the configured storage, and
+// the database it keeps the events in, if any.
+func newSportsRepo(cfg *config.Config, clk clock.Clock) (db.SportsRepo, *sql.DB, error) {
+	if cfg.Storage == server.StorageMemory {
+		return db.NewMemorySportsRepo(nil, db.WithClock(clk)), nil, nil
+	}
 
-	if err := grpcServer.Serve(conn); err != nil {
-		return err
+	sportsDB, err := sql.Open("sqlite3", cfg.DBPath)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return nil
+	return db.NewSportsRepo(sportsDB, db.WithSlowQueryThreshold(cfg.SlowQueryThreshold), db.WithClock(clk)), sportsDB, nil
 }
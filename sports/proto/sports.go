@@ -1,3 +1,3 @@
 package proto
 
-//go:generate protoc --go_out=. --go-grpc_out=require_unimplemented_servers=false:. sports/sports.proto --experimental_allow_proto3_optional
+//go:generate protoc -I . -I ../../common/proto --go_out=. --go_opt=module=git.neds.sh/matty/entain/sports/proto --go-grpc_out=require_unimplemented_servers=false,module=git.neds.sh/matty/entain/sports/proto:. sports/sports.proto --experimental_allow_proto3_optional
@@ -3,6 +3,12 @@ package service
 import (
 	"context"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/common/server"
 	"git.neds.sh/matty/entain/sports/db"
 	"git.neds.sh/matty/entain/sports/proto/sports"
 )
@@ -13,32 +19,94 @@ type Sports interface {
 
 	// GetEvent will return a single event based on the given ID.
 	GetEvent(ctx context.Context, in *sports.GetEventRequest) (*sports.Event, error)
+
+	// SearchEvents will return the events best matching a text query.
+	SearchEvents(ctx context.Context, in *sports.SearchEventsRequest) (*sports.SearchEventsResponse, error)
+
+	// ListSportTypes will return the types of sport events are of.
+	ListSportTypes(ctx context.Context, in *sports.ListSportTypesRequest) (*sports.ListSportTypesResponse, error)
+
+	// GetClock will return the time the service takes as now.
+	GetClock(ctx context.Context, in *sports.GetClockRequest) (*sports.Clock, error)
+
+	// SetClock will make the service take a simulated time as now.
+	SetClock(ctx context.Context, in *sports.SetClockRequest) (*sports.Clock, error)
 }
 
 // sportsService implements the Sports interface.
 type sportsService struct {
 	sportsRepo db.SportsRepo
+	clock      clock.Clock
 }
 
-// NewSportsService instantiates and returns a new sportsService.
-func NewSportsService(sportsRepo db.SportsRepo) Sports {
-	return &sportsService{sportsRepo}
+// NewSportsService instantiates and returns a new sportsService, telling the
+// time by clk. SetClock is only available if clk is a *clock.Simulated, which
+// should be shared with the repository.
+func NewSportsService(sportsRepo db.SportsRepo, clk clock.Clock) Sports {
+	return &sportsService{sportsRepo, clk}
 }
 
 func (s *sportsService) ListEvents(ctx context.Context, in *sports.ListEventsRequest) (*sports.ListEventsResponse, error) {
-	events, err := s.sportsRepo.List(in.Filter)
+	events, nextPageToken, err := s.sportsRepo.List(ctx, in)
 	if err != nil {
-		return nil, err
+		return nil, server.StatusError(err)
 	}
 
-	return &sports.ListEventsResponse{Events: events}, nil
+	return &sports.ListEventsResponse{Events: events, NextPageToken: nextPageToken}, nil
 }
 
 func (s *sportsService) GetEvent(ctx context.Context, in *sports.GetEventRequest) (*sports.Event, error) {
-	event, err := s.sportsRepo.Get(in)
+	event, err := s.sportsRepo.Get(ctx, in)
 	if err != nil {
-		return nil, err
+		return nil, server.StatusError(err)
 	}
 
 	return event, nil
 }
+
+func (s *sportsService) SearchEvents(ctx context.Context, in *sports.SearchEventsRequest) (*sports.SearchEventsResponse, error) {
+	results, err := s.sportsRepo.Search(ctx, in.Query, int(in.Limit))
+	if err != nil {
+		return nil, err
+	}
+
+	return &sports.SearchEventsResponse{Results: results}, nil
+}
+
+func (s *sportsService) ListSportTypes(ctx context.Context, in *sports.ListSportTypesRequest) (*sports.ListSportTypesResponse, error) {
+	sportTypes, err := s.sportsRepo.ListSportTypes(ctx, in.IncludeInactive)
+	if err != nil {
+		return nil, server.StatusError(err)
+	}
+
+	return &sports.ListSportTypesResponse{SportTypes: sportTypes}, nil
+}
+
+func (s *sportsService) GetClock(ctx context.Context, in *sports.GetClockRequest) (*sports.Clock, error) {
+	return s.clockState(), nil
+}
+
+func (s *sportsService) SetClock(ctx context.Context, in *sports.SetClockRequest) (*sports.Clock, error) {
+	simulated, ok := s.clock.(*clock.Simulated)
+	if !ok {
+		return nil, status.Error(codes.FailedPrecondition, "simulated clock is disabled, see --simulated-clock")
+	}
+
+	if in.Now == nil {
+		simulated.Reset()
+	} else {
+		simulated.Set(in.Now.AsTime())
+	}
+
+	return s.clockState(), nil
+}
+
+// clockState describes the service's clock.
+func (s *sportsService) clockState() *sports.Clock {
+	simulated, ok := s.clock.(*clock.Simulated)
+
+	return &sports.Clock{
+		Now:       timestamppb.New(s.clock.Now()),
+		Simulated: ok && simulated.Active(),
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/common/clock"
+	"git.neds.sh/matty/entain/sports/db"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+var now = time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+
+func newTestService(t *testing.T) Sports {
+	t.Helper()
+
+	clk := clock.NewSimulated(clock.NewFake(now))
+	repo := db.NewMemorySportsRepo([]*sports.Event{
+		{Id: 1, EventId: 1, SportsType: string(db.Tennis), Name: "Wimbledon Semi Final", Number: 3, AdvertisedStartTime: timestamppb.New(now.Add(-time.Hour))},
+		{Id: 2, EventId: 1, SportsType: string(db.Tennis), Name: "Wimbledon Final", Number: 1, AdvertisedStartTime: timestamppb.New(now.Add(time.Hour))},
+		{Id: 3, EventId: 2, SportsType: string(db.Football), Name: "Grand Final", Number: 2, AdvertisedStartTime: timestamppb.New(now.Add(2 * time.Hour))},
+	}, db.WithClock(clk))
+
+	return NewSportsService(repo, clk)
+}
+
+func TestListEvents(t *testing.T) {
+	svc := newTestService(t)
+
+	resp, err := svc.ListEvents(context.Background(), &sports.ListEventsRequest{
+		Filter:   &sports.ListEventsRequestFilter{EventIds: []int64{1}},
+		OrderBy:  "number",
+		PageSize: 1,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Events, 1)
+	assert.Equal(t, int64(2), resp.Events[0].Id)
+	assert.Equal(t, "OPEN", resp.Events[0].Status)
+
+	resp, err = svc.ListEvents(context.Background(), &sports.ListEventsRequest{
+		Filter:    &sports.ListEventsRequestFilter{EventIds: []int64{1}},
+		OrderBy:   "number",
+		PageSize:  1,
+		PageToken: resp.NextPageToken,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Events, 1)
+	assert.Equal(t, int64(1), resp.Events[0].Id)
+	assert.Empty(t, resp.NextPageToken)
+
+	_, err = svc.ListEvents(context.Background(), &sports.ListEventsRequest{OrderBy: "venue"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGetEvent(t *testing.T) {
+	svc := newTestService(t)
+
+	event, err := svc.GetEvent(context.Background(), &sports.GetEventRequest{Id: 3})
+	require.NoError(t, err)
+	assert.Equal(t, "Grand Final", event.Name)
+
+	_, err = svc.GetEvent(context.Background(), &sports.GetEventRequest{Id: 99})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestSetClock(t *testing.T) {
+	svc := newTestService(t)
+
+	_, err := svc.SetClock(context.Background(), &sports.SetClockRequest{Now: timestamppb.New(now.Add(90 * time.Minute))})
+	require.NoError(t, err)
+
+	event, err := svc.GetEvent(context.Background(), &sports.GetEventRequest{Id: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "CLOSED", event.Status, "events follow the simulated clock")
+}
+
+func TestListSportTypes(t *testing.T) {
+	svc := newTestService(t)
+
+	resp, err := svc.ListSportTypes(context.Background(), &sports.ListSportTypesRequest{})
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.SportTypes)
+	for _, sportType := range resp.SportTypes {
+		assert.True(t, sportType.Active, sportType.Name)
+	}
+
+	all, err := svc.ListSportTypes(context.Background(), &sports.ListSportTypesRequest{IncludeInactive: true})
+	require.NoError(t, err)
+	assert.Len(t, all.SportTypes, len(resp.SportTypes), "every sport type offered is active")
+}